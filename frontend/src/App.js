@@ -115,6 +115,34 @@ const StatusBadge = ({ status }) => {
           className: 'bg-red-100 text-red-800',
           description: 'Failed to send notification'
         };
+      case 'snoozed':
+        return {
+          icon: Clock,
+          text: 'Snoozed',
+          className: 'bg-purple-100 text-purple-800',
+          description: 'Put off until later'
+        };
+      case 'acknowledged':
+        return {
+          icon: CheckCircle,
+          text: 'Acknowledged',
+          className: 'bg-green-100 text-green-800',
+          description: 'Seen after it was sent'
+        };
+      case 'cancelled':
+        return {
+          icon: XCircle,
+          text: 'Cancelled',
+          className: 'bg-gray-100 text-gray-800',
+          description: 'Cancelled, will not be sent'
+        };
+      case 'expired':
+        return {
+          icon: AlertCircle,
+          text: 'Expired',
+          className: 'bg-orange-100 text-orange-800',
+          description: 'Missed and too late to send'
+        };
       default:
         return {
           icon: AlertCircle,
@@ -139,6 +167,12 @@ const StatusBadge = ({ status }) => {
   );
 };
 
+// A fresh key per form submission; retries and double-clicks reuse it so the server creates the reminder once
+const newIdempotencyKey = () =>
+  window.crypto && window.crypto.randomUUID
+    ? window.crypto.randomUUID()
+    : `${Date.now()}-${Math.random().toString(36).slice(2)}`;
+
 const ReminderApp = () => {
   // Component rendering
   const [reminders, setReminders] = useState([]);
@@ -147,6 +181,7 @@ const ReminderApp = () => {
   const [editingId, setEditingId] = useState(null);
   const [filter, setFilter] = useState('all');
   const [defaultEmails, setDefaultEmails] = useState(() => getDefaultEmails());
+  const [submissionKey, setSubmissionKey] = useState(() => newIdempotencyKey());
   const [formData, setFormData] = useState({
     title: '',
     description: '',
@@ -266,7 +301,8 @@ const ReminderApp = () => {
               method: 'POST',
               headers: { 
                 'Content-Type': 'application/json',
-                'X-User-ID': 'default-user'
+                'X-User-ID': 'default-user',
+                'Idempotency-Key': `${submissionKey}-early`
               },
               body: JSON.stringify(earlyRequestData)
             });
@@ -295,7 +331,8 @@ const ReminderApp = () => {
           method: 'POST',
           headers: { 
             'Content-Type': 'application/json',
-            'X-User-ID': 'default-user'
+            'X-User-ID': 'default-user',
+            'Idempotency-Key': submissionKey
           },
           body: JSON.stringify(mainRequestData)
         });
@@ -328,7 +365,8 @@ const ReminderApp = () => {
               method: 'POST',
               headers: { 
                 'Content-Type': 'application/json',
-                'X-User-ID': 'default-user'
+                'X-User-ID': 'default-user',
+                'Idempotency-Key': `${submissionKey}-early`
               },
               body: JSON.stringify(earlyRequestData)
             });
@@ -366,6 +404,7 @@ const ReminderApp = () => {
       });
       setEditingId(null);
       setShowModal(false);
+      setSubmissionKey(newIdempotencyKey());
       fetchReminders();
     } catch (error) {
       console.error('Error saving reminder:', error);
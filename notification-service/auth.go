@@ -0,0 +1,171 @@
+// auth.go - JWT authentication and role checks. The same in every service: keep the copies identical
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Roles carried in tokens; every service checks them
+const (
+	roleUser    = "user"
+	roleAdmin   = "admin"   // manages users' roles, and can also reach the internal endpoints
+	roleService = "service" // another service; may act on behalf of any user
+)
+
+// serviceTokenTTL is how long this service's own token (named by serviceName, see main.go) lasts
+const serviceTokenTTL = time.Hour
+
+// Claims carried in the tokens user-service issues, and in each service's own service token
+type Claims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+var (
+	jwtKey []byte
+
+	// trustUserHeader lets requests without a token act as the user named by X-User-ID or
+	// user_id. For local development only, where the web UI runs without signing in.
+	trustUserHeader bool
+
+	serviceToken        string
+	serviceTokenExpires time.Time
+	serviceTokenMu      sync.Mutex
+)
+
+// initAuth loads the key tokens are signed with, shared by all services
+func initAuth() {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		log.Fatal("JWT_SECRET is required")
+	}
+	jwtKey = []byte(secret)
+
+	trustUserHeader = os.Getenv("TRUST_USER_HEADER") == "true"
+	if trustUserHeader {
+		log.Println("TRUST_USER_HEADER is set: requests without a token are trusted as the user they name; never use this in production")
+	}
+}
+
+func parseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// authMiddleware requires a valid bearer token. A user's token settles who they are: its subject
+// replaces any X-User-ID header or user_id parameter, and only services may name themselves with
+// X-Service-Name. Services act for users, so their requests are left as they are.
+// With TRUST_USER_HEADER a request without a token is taken as an ordinary user's.
+func authMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := requestClaims(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		if claims.Role != roleService {
+			c.Request.Header.Set("X-User-ID", claims.Subject)
+			c.Request.Header.Del("X-Service-Name")
+			query := c.Request.URL.Query()
+			query.Set("user_id", claims.Subject)
+			c.Request.URL.RawQuery = query.Encode()
+		}
+
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
+
+// requestClaims reads the request's bearer token, or without one the user it names when
+// trustUserHeader is set
+func requestClaims(c *gin.Context) (*Claims, error) {
+	authorization := c.GetHeader("Authorization")
+	if authorization == "" && trustUserHeader {
+		userID := c.GetHeader("X-User-ID")
+		if userID == "" {
+			userID = c.Query("user_id")
+		}
+		if userID != "" {
+			return &Claims{Role: roleUser, RegisteredClaims: jwt.RegisteredClaims{Subject: userID}}, nil
+		}
+	}
+	return parseToken(strings.TrimPrefix(authorization, "Bearer "))
+}
+
+// requireRole lets through only tokens with one of the roles
+func requireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("claims")
+		if claims, ok := value.(*Claims); ok {
+			for _, role := range roles {
+				if claims.Role == role {
+					c.Next()
+					return
+				}
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You don't have permission to do that"})
+	}
+}
+
+// newServiceRequest builds a request to another service, authenticated as this one
+func newServiceRequest(method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	token, err := currentServiceToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign service token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Service-Name", serviceName)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// currentServiceToken returns this service's token, signing a new one shortly before the last expires
+func currentServiceToken() (string, error) {
+	serviceTokenMu.Lock()
+	defer serviceTokenMu.Unlock()
+
+	if serviceToken != "" && time.Until(serviceTokenExpires) > serviceTokenTTL/10 {
+		return serviceToken, nil
+	}
+
+	expires := time.Now().Add(serviceTokenTTL)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		Role: roleService,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   serviceName,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}).SignedString(jwtKey)
+	if err != nil {
+		return "", err
+	}
+
+	serviceToken, serviceTokenExpires = token, expires
+	return token, nil
+}
@@ -0,0 +1,166 @@
+// dedup.go - Duplicate-send protection per reminder occurrence, channel and recipient
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	dedupSentTTL  = 7 * 24 * time.Hour // how long a delivery is remembered
+	dedupClaimTTL = 10 * time.Minute   // how long an in-flight send holds its claim
+)
+
+// DedupStore remembers which recipients already got a reminder occurrence. A send first claims its
+// key, then confirms it once delivered or releases it on failure so a retry can go ahead.
+type DedupStore interface {
+	Claim(key string) (bool, error)
+	Confirm(key string) error
+	Release(key string) error
+}
+
+var dedupStore DedupStore
+
+// initDedupStore uses Redis when REDIS_URL is set and reachable, otherwise an in-memory store
+// (which only protects against redeliveries to this instance, and forgets on restart). Tenant
+// quotas are counted in the same place.
+func initDedupStore() {
+	redisURL := getEnv("REDIS_URL", "")
+	if redisURL != "" {
+		opts, err := redis.ParseURL(redisURL)
+		if err != nil {
+			log.Printf("Invalid REDIS_URL, using in-memory dedup store: %v", err)
+		} else {
+			client := redis.NewClient(opts)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := client.Ping(ctx).Err(); err != nil {
+				log.Printf("Redis unavailable, using in-memory dedup store: %v", err)
+			} else {
+				dedupStore = &redisDedupStore{client: client}
+				quotaCounter = &redisQuotaCounter{client: client}
+				log.Println("Using Redis dedup store")
+				return
+			}
+		}
+	}
+
+	dedupStore = newMemoryDedupStore()
+	quotaCounter = newMemoryQuotaCounter()
+	log.Println("Using in-memory dedup store")
+}
+
+// dedupKey identifies one delivery: a reminder's occurrence (its due time), or a digest's period,
+// on a channel to a recipient
+func dedupKey(req NotificationRequest, channel, recipient string) string {
+	id := req.ReminderID
+	if req.DigestID != "" {
+		id = "digest:" + req.DigestID
+	}
+	return fmt.Sprintf("notification:sent:%s:%d:%s:%s",
+		id, req.DateTime.Unix(), channel, strings.ToLower(strings.TrimSpace(recipient)))
+}
+
+// claimDelivery reports whether the recipient still needs this message. Manual sends (no reminder or
+// digest) are never deduplicated, and a store error lets the send go ahead: a duplicate beats a lost reminder.
+func claimDelivery(req NotificationRequest, channel, recipient string) (string, bool) {
+	if req.ReminderID == "" && req.DigestID == "" {
+		return "", true
+	}
+
+	key := dedupKey(req, channel, recipient)
+	claimed, err := dedupStore.Claim(key)
+	if err != nil {
+		log.Printf("Error checking dedup store for %s, sending anyway: %v", key, err)
+		return "", true
+	}
+	return key, claimed
+}
+
+// finishDelivery confirms or releases a claim taken by claimDelivery
+func finishDelivery(key string, sent bool) {
+	if key == "" {
+		return
+	}
+
+	var err error
+	if sent {
+		err = dedupStore.Confirm(key)
+	} else {
+		err = dedupStore.Release(key)
+	}
+	if err != nil {
+		log.Printf("Error updating dedup store for %s: %v", key, err)
+	}
+}
+
+type redisDedupStore struct {
+	client *redis.Client
+}
+
+func (s *redisDedupStore) Claim(key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return s.client.SetNX(ctx, key, "sending", dedupClaimTTL).Result()
+}
+
+func (s *redisDedupStore) Confirm(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return s.client.Set(ctx, key, "sent", dedupSentTTL).Err()
+}
+
+func (s *redisDedupStore) Release(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return s.client.Del(ctx, key).Err()
+}
+
+type memoryDedupStore struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+func newMemoryDedupStore() *memoryDedupStore {
+	return &memoryDedupStore{expires: make(map[string]time.Time)}
+}
+
+func (s *memoryDedupStore) Claim(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if expiry, ok := s.expires[key]; ok && expiry.After(now) {
+		return false, nil
+	}
+	// Drop expired entries now and then so the map doesn't grow forever
+	if len(s.expires)%1000 == 0 {
+		for k, expiry := range s.expires {
+			if !expiry.After(now) {
+				delete(s.expires, k)
+			}
+		}
+	}
+	s.expires[key] = now.Add(dedupClaimTTL)
+	return true, nil
+}
+
+func (s *memoryDedupStore) Confirm(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expires[key] = time.Now().Add(dedupSentTTL)
+	return nil
+}
+
+func (s *memoryDedupStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.expires, key)
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// useDedupStore swaps in a store for one test
+func useDedupStore(t *testing.T, store DedupStore) {
+	t.Helper()
+	previous := dedupStore
+	dedupStore = store
+	t.Cleanup(func() { dedupStore = previous })
+}
+
+// failingDedupStore can't be reached
+type failingDedupStore struct{}
+
+func (failingDedupStore) Claim(key string) (bool, error) { return false, errors.New("unreachable") }
+func (failingDedupStore) Confirm(key string) error       { return errors.New("unreachable") }
+func (failingDedupStore) Release(key string) error       { return errors.New("unreachable") }
+
+var dedupDueTime = time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+
+func TestDedupKey(t *testing.T) {
+	req := NotificationRequest{ReminderID: "r1", DateTime: dedupDueTime}
+	key := dedupKey(req, "email", "Me@Example.com ")
+
+	tests := []struct {
+		name      string
+		req       NotificationRequest
+		channel   string
+		recipient string
+		same      bool
+	}{
+		{"same delivery, address case and spacing aside", req, "email", "me@example.com", true},
+		{"another recipient", req, "email", "you@example.com", false},
+		{"another channel", req, "sms", "me@example.com", false},
+		{"the next occurrence", NotificationRequest{ReminderID: "r1", DateTime: dedupDueTime.Add(24 * time.Hour)}, "email", "me@example.com", false},
+		{"another reminder", NotificationRequest{ReminderID: "r2", DateTime: dedupDueTime}, "email", "me@example.com", false},
+		{"a digest with the same ID", NotificationRequest{DigestID: "r1", DateTime: dedupDueTime}, "email", "me@example.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := dedupKey(tt.req, tt.channel, tt.recipient) == key; got != tt.same {
+			t.Errorf("%s: same key = %v, want %v", tt.name, got, tt.same)
+		}
+	}
+}
+
+func TestClaimAndConfirmDelivery(t *testing.T) {
+	useDedupStore(t, newMemoryDedupStore())
+	req := NotificationRequest{ReminderID: "r1", DateTime: dedupDueTime}
+
+	key, needed := claimDelivery(req, "email", "me@example.com")
+	if !needed || key == "" {
+		t.Fatalf("first claim: key %q, needed %v; want a claim", key, needed)
+	}
+
+	// While the send is in flight a redelivery is held off
+	if _, needed := claimDelivery(req, "email", "me@example.com"); needed {
+		t.Error("claim while in flight: needed, want skipped")
+	}
+
+	// Once confirmed it stays sent
+	finishDelivery(key, true)
+	if _, needed := claimDelivery(req, "email", "me@example.com"); needed {
+		t.Error("claim after confirm: needed, want skipped")
+	}
+
+	// Other recipients aren't affected
+	if _, needed := claimDelivery(req, "email", "you@example.com"); !needed {
+		t.Error("claim for another recipient: skipped, want needed")
+	}
+}
+
+func TestReleaseDeliveryAllowsRetry(t *testing.T) {
+	useDedupStore(t, newMemoryDedupStore())
+	req := NotificationRequest{ReminderID: "r1", DateTime: dedupDueTime}
+
+	key, _ := claimDelivery(req, "sms", "+15550100")
+	finishDelivery(key, false)
+
+	if _, needed := claimDelivery(req, "sms", "+15550100"); !needed {
+		t.Error("claim after a failed send: skipped, want needed")
+	}
+}
+
+func TestClaimDeliveryWithoutReminder(t *testing.T) {
+	useDedupStore(t, newMemoryDedupStore())
+	req := NotificationRequest{Title: "Manual", DateTime: dedupDueTime}
+
+	// Manual sends are never deduplicated
+	for i := 0; i < 2; i++ {
+		if key, needed := claimDelivery(req, "email", "me@example.com"); !needed || key != "" {
+			t.Errorf("manual send %d: key %q, needed %v; want sent without a claim", i, key, needed)
+		}
+	}
+}
+
+func TestClaimDeliveryStoreDown(t *testing.T) {
+	useDedupStore(t, failingDedupStore{})
+	req := NotificationRequest{ReminderID: "r1", DateTime: dedupDueTime}
+
+	// A duplicate beats a lost reminder
+	key, needed := claimDelivery(req, "email", "me@example.com")
+	if !needed || key != "" {
+		t.Errorf("claim with the store down: key %q, needed %v; want sent without a claim", key, needed)
+	}
+	finishDelivery(key, true)
+}
+
+func TestMemoryDedupStoreExpiry(t *testing.T) {
+	store := newMemoryDedupStore()
+
+	if claimed, _ := store.Claim("k"); !claimed {
+		t.Fatal("first claim refused")
+	}
+	// An abandoned claim lapses
+	store.expires["k"] = time.Now().Add(-time.Second)
+	if claimed, _ := store.Claim("k"); !claimed {
+		t.Error("claim after the previous one lapsed refused")
+	}
+}
@@ -0,0 +1,133 @@
+// digest.go - Digest notifications: several reminders in one message
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+const digestTemplate = "digest"
+
+// DigestItem is one reminder listed in a digest (matches scheduler service)
+type DigestItem struct {
+	ReminderID  string    `json:"reminder_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	DateTime    time.Time `json:"datetime"`
+	Priority    string    `json:"priority"`
+}
+
+var digestEmailTemplate = template.Must(template.New("digest").Parse(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #4F46E5; color: white; padding: 20px; border-radius: 5px; }
+        .content { padding: 20px; background-color: #f9f9f9; border-radius: 5px; margin-top: 20px; }
+        .item { padding: 10px 0; border-bottom: 1px solid #e5e5e5; }
+        .item:last-child { border-bottom: none; }
+        .time { font-weight: bold; color: #4F46E5; }
+        .high { color: #DC2626; font-size: 12px; text-transform: uppercase; }
+        .footer { margin-top: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h2>📋 {{.Title}}</h2>
+        </div>
+        <div class="content">
+            {{range .Items}}
+            <div class="item">
+                <span class="time">{{.Time}}</span> {{.Title}}
+                {{if .High}}<span class="high">{{.Priority}}</span>{{end}}
+                {{if .Description}}<br>{{.Description}}{{end}}
+            </div>
+            {{end}}
+        </div>
+        <div class="footer">
+            <p>This is your reminder digest from your Reminder System. These reminders won't be sent again one by one.</p>
+        </div>
+    </div>
+</body>
+</html>
+`))
+
+// digestItemView is a digest item ready for display
+type digestItemView struct {
+	Time        string
+	Title       string
+	Description string
+	Priority    string
+	High        bool
+}
+
+// digestLocation is the zone a digest's item times are shown in
+func digestLocation(req NotificationRequest) *time.Location {
+	loc, err := time.LoadLocation(req.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// digestTimeLayout shows the weekday too when the digest spans more than a day
+func digestTimeLayout(req NotificationRequest) string {
+	if len(req.Items) > 0 && req.Items[len(req.Items)-1].DateTime.Sub(req.Items[0].DateTime) >= 24*time.Hour {
+		return "Mon 3:04 PM"
+	}
+	return "3:04 PM"
+}
+
+func generateDigestEmailBody(req NotificationRequest) (string, error) {
+	loc := digestLocation(req)
+	layout := digestTimeLayout(req)
+
+	items := make([]digestItemView, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, digestItemView{
+			Time:        item.DateTime.In(loc).Format(layout),
+			Title:       item.Title,
+			Description: item.Description,
+			Priority:    item.Priority,
+			High:        item.Priority == "high",
+		})
+	}
+
+	var buf bytes.Buffer
+	err := digestEmailTemplate.Execute(&buf, struct {
+		Title string
+		Items []digestItemView
+	}{req.Title, items})
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// digestSMSBody lists the digest's reminders one per line
+func digestSMSBody(req NotificationRequest) string {
+	loc := digestLocation(req)
+	layout := digestTimeLayout(req)
+
+	lines := []string{req.Title + ":"}
+	for _, item := range req.Items {
+		lines = append(lines, fmt.Sprintf("%s %s", item.DateTime.In(loc).Format(layout), item.Title))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// digestReminderIDs lists the reminders a digest covered
+func digestReminderIDs(req NotificationRequest) []string {
+	ids := make([]string, 0, len(req.Items))
+	for _, item := range req.Items {
+		ids = append(ids, item.ReminderID)
+	}
+	return ids
+}
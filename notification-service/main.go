@@ -19,17 +19,33 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// serviceName is who this service is in its service token and X-Service-Name (see auth.go)
+const serviceName = "notification-service"
+
 // NotificationRequest from RabbitMQ or HTTP
 type NotificationRequest struct {
-	ReminderID       string    `json:"reminder_id"`
-	Title            string    `json:"title"`
-	Description      string    `json:"description"`
-	DateTime         time.Time `json:"datetime"`
-	NotificationType string    `json:"notification_type"` // email or sms
-	Email            string    `json:"email,omitempty"`
-	Phone            string    `json:"phone,omitempty"`
+	ReminderID       string       `json:"reminder_id"`
+	Title            string       `json:"title"`
+	Description      string       `json:"description"`
+	DateTime         time.Time    `json:"datetime"`
+	NotificationType string       `json:"notification_type"` // email or sms
+	Email            string       `json:"email,omitempty"`
+	Phone            string       `json:"phone,omitempty"`
+	Priority         string       `json:"priority,omitempty"` // low, normal, high, critical
+	DigestID         string       `json:"digest_id,omitempty"`
+	Template         string       `json:"template,omitempty"` // "digest" for a digest of Items
+	Items            []DigestItem `json:"items,omitempty"`
+	Timezone         string       `json:"timezone,omitempty"` // zone item times are shown in
+	OrgID            string       `json:"org_id,omitempty"`   // sent with the organization's credentials and counted against its quota
 }
 
+// Queues (matches scheduler service)
+const (
+	notificationsQueue         = "notifications"
+	priorityNotificationsQueue = "notifications.priority"
+	maxMessagePriority         = 10
+)
+
 // Email configuration
 type EmailConfig struct {
 	Host     string
@@ -53,21 +69,31 @@ var (
 	smsConfig      SMSConfig
 	rabbitConn     *amqp.Connection
 	rabbitCh       *amqp.Channel
+	priorityCh     *amqp.Channel
 	emailRateLimit = make(chan struct{}, 1) // Rate limit for Gmail
 	emailMutex     sync.Mutex
+
+	reminderServiceURL string
+	userServiceURL     string
 )
 
 func main() {
 	// Load configurations
 	loadConfigs()
 
+	// Remember deliveries so redelivered messages aren't sent twice
+	initDedupStore()
+
 	// Initialize RabbitMQ
 	initRabbitMQ()
 	defer rabbitConn.Close()
 	defer rabbitCh.Close()
+	defer priorityCh.Close()
 
-	// Start consuming from queue
-	go consumeNotifications()
+	// Start consuming from queues; the priority queue has its own worker so
+	// high and critical notifications never wait behind bulk sends
+	go consumeNotifications(priorityCh, priorityNotificationsQueue)
+	go consumeNotifications(rabbitCh, notificationsQueue)
 
 	// Initialize Gin router
 	router := gin.Default()
@@ -75,8 +101,9 @@ func main() {
 	// Health check
 	router.GET("/health", healthCheck)
 
-	// Manual notification endpoints (for testing)
+	// Manual notification endpoints (for testing) - they send straight away, so admins only
 	api := router.Group("/api/notifications")
+	api.Use(authMiddleware(), requireRole(roleAdmin, roleService))
 	{
 		api.POST("/email", sendEmailHandler)
 		api.POST("/sms", sendSMSHandler)
@@ -95,6 +122,9 @@ func main() {
 }
 
 func loadConfigs() {
+	// Key tokens are signed with, shared by all services
+	initAuth()
+
 	// Email config (SMTP for your home mail server)
 	emailConfig = EmailConfig{
 		Host:     getEnv("SMTP_HOST", "localhost"),
@@ -113,6 +143,12 @@ func loadConfigs() {
 		TwilioURL:  "https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json",
 	}
 
+	// Delivery failures are reported back to the reminder service
+	reminderServiceURL = getEnv("REMINDER_SERVICE_URL", "http://reminder-service:8081")
+
+	// Organizations' own credentials and quotas come from the user service
+	userServiceURL = getEnv("USER_SERVICE_URL", "http://user-service:8084")
+
 	log.Println("Configuration loaded successfully")
 }
 
@@ -130,96 +166,169 @@ func initRabbitMQ() {
 		log.Fatal("Failed to open channel:", err)
 	}
 
+	priorityCh, err = rabbitConn.Channel()
+	if err != nil {
+		log.Fatal("Failed to open priority channel:", err)
+	}
+
 	// Declare queue
 	_, err = rabbitCh.QueueDeclare(
-		"notifications", // queue name
-		true,            // durable
-		false,           // delete when unused
-		false,           // exclusive
-		false,           // no-wait
-		nil,             // arguments
+		notificationsQueue, // queue name
+		true,               // durable
+		false,              // delete when unused
+		false,              // exclusive
+		false,              // no-wait
+		nil,                // arguments
 	)
 	if err != nil {
 		log.Fatal("Failed to declare queue:", err)
 	}
 
+	// Declare priority queue
+	_, err = priorityCh.QueueDeclare(
+		priorityNotificationsQueue, // queue name
+		true,                       // durable
+		false,                      // delete when unused
+		false,                      // exclusive
+		false,                      // no-wait
+		amqp.Table{"x-max-priority": maxMessagePriority}, // arguments
+	)
+	if err != nil {
+		log.Fatal("Failed to declare priority queue:", err)
+	}
+
+	// Fetch one message at a time so the broker can reorder waiting messages by priority
+	for _, ch := range []*amqp.Channel{rabbitCh, priorityCh} {
+		if err := ch.Qos(1, 0, false); err != nil {
+			log.Fatal("Failed to set QoS:", err)
+		}
+	}
+
 	log.Println("RabbitMQ connected successfully")
 }
 
-func consumeNotifications() {
-	msgs, err := rabbitCh.Consume(
-		"notifications", // queue
-		"",              // consumer
-		false,           // auto-ack
-		false,           // exclusive
-		false,           // no-local
-		false,           // no-wait
-		nil,             // args
+func consumeNotifications(ch *amqp.Channel, queue string) {
+	msgs, err := ch.Consume(
+		queue, // queue
+		"",    // consumer
+		false, // auto-ack
+		false, // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,   // args
 	)
 	if err != nil {
 		log.Fatal("Failed to register consumer:", err)
 	}
 
-	log.Println("Waiting for notification messages...")
+	log.Printf("Waiting for notification messages on %s...", queue)
 
 	for msg := range msgs {
-		var req NotificationRequest
-		if err := json.Unmarshal(msg.Body, &req); err != nil {
-			log.Printf("Error parsing message: %v", err)
-			msg.Nack(false, false) // Don't requeue malformed messages
-			continue
-		}
-
-		log.Printf("Processing notification: %s (%s) scheduled for: %s", req.ReminderID, req.NotificationType, req.DateTime.Format("2006-01-02 15:04:05"))
+		processNotification(msg)
+	}
+}
 
-		// Check if this notification is for a future reminder (shouldn't be sent yet)
-		if req.DateTime.After(time.Now()) {
-			log.Printf("Notification %s is for future time %s, discarding (should not have been queued)", req.ReminderID, req.DateTime.Format("2006-01-02 15:04:05"))
-			msg.Ack(false) // Acknowledge to remove from queue
-			continue
-		}
+func processNotification(msg amqp.Delivery) {
+	var req NotificationRequest
+	if err := json.Unmarshal(msg.Body, &req); err != nil {
+		log.Printf("Error parsing message: %v", err)
+		msg.Nack(false, false) // Don't requeue malformed messages
+		return
+	}
 
-		var err error
-		maxRetries := 3
+	log.Printf("Processing notification: %s (%s) scheduled for: %s", req.ReminderID, req.NotificationType, req.DateTime.Format("2006-01-02 15:04:05"))
 
-		for attempt := 1; attempt <= maxRetries; attempt++ {
-			if req.NotificationType == "email" {
-				err = sendEmail(req)
-			} else if req.NotificationType == "sms" {
-				err = sendSMS(req)
-			}
+	// Check if this notification is for a future reminder (shouldn't be sent yet)
+	if req.DateTime.After(time.Now()) {
+		log.Printf("Notification %s is for future time %s, discarding (should not have been queued)", req.ReminderID, req.DateTime.Format("2006-01-02 15:04:05"))
+		msg.Ack(false) // Acknowledge to remove from queue
+		return
+	}
 
-			if err == nil {
-				break // Success, exit retry loop
-			}
+	var err error
+	maxRetries := 3
 
-			log.Printf("Attempt %d/%d failed for notification %s: %v", attempt, maxRetries, req.ReminderID, err)
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if req.NotificationType == "email" {
+			err = sendEmail(req)
+		} else if req.NotificationType == "sms" {
+			err = sendSMS(req)
+		}
 
-			if attempt < maxRetries {
-				// Wait before retrying, with exponential backoff
-				waitTime := time.Duration(attempt*attempt) * 5 * time.Second
-				log.Printf("Waiting %v before retry...", waitTime)
-				time.Sleep(waitTime)
-			}
+		if err == nil {
+			break // Success, exit retry loop
+		}
+		if strings.Contains(err.Error(), errQuotaExceeded.Error()) {
+			break // Retrying won't help until tomorrow
 		}
 
-		if err != nil {
-			log.Printf("Failed to send notification after %d attempts: %v", maxRetries, err)
+		log.Printf("Attempt %d/%d failed for notification %s: %v", attempt, maxRetries, req.ReminderID, err)
 
-			// Check if it's a Gmail rate limiting error - don't requeue these
-			if strings.Contains(err.Error(), "Too many login attempts") || strings.Contains(err.Error(), "454 4.7.0") {
-				log.Printf("Gmail rate limiting detected, discarding message %s to prevent infinite loop", req.ReminderID)
-				msg.Ack(false) // Acknowledge to remove from queue
-			} else {
-				msg.Nack(false, false) // Don't requeue after max retries to avoid infinite loop
+		if attempt < maxRetries {
+			// Wait before retrying, with exponential backoff
+			waitTime := time.Duration(attempt*attempt) * 5 * time.Second
+			log.Printf("Waiting %v before retry...", waitTime)
+			time.Sleep(waitTime)
+		}
+	}
+
+	if err != nil {
+		log.Printf("Failed to send notification after %d attempts: %v", maxRetries, err)
+		// A digest's failure belongs to each reminder it listed
+		reminderIDs := []string{req.ReminderID}
+		if req.Template == digestTemplate {
+			reminderIDs = digestReminderIDs(req)
+		}
+		for _, reminderID := range reminderIDs {
+			if reportErr := reportDeliveryFailure(reminderID, err); reportErr != nil {
+				log.Printf("Error reporting delivery failure for %s: %v", reminderID, reportErr)
 			}
+		}
+
+		// Check if it's a Gmail rate limiting error - don't requeue these
+		if strings.Contains(err.Error(), "Too many login attempts") || strings.Contains(err.Error(), "454 4.7.0") {
+			log.Printf("Gmail rate limiting detected, discarding message %s to prevent infinite loop", req.ReminderID)
+			msg.Ack(false) // Acknowledge to remove from queue
 		} else {
-			log.Printf("Notification sent successfully: %s", req.ReminderID)
-			msg.Ack(false)
+			msg.Nack(false, false) // Don't requeue after max retries to avoid infinite loop
 		}
+	} else {
+		log.Printf("Notification sent successfully: %s", req.ReminderID)
+		msg.Ack(false)
 	}
 }
 
+// reportDeliveryFailure records the error on the reminder so it shows up for triage and retry
+func reportDeliveryFailure(reminderID string, cause error) error {
+	if reminderID == "" {
+		return nil // manual sends through the HTTP endpoints have no reminder
+	}
+
+	url := fmt.Sprintf("%s/api/reminders/%s/delivery-failure", reminderServiceURL, reminderID)
+	jsonData, err := json.Marshal(map[string]string{"error": cause.Error()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := newServiceRequest("POST", url, bytes.NewBuffer(jsonData)) // the service name shows up as the actor in reminder history
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
 func sendEmail(req NotificationRequest) error {
 	// Parse multiple email addresses
 	emailAddresses := parseEmailAddresses(req.Email)
@@ -230,30 +339,58 @@ func sendEmail(req NotificationRequest) error {
 	log.Printf("Sending email to %d recipients: %s", len(emailAddresses), strings.Join(emailAddresses, ", "))
 
 	// Create email body from template
+	subject := fmt.Sprintf("Reminder: %s", req.Title)
 	emailBody, err := generateEmailBody(req)
+	if req.Template == digestTemplate {
+		subject = req.Title
+		emailBody, err = generateDigestEmailBody(req)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to generate email body: %w", err)
 	}
 
+	// The reminder's organization may send through its own mail server
+	settings, err := tenantSettings(req.OrgID)
+	if err != nil {
+		return err
+	}
+	mailConfig := emailConfigFor(settings)
+
 	// Send to each recipient individually to avoid Gmail rate limits
 	// Use a single SMTP connection with delays between sends
-	auth := smtp.PlainAuth("", emailConfig.Username, emailConfig.Password, emailConfig.Host)
-	addr := fmt.Sprintf("%s:%s", emailConfig.Host, emailConfig.Port)
+	auth := smtp.PlainAuth("", mailConfig.Username, mailConfig.Password, mailConfig.Host)
+	addr := fmt.Sprintf("%s:%s", mailConfig.Host, mailConfig.Port)
 
 	var failedRecipients []string
 	successCount := 0
 
 	for i, recipient := range emailAddresses {
-		// Add significant delay between emails to avoid Gmail rate limiting
-		if i > 0 {
+		// A redelivered message skips recipients who already got this occurrence
+		dedupKey, needed := claimDelivery(req, "email", recipient)
+		if !needed {
+			log.Printf("Email for reminder %s already sent to %s, skipping", req.ReminderID, recipient)
+			successCount++
+			continue
+		}
+
+		var quotaKey string
+		quotaKey, err = reserveQuota(settings, "email")
+		if err != nil {
+			log.Printf("Not sending email to %s: %v", recipient, err)
+			failedRecipients = append(failedRecipients, recipient)
+			finishDelivery(dedupKey, false)
+			continue
+		}
+
+		// Add significant delay between emails to avoid Gmail rate limiting (critical ones can't wait)
+		if i > 0 && req.Priority != "critical" {
 			log.Printf("Waiting 30 seconds before sending to next recipient...")
 			time.Sleep(30 * time.Second)
 		}
 
 		// Email headers for individual recipient
-		subject := fmt.Sprintf("Reminder: %s", req.Title)
 		headers := make(map[string]string)
-		headers["From"] = emailConfig.From
+		headers["From"] = mailConfig.From
 		headers["To"] = recipient
 		headers["Subject"] = subject
 		headers["MIME-Version"] = "1.0"
@@ -272,7 +409,7 @@ func sendEmail(req NotificationRequest) error {
 		sent := false
 
 		for attempt := 0; attempt <= maxRateLimitRetries && !sent; attempt++ {
-			err = smtp.SendMail(addr, auth, emailConfig.From, []string{recipient}, message.Bytes())
+			err = smtp.SendMail(addr, auth, mailConfig.From, []string{recipient}, message.Bytes())
 
 			if err == nil {
 				log.Printf("Email sent successfully to: %s", recipient)
@@ -295,6 +432,10 @@ func sendEmail(req NotificationRequest) error {
 				break
 			}
 		}
+		if !sent {
+			releaseQuota(quotaKey)
+		}
+		finishDelivery(dedupKey, sent)
 	}
 
 	// Report results
@@ -312,20 +453,72 @@ func sendEmail(req NotificationRequest) error {
 }
 
 func sendSMS(req NotificationRequest) error {
-	if smsConfig.AccountSID == "" || smsConfig.AuthToken == "" {
+	// The reminder's organization may send through its own Twilio account
+	settings, err := tenantSettings(req.OrgID)
+	if err != nil {
+		return err
+	}
+	config := smsConfigFor(settings)
+	if config.AccountSID == "" || config.AuthToken == "" {
 		return fmt.Errorf("Twilio credentials not configured")
 	}
 
+	// Parse multiple phone numbers (contact groups may expand to several)
+	phoneNumbers := parsePhoneNumbers(req.Phone)
+	if len(phoneNumbers) == 0 {
+		return fmt.Errorf("no valid phone numbers found")
+	}
+
 	// Create SMS body
 	smsBody := fmt.Sprintf("Reminder: %s\n%s\nScheduled for: %s",
 		req.Title,
 		req.Description,
 		req.DateTime.Format("Jan 02, 2006 at 3:04 PM"),
 	)
+	if req.Template == digestTemplate {
+		smsBody = digestSMSBody(req)
+	}
+
+	var failedRecipients []string
+
+	for _, phone := range phoneNumbers {
+		// A redelivered message skips recipients who already got this occurrence
+		dedupKey, needed := claimDelivery(req, "sms", phone)
+		if !needed {
+			log.Printf("SMS for reminder %s already sent to %s, skipping", req.ReminderID, phone)
+			continue
+		}
+
+		var quotaKey string
+		quotaKey, err = reserveQuota(settings, "sms")
+		if err == nil {
+			err = sendSMSTo(config, phone, smsBody)
+			if err != nil {
+				releaseQuota(quotaKey)
+			}
+		}
+		finishDelivery(dedupKey, err == nil)
+		if err != nil {
+			log.Printf("Failed to send SMS to %s: %v", phone, err)
+			failedRecipients = append(failedRecipients, phone)
+		}
+	}
 
+	if len(failedRecipients) == len(phoneNumbers) {
+		return fmt.Errorf("failed to send SMS to all %d recipients. Last error: %v", len(phoneNumbers), err)
+	}
+	if len(failedRecipients) > 0 {
+		log.Printf("Partial success: %d/%d SMS sent. Failed recipients: %s",
+			len(phoneNumbers)-len(failedRecipients), len(phoneNumbers), strings.Join(failedRecipients, ", "))
+	}
+
+	return nil
+}
+
+func sendSMSTo(config SMSConfig, phone, smsBody string) error {
 	// Prepare Twilio API request
-	apiURL := fmt.Sprintf(smsConfig.TwilioURL, smsConfig.AccountSID)
-	data := fmt.Sprintf("To=%s&From=%s&Body=%s", req.Phone, smsConfig.FromPhone, smsBody)
+	apiURL := fmt.Sprintf(config.TwilioURL, config.AccountSID)
+	data := fmt.Sprintf("To=%s&From=%s&Body=%s", phone, config.FromPhone, smsBody)
 
 	client := &http.Client{}
 	httpReq, err := http.NewRequest("POST", apiURL, bytes.NewBufferString(data))
@@ -333,7 +526,7 @@ func sendSMS(req NotificationRequest) error {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpReq.SetBasicAuth(smsConfig.AccountSID, smsConfig.AuthToken)
+	httpReq.SetBasicAuth(config.AccountSID, config.AuthToken)
 	httpReq.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 
 	resp, err := client.Do(httpReq)
@@ -361,6 +554,7 @@ func generateEmailBody(req NotificationRequest) (string, error) {
         .content { padding: 20px; background-color: #f9f9f9; border-radius: 5px; margin-top: 20px; }
         .footer { margin-top: 20px; font-size: 12px; color: #666; }
         .datetime { font-weight: bold; color: #4F46E5; font-size: 18px; }
+        .description { white-space: pre-line; }
     </style>
 </head>
 <body>
@@ -370,7 +564,7 @@ func generateEmailBody(req NotificationRequest) (string, error) {
         </div>
         <div class="content">
             <h3>{{.Title}}</h3>
-            <p>{{.Description}}</p>
+            <p class="description">{{.Description}}</p>
             <p class="datetime">📅 {{.DateTimeFormatted}}</p>
         </div>
         <div class="footer">
@@ -475,6 +669,18 @@ func parseEmailAddresses(emailString string) []string {
 	return validEmails
 }
 
+func parsePhoneNumbers(phoneString string) []string {
+	var phoneNumbers []string
+	for _, phone := range strings.FieldsFunc(phoneString, func(c rune) bool {
+		return c == ',' || c == ';'
+	}) {
+		if phone = strings.TrimSpace(phone); phone != "" {
+			phoneNumbers = append(phoneNumbers, phone)
+		}
+	}
+	return phoneNumbers
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -0,0 +1,237 @@
+// tenant.go - Per-organization sending: each tenant's own SMTP and Twilio credentials, and daily quotas
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	tenantSettingsTTL = 5 * time.Minute
+	quotaCounterTTL   = 48 * time.Hour // a day's count outlives the day, whatever the zone
+)
+
+var errQuotaExceeded = errors.New("daily sending quota exceeded")
+
+// DeliverySettings structure (matches user service); empty fields fall back to the system's own
+type DeliverySettings struct {
+	OrgID            string `json:"org_id"`
+	SMTPHost         string `json:"smtp_host,omitempty"`
+	SMTPPort         string `json:"smtp_port,omitempty"`
+	SMTPUsername     string `json:"smtp_username,omitempty"`
+	SMTPPassword     string `json:"smtp_password,omitempty"`
+	SMTPFrom         string `json:"smtp_from,omitempty"`
+	TwilioAccountSID string `json:"twilio_account_sid,omitempty"`
+	TwilioAuthToken  string `json:"twilio_auth_token,omitempty"`
+	TwilioFromPhone  string `json:"twilio_from_phone,omitempty"`
+	EmailDailyQuota  int    `json:"email_daily_quota"` // 0 = unlimited
+	SMSDailyQuota    int    `json:"sms_daily_quota"`
+}
+
+type cachedSettings struct {
+	settings DeliverySettings
+	expires  time.Time
+}
+
+var (
+	settingsCache   = make(map[string]cachedSettings)
+	settingsCacheMu sync.Mutex
+)
+
+// tenantSettings returns the organization's delivery settings; no organization means the system's.
+// Lookups are cached for a few minutes.
+func tenantSettings(orgID string) (DeliverySettings, error) {
+	if orgID == "" {
+		return DeliverySettings{}, nil
+	}
+
+	settingsCacheMu.Lock()
+	cached, ok := settingsCache[orgID]
+	settingsCacheMu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.settings, nil
+	}
+
+	settings, err := fetchDeliverySettings(orgID)
+	if err != nil {
+		return DeliverySettings{}, err
+	}
+
+	settingsCacheMu.Lock()
+	settingsCache[orgID] = cachedSettings{settings: settings, expires: time.Now().Add(tenantSettingsTTL)}
+	settingsCacheMu.Unlock()
+	return settings, nil
+}
+
+func fetchDeliverySettings(orgID string) (DeliverySettings, error) {
+	url := fmt.Sprintf("%s/api/orgs/%s/delivery-settings", userServiceURL, orgID)
+
+	req, err := newServiceRequest("GET", url, nil)
+	if err != nil {
+		return DeliverySettings{}, err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return DeliverySettings{}, fmt.Errorf("failed to fetch delivery settings: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return DeliverySettings{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var settings DeliverySettings
+	if err := json.NewDecoder(resp.Body).Decode(&settings); err != nil {
+		return DeliverySettings{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return settings, nil
+}
+
+// emailConfigFor uses the tenant's mail server when it has one
+func emailConfigFor(settings DeliverySettings) EmailConfig {
+	if settings.SMTPHost == "" {
+		return emailConfig
+	}
+
+	config := EmailConfig{
+		Host:     settings.SMTPHost,
+		Port:     settings.SMTPPort,
+		Username: settings.SMTPUsername,
+		Password: settings.SMTPPassword,
+		From:     settings.SMTPFrom,
+		TLS:      emailConfig.TLS,
+	}
+	if config.Port == "" {
+		config.Port = "587"
+	}
+	if config.From == "" {
+		config.From = emailConfig.From
+	}
+	return config
+}
+
+// smsConfigFor uses the tenant's Twilio account when it has one
+func smsConfigFor(settings DeliverySettings) SMSConfig {
+	if settings.TwilioAccountSID == "" {
+		return smsConfig
+	}
+
+	return SMSConfig{
+		AccountSID: settings.TwilioAccountSID,
+		AuthToken:  settings.TwilioAuthToken,
+		FromPhone:  settings.TwilioFromPhone,
+		TwilioURL:  smsConfig.TwilioURL,
+	}
+}
+
+// QuotaCounter counts each tenant's sends per channel and day
+type QuotaCounter interface {
+	Increment(key string) (int64, error)
+	Decrement(key string) error
+}
+
+var quotaCounter QuotaCounter
+
+func quotaCounterKey(orgID, channel string) string {
+	return fmt.Sprintf("notification:quota:%s:%s:%s", orgID, channel, time.Now().UTC().Format("2006-01-02"))
+}
+
+// reserveQuota takes one send from the tenant's daily quota for the channel, or returns
+// errQuotaExceeded once it's used up. A counter error lets the send go ahead.
+func reserveQuota(settings DeliverySettings, channel string) (string, error) {
+	limit := settings.EmailDailyQuota
+	if channel == "sms" {
+		limit = settings.SMSDailyQuota
+	}
+	if settings.OrgID == "" || limit <= 0 {
+		return "", nil
+	}
+
+	key := quotaCounterKey(settings.OrgID, channel)
+	count, err := quotaCounter.Increment(key)
+	if err != nil {
+		log.Printf("Error counting quota for %s, sending anyway: %v", key, err)
+		return "", nil
+	}
+	if count > int64(limit) {
+		releaseQuota(key)
+		return "", fmt.Errorf("%w: organization %s, %s, %d per day", errQuotaExceeded, settings.OrgID, channel, limit)
+	}
+	return key, nil
+}
+
+// releaseQuota gives back a send that didn't go out
+func releaseQuota(key string) {
+	if key == "" {
+		return
+	}
+	if err := quotaCounter.Decrement(key); err != nil {
+		log.Printf("Error releasing quota for %s: %v", key, err)
+	}
+}
+
+type redisQuotaCounter struct {
+	client *redis.Client
+}
+
+func (q *redisQuotaCounter) Increment(key string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	count, err := q.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		q.client.Expire(ctx, key, quotaCounterTTL)
+	}
+	return count, nil
+}
+
+func (q *redisQuotaCounter) Decrement(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return q.client.Decr(ctx, key).Err()
+}
+
+type memoryQuotaCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func newMemoryQuotaCounter() *memoryQuotaCounter {
+	return &memoryQuotaCounter{counts: make(map[string]int64)}
+}
+
+func (q *memoryQuotaCounter) Increment(key string) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// Yesterday's keys are done with
+	today := time.Now().UTC().Format("2006-01-02")
+	for k := range q.counts {
+		if !strings.HasSuffix(k, today) {
+			delete(q.counts, k)
+		}
+	}
+	q.counts[key]++
+	return q.counts[key], nil
+}
+
+func (q *memoryQuotaCounter) Decrement(key string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.counts[key]--
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// authRouter serves GET /whoami behind authMiddleware, answering with the user it settled on
+func authRouter(t *testing.T, trustHeader bool) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	previousKey, previousTrust := jwtKey, trustUserHeader
+	jwtKey, trustUserHeader = []byte("test-secret"), trustHeader
+	t.Cleanup(func() { jwtKey, trustUserHeader = previousKey, previousTrust })
+
+	router := gin.New()
+	router.GET("/whoami", authMiddleware(), func(c *gin.Context) {
+		c.String(http.StatusOK, getUserID(c))
+	})
+	return router
+}
+
+func userToken(t *testing.T, userID string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		Role: roleUser,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString(jwtKey)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+func whoami(router *gin.Engine, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	router := authRouter(t, false)
+
+	if w := whoami(router, map[string]string{"X-User-ID": "default-user"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("header without a token = %d, want 401", w.Code)
+	}
+
+	// The token's subject wins over the header
+	w := whoami(router, map[string]string{"Authorization": "Bearer " + userToken(t, "u1"), "X-User-ID": "u2"})
+	if w.Code != http.StatusOK || w.Body.String() != "u1" {
+		t.Errorf("token for u1 claiming u2 = %d %q, want 200 u1", w.Code, w.Body)
+	}
+}
+
+func TestAuthMiddlewareTrustUserHeader(t *testing.T) {
+	router := authRouter(t, true)
+
+	w := whoami(router, map[string]string{"X-User-ID": "default-user"})
+	if w.Code != http.StatusOK || w.Body.String() != "default-user" {
+		t.Errorf("trusted header = %d %q, want 200 default-user", w.Code, w.Body)
+	}
+
+	// Nobody named is still nobody
+	if w := whoami(router, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("no token and no user = %d, want 401", w.Code)
+	}
+
+	// A bad token isn't excused by the header
+	if w := whoami(router, map[string]string{"Authorization": "Bearer nonsense", "X-User-ID": "u1"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("bad token = %d, want 401", w.Code)
+	}
+}
+
+func TestTrustedHeaderIsNeverAService(t *testing.T) {
+	authRouter(t, true)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/?user_id=u1", nil)
+
+	claims, err := requestClaims(c)
+	if err != nil || claims.Role != roleUser || claims.Subject != "u1" {
+		t.Errorf("claims = %+v, err %v; want an ordinary user u1", claims, err)
+	}
+}
+
+// Every service has its own copy of auth.go; they must not drift apart
+func TestAuthCopiesIdentical(t *testing.T) {
+	ours, err := os.ReadFile("auth.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, service := range []string{"user-service", "scheduler-service", "notification-service"} {
+		theirs, err := os.ReadFile("../" + service + "/auth.go")
+		if os.IsNotExist(err) {
+			t.Skipf("%s isn't checked out alongside", service)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ours, theirs) {
+			t.Errorf("%s/auth.go differs from reminder-service's", service)
+		}
+	}
+}
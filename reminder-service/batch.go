@@ -0,0 +1,232 @@
+// batch.go - Apply one operation to many reminders at once
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxBatchSize = 1000
+
+// BatchRequest DTO - targets either explicit IDs or every reminder matching a filter
+type BatchRequest struct {
+	Operation        string          `json:"operation" binding:"required,oneof=delete reschedule set_channel retry"`
+	IDs              []string        `json:"ids"`
+	Filter           *ReminderFilter `json:"filter"`
+	ShiftMinutes     int             `json:"shift_minutes"`                                         // reschedule: minutes to move by, may be negative (1440 = +1 day)
+	NotificationType string          `json:"notification_type" binding:"omitempty,oneof=email sms"` // set_channel
+	Email            string          `json:"email"`                                                 // set_channel: optional new address
+	Phone            string          `json:"phone"`                                                 // set_channel: optional new number
+}
+
+// BatchItemResult is the outcome for one reminder
+type BatchItemResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"` // ok or error
+	Error  string `json:"error,omitempty"`
+}
+
+// batchReminders applies the operation inside a single transaction. Reminders the operation
+// doesn't apply to are reported per item and left alone; a database error rolls everything back.
+func batchReminders(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (len(req.IDs) == 0) == (req.Filter == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either ids or filter"})
+		return
+	}
+	if len(req.IDs) > maxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d ids per batch", maxBatchSize)})
+		return
+	}
+	switch req.Operation {
+	case "reschedule":
+		if req.ShiftMinutes == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "shift_minutes is required for reschedule"})
+			return
+		}
+	case "set_channel":
+		if req.NotificationType == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "notification_type is required for set_channel"})
+			return
+		}
+	}
+
+	results, err := runBatch(userID, req, auditFrom(c))
+	if err == errBatchTooLarge {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Filter matches more than %d reminders, narrow it down", maxBatchSize)})
+		return
+	}
+	if err != nil {
+		log.Printf("Error applying batch %s for user %s: %v", req.Operation, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply batch operation"})
+		return
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Status == "ok" {
+			succeeded++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"operation": req.Operation,
+		"matched":   len(results),
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	})
+}
+
+// runBatch applies a validated batch request to the user's reminders in one transaction
+func runBatch(userID string, req BatchRequest, audit auditInfo) ([]BatchItemResult, error) {
+	var results []BatchItemResult
+	err := db.Transaction(func(tx *gorm.DB) error {
+		results = nil
+
+		// Lock the rows so the scheduler can't pick them up halfway through
+		// Only the owner can delete; collaborators with edit permission can do the rest
+		permission := permissionEdit
+		if req.Operation == "delete" {
+			permission = permissionOwner
+		}
+		var reminders []Reminder
+		query := accessibleReminders(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID, permission)
+		if req.Filter != nil {
+			query = req.Filter.Apply(query).Limit(maxBatchSize + 1)
+		} else {
+			query = query.Where("id IN ?", req.IDs)
+		}
+		if err := query.Order("id asc").Find(&reminders).Error; err != nil {
+			return err
+		}
+		if len(reminders) > maxBatchSize {
+			return errBatchTooLarge
+		}
+
+		// Ownership is per row: IDs that aren't the caller's look exactly like missing ones
+		byID := make(map[string]*Reminder, len(reminders))
+		for i := range reminders {
+			byID[reminders[i].ID] = &reminders[i]
+		}
+		ids := req.IDs
+		if req.Filter != nil {
+			ids = make([]string, 0, len(reminders))
+			for _, reminder := range reminders {
+				ids = append(ids, reminder.ID)
+			}
+		}
+
+		seen := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			// A repeated ID is only acted on once (rescheduling it twice would move it twice)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			reminder, ok := byID[id]
+			if !ok {
+				results = append(results, BatchItemResult{ID: id, Status: "error", Error: "Reminder not found"})
+				continue
+			}
+			if err := applyBatchOperation(tx, reminder, req, audit); err != nil {
+				if itemErr, ok := err.(batchItemError); ok {
+					results = append(results, BatchItemResult{ID: id, Status: "error", Error: string(itemErr)})
+					continue
+				}
+				return err
+			}
+			results = append(results, BatchItemResult{ID: id, Status: "ok"})
+		}
+		return nil
+	})
+	return results, err
+}
+
+// batchItemError means the operation doesn't apply to one reminder; the rest of the batch goes ahead
+type batchItemError string
+
+func (e batchItemError) Error() string { return string(e) }
+
+var errBatchTooLarge = fmt.Errorf("batch too large")
+
+func applyBatchOperation(tx *gorm.DB, reminder *Reminder, req BatchRequest, audit auditInfo) error {
+	if reminder.Status == "processing" && req.Operation != "delete" {
+		return batchItemError("Reminder is being sent")
+	}
+
+	before := *reminder
+	action := "update"
+
+	switch req.Operation {
+	case "delete":
+		if err := tx.Delete(reminder).Error; err != nil {
+			return err
+		}
+		return recordHistory(tx, audit, "delete", &before, nil)
+
+	case "reschedule":
+		shift := time.Duration(req.ShiftMinutes) * time.Minute
+		reminder.DateTime = reminder.DateTime.Add(shift)
+		if reminder.RecurrenceStart != nil {
+			start := reminder.RecurrenceStart.Add(shift)
+			reminder.RecurrenceStart = &start
+		}
+		syncLocalTime(reminder)
+		if err := updateVersioned(tx, reminder, "date_time", "recurrence_start", "local_time"); err != nil {
+			return err
+		}
+
+	case "set_channel":
+		email, phone := reminder.Email, reminder.Phone
+		if req.Email != "" {
+			email = req.Email
+		}
+		if req.Phone != "" {
+			phone = req.Phone
+		}
+		hasContacts := len(reminder.ContactIDs) > 0 || len(reminder.GroupIDs) > 0
+		if req.NotificationType == "email" && email == "" && !hasContacts {
+			return batchItemError("Email is required for email notifications")
+		}
+		if req.NotificationType == "sms" && phone == "" && !hasContacts {
+			return batchItemError("Phone is required for SMS notifications")
+		}
+		reminder.NotificationType, reminder.Email, reminder.Phone = req.NotificationType, email, phone
+		if err := updateVersioned(tx, reminder, "notification_type", "email", "phone"); err != nil {
+			return err
+		}
+
+	case "retry":
+		if reminder.Status != "failed" {
+			return batchItemError("Reminder has not failed")
+		}
+		setStatus(reminder, StatusPending, "")
+		if err := updateVersioned(tx, reminder, "status", "requeued_at"); err != nil { // keeps last_error for triage
+			return err
+		}
+		action = "retry"
+
+	default:
+		return batchItemError("Unknown operation")
+	}
+
+	return recordHistory(tx, audit, action, &before, reminder)
+}
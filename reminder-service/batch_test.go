@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// fakeDB stands in for Postgres: gorm builds every statement as usual (in dry-run mode), queries
+// are answered from rows, and writes are recorded and report affected rows
+type fakeDB struct {
+	mu         sync.Mutex
+	rows       map[string]func(stmt *gorm.Statement) interface{} // by table: a slice of rows the query returns
+	affected   func(stmt *gorm.Statement) int64                  // rows a write affects; 1 if nil
+	failOn     string                                            // writes whose SQL contains this fail
+	statements []fakeStatement
+}
+
+type fakeStatement struct {
+	SQL  string
+	Vars []interface{}
+}
+
+// set returns the value an UPDATE statement sets the column to
+func (s fakeStatement) set(column string) (interface{}, bool) {
+	var n int
+	i := strings.Index(s.SQL, `"`+column+`"=$`)
+	if i < 0 {
+		return nil, false
+	}
+	if _, err := fmt.Sscanf(s.SQL[i+len(column)+4:], "%d", &n); err != nil || n > len(s.Vars) {
+		return nil, false
+	}
+	return s.Vars[n-1], true
+}
+
+var errFakeDB = fmt.Errorf("fake database error")
+
+// useFakeDB points db at a fakeDB for the test
+func useFakeDB(t *testing.T) *fakeDB {
+	t.Helper()
+	fake := &fakeDB{rows: make(map[string]func(*gorm.Statement) interface{})}
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: fakePool{fake}}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("open fake db: %v", err)
+	}
+
+	callbacks := gdb.Callback()
+	callbacks.Query().After("gorm:query").Before("gorm:preload").Register("fake:query", fake.query)
+	callbacks.Create().After("gorm:create").Register("fake:write", fake.write)
+	callbacks.Update().After("gorm:update").Register("fake:write", fake.write)
+	callbacks.Delete().After("gorm:delete").Register("fake:write", fake.write)
+	callbacks.Raw().After("gorm:raw").Register("fake:write", fake.write)
+
+	previous := db
+	db = gdb
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() {
+		db = previous
+		log.SetOutput(os.Stderr)
+	})
+	return fake
+}
+
+// returns makes queries on the table return these rows, whatever their conditions
+func (f *fakeDB) returns(table string, rows interface{}) {
+	f.rows[table] = func(*gorm.Statement) interface{} { return rows }
+}
+
+func (f *fakeDB) record(db *gorm.DB) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statements = append(f.statements, fakeStatement{SQL: db.Statement.SQL.String(), Vars: db.Statement.Vars})
+}
+
+// executed returns the statements whose SQL contains all of the fragments
+func (f *fakeDB) executed(fragments ...string) []fakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var matched []fakeStatement
+	for _, stmt := range f.statements {
+		all := true
+		for _, fragment := range fragments {
+			all = all && strings.Contains(stmt.SQL, fragment)
+		}
+		if all {
+			matched = append(matched, stmt)
+		}
+	}
+	return matched
+}
+
+func (f *fakeDB) query(db *gorm.DB) {
+	f.record(db)
+	if db.Error != nil {
+		return
+	}
+
+	var rows reflect.Value
+	if source, ok := f.rows[db.Statement.Table]; ok {
+		rows = reflect.ValueOf(source(db.Statement))
+	}
+	n := 0
+	if rows.IsValid() {
+		n = rows.Len()
+	}
+
+	dest := reflect.ValueOf(db.Statement.Dest)
+	if dest.Kind() != reflect.Ptr {
+		return
+	}
+	target := dest.Elem()
+	switch {
+	case target.Kind() == reflect.Int64: // Count
+		target.SetInt(int64(n))
+		db.RowsAffected = 1
+		return
+	case target.Kind() == reflect.Slice && rows.IsValid() && rows.Type().AssignableTo(target.Type()):
+		copied := reflect.MakeSlice(target.Type(), n, n)
+		reflect.Copy(copied, rows)
+		target.Set(copied)
+	case target.Kind() == reflect.Struct && n > 0 && rows.Type().Elem().AssignableTo(target.Type()):
+		target.Set(rows.Index(0))
+		n = 1
+	case target.Kind() == reflect.Struct && db.Statement.RaiseErrorOnNotFound:
+		db.AddError(gorm.ErrRecordNotFound)
+		return
+	}
+	db.RowsAffected = int64(n)
+}
+
+func (f *fakeDB) write(db *gorm.DB) {
+	f.record(db)
+	if db.Error != nil {
+		return
+	}
+	if f.failOn != "" && strings.Contains(db.Statement.SQL.String(), f.failOn) {
+		db.AddError(errFakeDB)
+		return
+	}
+	db.RowsAffected = 1
+	if f.affected != nil {
+		db.RowsAffected = f.affected(db.Statement)
+	}
+}
+
+// fakePool is the connection gorm begins transactions on; nothing is ever sent to it
+type fakePool struct{ fake *fakeDB }
+
+func (p fakePool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errFakeDB
+}
+func (p fakePool) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errFakeDB
+}
+func (p fakePool) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errFakeDB
+}
+func (p fakePool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row { return nil }
+
+func (p fakePool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	p.fake.mu.Lock()
+	p.fake.statements = append(p.fake.statements, fakeStatement{SQL: "BEGIN"})
+	p.fake.mu.Unlock()
+	return &fakeTx{p.fake}, nil
+}
+
+// fakeTx is a transaction on the pool. Like *sql.Tx it can't begin another, so gorm doesn't nest them.
+type fakeTx struct{ fake *fakeDB }
+
+func (tx *fakeTx) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errFakeDB
+}
+func (tx *fakeTx) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errFakeDB
+}
+func (tx *fakeTx) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errFakeDB
+}
+func (tx *fakeTx) QueryRowContext(context.Context, string, ...interface{}) *sql.Row { return nil }
+
+func (tx *fakeTx) Commit() error   { return tx.end("COMMIT") }
+func (tx *fakeTx) Rollback() error { return tx.end("ROLLBACK") }
+
+func (tx *fakeTx) end(sql string) error {
+	tx.fake.mu.Lock()
+	defer tx.fake.mu.Unlock()
+	tx.fake.statements = append(tx.fake.statements, fakeStatement{SQL: sql})
+	return nil
+}
+
+func batchFixtures() []Reminder {
+	due := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	return []Reminder{
+		{ID: "r1", UserID: "u1", Title: "Failed", DateTime: due, NotificationType: "email", Email: "a@example.com", Status: StatusFailed, LastError: "smtp down", Version: 3},
+		{ID: "r2", UserID: "u1", Title: "Pending", DateTime: due, NotificationType: "email", Email: "a@example.com", Status: StatusPending, Version: 1},
+		{ID: "r3", UserID: "u1", Title: "Sending", DateTime: due, NotificationType: "email", Email: "a@example.com", Status: StatusProcessing, Version: 1},
+	}
+}
+
+func batchStatuses(results []BatchItemResult) map[string]string {
+	statuses := make(map[string]string)
+	for _, result := range results {
+		statuses[result.ID] = result.Status
+		if result.Error != "" {
+			statuses[result.ID] += ": " + result.Error
+		}
+	}
+	return statuses
+}
+
+func TestRunBatchPartialFailure(t *testing.T) {
+	fake := useFakeDB(t)
+	useTenant(t, "u1", Tenant{})
+	fake.returns("reminders", batchFixtures())
+
+	req := BatchRequest{Operation: "retry", IDs: []string{"r1", "r2", "r3", "missing", "r1"}}
+	results, err := runBatch("u1", req, auditInfo{Actor: "user:u1"})
+	if err != nil {
+		t.Fatalf("runBatch: %v", err)
+	}
+
+	want := map[string]string{
+		"r1":      "ok",
+		"r2":      "error: Reminder has not failed",
+		"r3":      "error: Reminder is being sent",
+		"missing": "error: Reminder not found",
+	}
+	if got := batchStatuses(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v\nwant %v", got, want)
+	}
+	if len(results) != 4 {
+		t.Errorf("got %d results, want the repeated id once", len(results))
+	}
+
+	if locks := fake.executed("SELECT", "FOR UPDATE"); len(locks) != 1 {
+		t.Errorf("rows weren't locked: %v", fake.statements)
+	}
+	updates := fake.executed(`UPDATE "reminders"`)
+	if len(updates) != 1 || !strings.Contains(updates[0].SQL, `"status"`) || strings.Contains(updates[0].SQL, `"last_error"`) {
+		t.Errorf("updates = %v, want just r1's status (its last_error kept)", updates)
+	}
+	if history := fake.executed(`INSERT INTO "reminder_histories"`); len(history) != 1 {
+		t.Errorf("got %d history rows, want 1", len(history))
+	}
+	if len(fake.executed("COMMIT")) != 1 {
+		t.Error("batch wasn't committed")
+	}
+}
+
+func TestRunBatchSetChannelPerItem(t *testing.T) {
+	fake := useFakeDB(t)
+	useTenant(t, "u1", Tenant{})
+	fixtures := batchFixtures()
+	fixtures[1].Phone = "+15550100"
+	fake.returns("reminders", fixtures)
+
+	results, err := runBatch("u1", BatchRequest{Operation: "set_channel", IDs: []string{"r1", "r2"}, NotificationType: "sms"}, auditInfo{})
+	if err != nil {
+		t.Fatalf("runBatch: %v", err)
+	}
+
+	want := map[string]string{"r1": "error: Phone is required for SMS notifications", "r2": "ok"}
+	if got := batchStatuses(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v\nwant %v", got, want)
+	}
+}
+
+func TestRunBatchDatabaseErrorRollsBack(t *testing.T) {
+	fake := useFakeDB(t)
+	useTenant(t, "u1", Tenant{})
+	fake.returns("reminders", batchFixtures())
+	fake.failOn = `INSERT INTO "reminder_histories"`
+
+	shift := BatchRequest{Operation: "reschedule", IDs: []string{"r1", "r2"}, ShiftMinutes: 60}
+	if _, err := runBatch("u1", shift, auditInfo{}); err != errFakeDB {
+		t.Fatalf("runBatch error = %v, want the database error", err)
+	}
+	if len(fake.executed("ROLLBACK")) != 1 || len(fake.executed("COMMIT")) != 0 {
+		t.Errorf("batch wasn't rolled back: %v", fake.statements)
+	}
+}
+
+func TestRunBatchVersionConflictRollsBack(t *testing.T) {
+	fake := useFakeDB(t)
+	useTenant(t, "u1", Tenant{})
+	fake.returns("reminders", batchFixtures())
+	fake.affected = func(*gorm.Statement) int64 { return 0 }
+
+	if _, err := runBatch("u1", BatchRequest{Operation: "retry", IDs: []string{"r1"}}, auditInfo{}); err != errVersionConflict {
+		t.Fatalf("runBatch error = %v, want a version conflict", err)
+	}
+	if len(fake.executed("ROLLBACK")) != 1 {
+		t.Error("batch wasn't rolled back")
+	}
+}
+
+func TestRunBatchFilterTooLarge(t *testing.T) {
+	fake := useFakeDB(t)
+	useTenant(t, "u1", Tenant{})
+	fake.returns("reminders", make([]Reminder, maxBatchSize+1))
+
+	req := BatchRequest{Operation: "delete", Filter: &ReminderFilter{Status: []string{StatusPending}}}
+	if _, err := runBatch("u1", req, auditInfo{}); err != errBatchTooLarge {
+		t.Fatalf("runBatch error = %v, want errBatchTooLarge", err)
+	}
+	selects := fake.executed("SELECT", "LIMIT")
+	if len(selects) != 1 || selects[0].Vars[len(selects[0].Vars)-1] != maxBatchSize+1 {
+		t.Errorf("filter query wasn't limited: %v", fake.statements)
+	}
+}
@@ -0,0 +1,538 @@
+// bulk.go - CSV and JSON Lines bulk import and export
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+const (
+	maxImportSize  = 10 << 20
+	exportBatch    = 500
+	listSeparator  = ";" // separates IDs, tags and lead times inside a CSV cell
+	maxJSONLineLen = 1 << 20
+)
+
+// CSV columns, in export order. id and status are exported for reference and ignored on import.
+var csvColumns = []string{
+	"id", "title", "description", "datetime", "notification_type", "email", "phone",
+	"contact_ids", "group_ids", "priority", "list_id", "tags", "recurrence", "lead_times",
+	"timezone", "catch_up", "grace_minutes", "team_id", "status",
+}
+
+// ReminderRecord is one exported reminder; apart from id and status it is a valid import row
+type ReminderRecord struct {
+	ID string `json:"id"`
+	CreateReminderRequest
+	Status string `json:"status"`
+}
+
+// BulkImportResult reports a CSV or JSON Lines import row by row
+type BulkImportResult struct {
+	DryRun  bool          `json:"dry_run"`
+	Rows    int           `json:"rows"`
+	Valid   int           `json:"valid"`
+	Created int           `json:"created"`
+	Errors  []ImportError `json:"errors"`
+}
+
+// importRow is one parsed row, or why it couldn't be parsed
+type importRow struct {
+	Line    int
+	Request CreateReminderRequest
+	Err     error
+}
+
+// importReminders accepts an iCalendar file, CSV or JSON Lines, chosen by the format
+// query parameter or else by Content-Type (or the uploaded file's extension)
+func importReminders(c *gin.Context) {
+	switch format := importFormat(c); format {
+	case "ics":
+		importICS(c)
+	case "csv", "jsonl":
+		importRows(c, format)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format, use ics, csv or jsonl"})
+	}
+}
+
+func importFormat(c *gin.Context) string {
+	if format := c.Query("format"); format != "" {
+		return format
+	}
+
+	contentType := c.ContentType()
+	if contentType == "multipart/form-data" {
+		if header, err := c.FormFile("file"); err == nil {
+			contentType = mime.TypeByExtension(filepath.Ext(header.Filename))
+			if ext := strings.ToLower(filepath.Ext(header.Filename)); ext == ".jsonl" || ext == ".ndjson" {
+				return "jsonl"
+			}
+		}
+		contentType, _, _ = mime.ParseMediaType(contentType)
+	}
+
+	switch contentType {
+	case "text/csv":
+		return "csv"
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return "jsonl"
+	default:
+		// Calendar files were the first import format, so they stay the default
+		return "ics"
+	}
+}
+
+// importBody returns the upload: the raw request body, or multipart field "file"
+func importBody(c *gin.Context) (io.ReadCloser, error) {
+	if c.ContentType() != "multipart/form-data" {
+		return http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize), nil
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("file is required")
+	}
+	if header.Size > maxImportSize {
+		return nil, fmt.Errorf("file is too large")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read file")
+	}
+	return file, nil
+}
+
+// importRows validates every row with the same rules as createReminder. With dry_run=true
+// nothing is written; otherwise the valid rows are created in one transaction and the
+// invalid ones are reported and skipped.
+func importRows(c *gin.Context, format string) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run"})
+		return
+	}
+
+	body, err := importBody(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer body.Close()
+
+	var rows []importRow
+	if format == "csv" {
+		rows, err = readCSVRows(body)
+	} else {
+		rows, err = readJSONLRows(body)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := BulkImportResult{DryRun: dryRun, Rows: len(rows), Errors: []ImportError{}}
+	type validRow struct {
+		reminder Reminder
+		req      CreateReminderRequest
+	}
+	var valid []validRow
+
+	for i, row := range rows {
+		err := row.Err
+		if err == nil {
+			err = binding.Validator.ValidateStruct(&row.Request)
+		}
+		var reminder Reminder
+		if err == nil {
+			reminder, err = newReminder(&row.Request, userID)
+		}
+		if err == errTenantUnavailable {
+			// Not the row's fault; the whole import can be tried again
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, ImportError{Index: i, Line: row.Line, Error: err.Error()})
+			continue
+		}
+		valid = append(valid, validRow{reminder: reminder, req: row.Request})
+	}
+	result.Valid = len(valid)
+
+	if !dryRun && len(valid) > 0 {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for i := range valid {
+				if err := insertReminder(tx, &valid[i].reminder, valid[i].req, auditFrom(c), "import"); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Error importing reminders for user %s: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import reminders"})
+			return
+		}
+		result.Created = len(valid)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// readCSVRows reads a CSV with a header row naming any of csvColumns, in any order
+func readCSVRows(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read CSV header: %v", err)
+	}
+
+	known := make(map[string]bool)
+	for _, column := range csvColumns {
+		known[column] = true
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) // spreadsheets often add a BOM
+		if !known[name] {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"title", "datetime"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV column %q is required", required)
+		}
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			parseErr, ok := err.(*csv.ParseError)
+			if !ok {
+				return nil, fmt.Errorf("Failed to read CSV: %v", err)
+			}
+			rows = append(rows, importRow{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := importRow{Line: line}
+		row.Request = CreateReminderRequest{
+			Title:            cell("title"),
+			Description:      cell("description"),
+			DateTime:         cell("datetime"),
+			NotificationType: cell("notification_type"),
+			Email:            cell("email"),
+			Phone:            cell("phone"),
+			ContactIDs:       splitList(cell("contact_ids")),
+			GroupIDs:         splitList(cell("group_ids")),
+			Priority:         cell("priority"),
+			ListID:           cell("list_id"),
+			Tags:             splitList(cell("tags")),
+			Recurrence:       cell("recurrence"),
+			Timezone:         cell("timezone"),
+			CatchUp:          cell("catch_up"),
+			TeamID:           cell("team_id"),
+		}
+		if value := cell("grace_minutes"); value != "" {
+			minutes, err := strconv.Atoi(value)
+			if err != nil {
+				row.Err = fmt.Errorf("invalid grace_minutes %q", value)
+			}
+			row.Request.GraceMinutes = minutes
+		}
+		for _, value := range splitList(cell("lead_times")) {
+			minutes, err := strconv.Atoi(value)
+			if err != nil {
+				row.Err = fmt.Errorf("invalid lead time %q", value)
+				break
+			}
+			row.Request.LeadTimes = append(row.Request.LeadTimes, minutes)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// readJSONLRows reads one JSON object per line, shaped like a create request; blank lines are skipped
+func readJSONLRows(r io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxJSONLineLen)
+
+	var rows []importRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		row := importRow{Line: line}
+		if err := json.Unmarshal([]byte(text), &row.Request); err != nil {
+			row.Err = fmt.Errorf("invalid JSON: %v", err)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read JSON Lines: %v", err)
+	}
+
+	return rows, nil
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(value, listSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// exportReminders streams the user's reminders (narrowed by the listing filters) as CSV or JSON Lines.
+// Early reminders are exported as lead_times of the reminder they lead, so an export imports back
+// as it was; one whose reminder isn't in the export is exported on its own.
+func exportReminders(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format == "ics" {
+		exportICS(c)
+		return
+	}
+	if format != "csv" && format != "jsonl" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format, use csv, jsonl or ics"})
+		return
+	}
+
+	filter, err := parseReminderFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	base := filter.Apply(accessibleReminders(db.Model(&Reminder{}), userID, permissionOwner))
+	earlyPattern := escapeLike(earlyTitlePrefix) + "%"
+
+	var earlyRows []Reminder
+	if err := base.Session(&gorm.Session{}).Where("title LIKE ?", earlyPattern).Preload("Tags").Find(&earlyRows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminders"})
+		return
+	}
+	early := indexEarlyReminders(earlyRows)
+
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="reminders.%s"`, format))
+	c.Status(http.StatusOK)
+
+	csvWriter := csv.NewWriter(c.Writer)
+	encoder := json.NewEncoder(c.Writer)
+	write := func(record ReminderRecord) error {
+		if format == "csv" {
+			return csvWriter.Write(csvRow(record))
+		}
+		return encoder.Encode(record)
+	}
+	if format == "csv" {
+		err = csvWriter.Write(csvColumns)
+	}
+
+	// Batches keep memory flat however many reminders the user has
+	if err == nil {
+		var batch []Reminder
+		query := base.Session(&gorm.Session{}).Where("title NOT LIKE ?", earlyPattern).Preload("Tags")
+		err = query.FindInBatches(&batch, exportBatch, func(tx *gorm.DB, _ int) error {
+			for _, reminder := range batch {
+				if err := write(reminderRecord(reminder, early.leadTimes(reminder))); err != nil {
+					return err
+				}
+			}
+			csvWriter.Flush()
+			c.Writer.Flush()
+			return csvWriter.Error()
+		}).Error
+	}
+	if err == nil {
+		for _, reminder := range early.unmatched() {
+			if err = write(reminderRecord(reminder, nil)); err != nil {
+				break
+			}
+		}
+		csvWriter.Flush()
+		if err == nil {
+			err = csvWriter.Error()
+		}
+	}
+	if err != nil {
+		// Headers are already sent, so the client just sees a truncated file
+		log.Printf("Error exporting reminders for user %s: %v", userID, err)
+	}
+}
+
+// earlyReminders holds early reminders by the title of the reminder they lead, until they're matched to it
+type earlyReminders map[string][]Reminder
+
+func indexEarlyReminders(reminders []Reminder) earlyReminders {
+	early := make(earlyReminders)
+	for _, reminder := range reminders {
+		title := strings.TrimPrefix(reminder.Title, earlyTitlePrefix)
+		early[title] = append(early[title], reminder)
+	}
+	return early
+}
+
+// leadTimes takes the reminder's early reminders out of the index and returns how many minutes
+// ahead each one fires. An early reminder is the reminder's copy, moved earlier by whole minutes.
+func (e earlyReminders) leadTimes(reminder Reminder) []int {
+	candidates := e[reminder.Title]
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var minutes []int
+	rest := candidates[:0]
+	for _, early := range candidates {
+		lead := reminder.DateTime.Sub(early.DateTime)
+		if reminder.RecurrenceStart != nil && early.RecurrenceStart != nil {
+			lead = reminder.RecurrenceStart.Sub(*early.RecurrenceStart)
+		}
+		if early.Description != earlyDescriptionPrefix+reminder.Description || early.Recurrence != reminder.Recurrence ||
+			early.UserID != reminder.UserID || lead <= 0 || lead%time.Minute != 0 {
+			rest = append(rest, early)
+			continue
+		}
+		minutes = append(minutes, int(lead/time.Minute))
+	}
+	e[reminder.Title] = rest
+	sort.Ints(minutes)
+	return minutes
+}
+
+// unmatched returns the early reminders no exported reminder claimed, in due order
+func (e earlyReminders) unmatched() []Reminder {
+	var reminders []Reminder
+	for _, early := range e {
+		reminders = append(reminders, early...)
+	}
+	sort.Slice(reminders, func(i, j int) bool {
+		if !reminders[i].DateTime.Equal(reminders[j].DateTime) {
+			return reminders[i].DateTime.Before(reminders[j].DateTime)
+		}
+		return reminders[i].ID < reminders[j].ID
+	})
+	return reminders
+}
+
+func reminderRecord(reminder Reminder, leadTimes []int) ReminderRecord {
+	record := ReminderRecord{
+		ID: reminder.ID,
+		CreateReminderRequest: CreateReminderRequest{
+			Title:            reminder.Title,
+			Description:      reminder.Description,
+			DateTime:         reminder.DateTime.Format(time.RFC3339),
+			NotificationType: reminder.NotificationType,
+			Email:            reminder.Email,
+			Phone:            reminder.Phone,
+			ContactIDs:       reminder.ContactIDs,
+			GroupIDs:         reminder.GroupIDs,
+			Priority:         reminder.Priority,
+			TeamID:           reminder.TeamID,
+			Recurrence:       reminder.Recurrence,
+			LeadTimes:        leadTimes,
+			Timezone:         reminder.Timezone,
+			CatchUp:          reminder.CatchUp,
+			GraceMinutes:     reminder.GraceMinutes,
+		},
+		Status: reminder.Status,
+	}
+	if reminder.ListID != nil {
+		record.ListID = *reminder.ListID
+	}
+	for _, tag := range reminder.Tags {
+		record.Tags = append(record.Tags, tag.Name)
+	}
+	return record
+}
+
+func csvRow(record ReminderRecord) []string {
+	leadTimes := make([]string, 0, len(record.LeadTimes))
+	for _, minutes := range record.LeadTimes {
+		leadTimes = append(leadTimes, strconv.Itoa(minutes))
+	}
+	graceMinutes := ""
+	if record.GraceMinutes > 0 {
+		graceMinutes = strconv.Itoa(record.GraceMinutes)
+	}
+
+	return []string{
+		record.ID,
+		record.Title,
+		record.Description,
+		record.DateTime,
+		record.NotificationType,
+		record.Email,
+		record.Phone,
+		strings.Join(record.ContactIDs, listSeparator),
+		strings.Join(record.GroupIDs, listSeparator),
+		record.Priority,
+		record.ListID,
+		strings.Join(record.Tags, listSeparator),
+		record.Recurrence,
+		strings.Join(leadTimes, listSeparator),
+		record.Timezone,
+		record.CatchUp,
+		graceMinutes,
+		record.TeamID,
+		record.Status,
+	}
+}
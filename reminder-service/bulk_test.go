@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadCSVRows(t *testing.T) {
+	input := "\ufeffTitle, datetime ,notification_type,email,tags,lead_times,timezone,catch_up,grace_minutes,team_id,status\n" +
+		"Pay rent,2026-03-01T09:00:00Z,email,a@example.com,home; bills ,15;60,Europe/Berlin,grace,30,t1,sent\n" +
+		"Bad lead,2026-03-01T09:00:00Z,sms,,,soon,,,,,\n" +
+		"Bad grace,2026-03-01T09:00:00Z,sms,,,,,,half,,\n" +
+		"\"Unclosed,2026-03-01T09:00:00Z\n"
+
+	rows, err := readCSVRows(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("readCSVRows: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("got %d rows, want 4: %+v", len(rows), rows)
+	}
+
+	want := CreateReminderRequest{
+		Title: "Pay rent", DateTime: "2026-03-01T09:00:00Z", NotificationType: "email", Email: "a@example.com",
+		Tags: []string{"home", "bills"}, LeadTimes: []int{15, 60},
+		Timezone: "Europe/Berlin", CatchUp: "grace", GraceMinutes: 30, TeamID: "t1",
+	}
+	if rows[0].Err != nil || rows[0].Line != 2 || !reflect.DeepEqual(rows[0].Request, want) {
+		t.Errorf("row 1 = %+v\nwant line 2, %+v", rows[0], want)
+	}
+	if rows[1].Err == nil || !strings.Contains(rows[1].Err.Error(), "lead time") {
+		t.Errorf("row 2 error = %v, want an invalid lead time", rows[1].Err)
+	}
+	if rows[2].Err == nil || !strings.Contains(rows[2].Err.Error(), "grace_minutes") {
+		t.Errorf("row 3 error = %v, want an invalid grace_minutes", rows[2].Err)
+	}
+	if rows[3].Err == nil || rows[3].Line != 5 {
+		t.Errorf("row 4 = %+v, want a parse error on line 5", rows[3])
+	}
+}
+
+func TestReadCSVRowsHeader(t *testing.T) {
+	tests := []struct {
+		name, input, wantErr string
+	}{
+		{"empty", "", "header"},
+		{"unknown column", "title,datetime,when\n", `unknown CSV column "when"`},
+		{"missing datetime", "title,email\n", `"datetime" is required`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readCSVRows(strings.NewReader(tt.input)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one mentioning %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadJSONLRows(t *testing.T) {
+	input := `{"title":"Pay rent","datetime":"2026-03-01T09:00:00Z","lead_times":[15]}` + "\n\n" +
+		`{"title": broken}` + "\n" +
+		`  {"title":"Call","when":"tomorrow 9am"}  ` + "\n"
+
+	rows, err := readJSONLRows(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("readJSONLRows: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3 (blank lines skipped)", len(rows))
+	}
+	if rows[0].Err != nil || rows[0].Line != 1 || rows[0].Request.Title != "Pay rent" || !reflect.DeepEqual(rows[0].Request.LeadTimes, []int{15}) {
+		t.Errorf("row 1 = %+v", rows[0])
+	}
+	if rows[1].Err == nil || rows[1].Line != 3 {
+		t.Errorf("row 2 = %+v, want invalid JSON on line 3", rows[1])
+	}
+	if rows[2].Err != nil || rows[2].Line != 4 || rows[2].Request.When != "tomorrow 9am" {
+		t.Errorf("row 3 = %+v", rows[2])
+	}
+}
+
+func TestReadJSONLRowsTooLong(t *testing.T) {
+	input := `{"title":"` + strings.Repeat("x", maxJSONLineLen) + `"}`
+	if _, err := readJSONLRows(strings.NewReader(input)); err == nil {
+		t.Error("a line over the limit was accepted")
+	}
+}
+
+func uploadContext(t *testing.T, rawQuery, contentType, filename string) *gin.Context {
+	t.Helper()
+	c := queryContext(rawQuery)
+	if filename == "" {
+		c.Request = httptest.NewRequest("POST", "/api/reminders/import?"+rawQuery, strings.NewReader("x"))
+		c.Request.Header.Set("Content-Type", contentType)
+		return c
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte("x"))
+	form.Close()
+	c.Request = httptest.NewRequest("POST", "/api/reminders/import?"+rawQuery, &body)
+	c.Request.Header.Set("Content-Type", form.FormDataContentType())
+	return c
+}
+
+func TestImportFormat(t *testing.T) {
+	tests := []struct {
+		name, query, contentType, filename, want string
+	}{
+		{"query wins", "format=csv", "text/calendar", "", "csv"},
+		{"csv body", "", "text/csv", "", "csv"},
+		{"csv body with charset", "", "text/csv; charset=utf-8", "", "csv"},
+		{"ndjson body", "", "application/x-ndjson", "", "jsonl"},
+		{"calendar body", "", "text/calendar", "", "ics"},
+		{"unknown body falls back to ics", "", "application/octet-stream", "", "ics"},
+		{"csv upload", "", "", "reminders.csv", "csv"},
+		{"jsonl upload", "", "", "reminders.jsonl", "jsonl"},
+		{"ndjson upload", "", "", "REMINDERS.NDJSON", "jsonl"},
+		{"ics upload", "", "", "calendar.ics", "ics"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := importFormat(uploadContext(t, tt.query, tt.contentType, tt.filename)); got != tt.want {
+				t.Errorf("importFormat = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEarlyRemindersLeadTimes(t *testing.T) {
+	due := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	parent := Reminder{ID: "p", UserID: "u1", Title: "Pay rent", Description: "flat", DateTime: due}
+	early := func(id string, before time.Duration) Reminder {
+		return Reminder{ID: id, UserID: "u1", Title: earlyTitlePrefix + "Pay rent",
+			Description: earlyDescriptionPrefix + "flat", DateTime: due.Add(-before)}
+	}
+	orphan := early("orphan", 10*time.Minute)
+	orphan.Description = earlyDescriptionPrefix + "another flat"
+
+	index := indexEarlyReminders([]Reminder{early("e60", time.Hour), early("e15", 15*time.Minute), orphan})
+	if got := index.leadTimes(parent); !reflect.DeepEqual(got, []int{15, 60}) {
+		t.Errorf("leadTimes = %v, want [15 60]", got)
+	}
+	if got := index.leadTimes(parent); got != nil {
+		t.Errorf("leadTimes a second time = %v, want none left", got)
+	}
+
+	unmatched := index.unmatched()
+	if len(unmatched) != 1 || unmatched[0].ID != "orphan" {
+		t.Errorf("unmatched = %+v, want just the orphan", unmatched)
+	}
+
+	record := reminderRecord(Reminder{ID: "p", Title: "Pay rent", DateTime: due, Timezone: "Europe/Berlin",
+		CatchUp: "grace", GraceMinutes: 30, TeamID: "t1", Status: "pending"}, []int{15, 60})
+	row := csvRow(record)
+	if len(row) != len(csvColumns) {
+		t.Fatalf("csvRow has %d cells, want one per column (%d)", len(row), len(csvColumns))
+	}
+	cells := make(map[string]string)
+	for i, column := range csvColumns {
+		cells[column] = row[i]
+	}
+	for column, want := range map[string]string{
+		"lead_times": "15;60", "timezone": "Europe/Berlin", "catch_up": "grace",
+		"grace_minutes": "30", "team_id": "t1", "status": "pending",
+	} {
+		if cells[column] != want {
+			t.Errorf("%s = %q, want %q", column, cells[column], want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// concurrency.go - Optimistic concurrency: versions, ETags and If-Match
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errVersionConflict means the reminder changed between reading and writing it
+var errVersionConflict = fmt.Errorf("reminder was modified concurrently")
+
+// REQUIRE_IF_MATCH=true makes user PUT/DELETE requests without If-Match fail with 428.
+// Internal services are exempt; their writes are still checked against the version they read.
+var requireIfMatch = os.Getenv("REQUIRE_IF_MATCH") == "true"
+
+func reminderETag(reminder *Reminder) string {
+	return fmt.Sprintf(`"%d"`, reminder.Version)
+}
+
+// checkIfMatch enforces the request's If-Match against the reminder's current version,
+// responding 412 (or 428 when required and missing) and returning false if it doesn't hold
+func checkIfMatch(c *gin.Context, reminder *Reminder) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		if requireIfMatch && c.GetHeader("X-Service-Name") == "" {
+			c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+			return false
+		}
+		return true
+	}
+
+	current := reminderETag(reminder)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+
+	c.Header("ETag", current)
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Reminder has been modified", "version": reminder.Version})
+	return false
+}
+
+// respondConflict reports a write that lost a race: 412 if the client asked for a precondition, else 409
+func respondConflict(c *gin.Context) {
+	status := http.StatusConflict
+	if c.GetHeader("If-Match") != "" {
+		status = http.StatusPreconditionFailed
+	}
+	c.JSON(status, gin.H{"error": "Reminder has been modified, fetch it and try again"})
+}
+
+// updateVersioned writes the reminder's columns (all of them if none are named) only if the row
+// is still at the version it was read at, and bumps the version
+func updateVersioned(tx *gorm.DB, reminder *Reminder, columns ...string) error {
+	if len(columns) == 0 {
+		columns = []string{"*"}
+	} else {
+		columns = append(columns, "version")
+	}
+
+	version := reminder.Version
+	reminder.Version++
+	result := tx.Model(reminder).Omit(clause.Associations).Where("version = ?", version).Select(columns).Updates(reminder)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = errVersionConflict
+	}
+	if result.Error != nil {
+		reminder.Version = version
+	}
+	return result.Error
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestCheckIfMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		required bool
+		want     int // 0 when the request may go ahead
+	}{
+		{"no precondition", nil, false, 0},
+		{"current version", map[string]string{"If-Match": `"3"`}, false, 0},
+		{"weak tag", map[string]string{"If-Match": `W/"3"`}, false, 0},
+		{"one of several", map[string]string{"If-Match": `"1", "3"`}, false, 0},
+		{"any version", map[string]string{"If-Match": "*"}, false, 0},
+		{"stale version", map[string]string{"If-Match": `"2"`}, false, http.StatusPreconditionFailed},
+		{"unquoted version", map[string]string{"If-Match": "3"}, false, http.StatusPreconditionFailed},
+		{"required and missing", nil, true, http.StatusPreconditionRequired},
+		{"required, services exempt", map[string]string{"X-Service-Name": "scheduler-service"}, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := requireIfMatch
+			requireIfMatch = tt.required
+			defer func() { requireIfMatch = previous }()
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("PUT", "/api/reminders/r1", nil)
+			for name, value := range tt.headers {
+				c.Request.Header.Set(name, value)
+			}
+
+			ok := checkIfMatch(c, &Reminder{ID: "r1", Version: 3})
+			if ok != (tt.want == 0) || (tt.want != 0 && w.Code != tt.want) {
+				t.Fatalf("checkIfMatch = %v with status %d, want status %d", ok, w.Code, tt.want)
+			}
+			if tt.want == http.StatusPreconditionFailed {
+				var body struct {
+					Version int `json:"version"`
+				}
+				json.Unmarshal(w.Body.Bytes(), &body)
+				if w.Header().Get("ETag") != `"3"` || body.Version != 3 {
+					t.Errorf("412 came with ETag %s and version %d, want the current one", w.Header().Get("ETag"), body.Version)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateVersioned(t *testing.T) {
+	fake := useFakeDB(t)
+	reminder := Reminder{ID: "r1", Title: "Dentist", Version: 3}
+
+	if err := updateVersioned(db, &reminder, "title"); err != nil {
+		t.Fatal(err)
+	}
+	updates := fake.executed(`UPDATE "reminders" SET "title"=$1,"version"=$2`, "WHERE version = $4")
+	if len(updates) != 1 || updates[0].Vars[1] != 4 || updates[0].Vars[3] != 3 || reminder.Version != 4 {
+		t.Errorf("updates = %v, version now %d; want version 3 bumped to 4", fake.statements, reminder.Version)
+	}
+
+	fake.affected = func(*gorm.Statement) int64 { return 0 }
+	if err := updateVersioned(db, &reminder, "title"); err != errVersionConflict {
+		t.Fatalf("error = %v, want a version conflict", err)
+	}
+	if reminder.Version != 4 {
+		t.Errorf("version after a conflict = %d, want it left at 4", reminder.Version)
+	}
+}
+
+// Each write handler refuses a stale If-Match without writing, and a write that loses the race
+// after the check answers 412 when the client sent If-Match, 409 when it didn't
+func TestWritesHonourIfMatch(t *testing.T) {
+	handlers := []struct {
+		name    string
+		handler gin.HandlerFunc
+		method  string
+		body    string
+	}{
+		{"PUT", updateReminder, "PUT", `{"title": "Dentist (moved)"}`},
+		{"PATCH", patchReminder, "PATCH", `{"title": "Dentist (moved)"}`},
+		{"DELETE", deleteReminder, "DELETE", ""},
+	}
+	cases := []struct {
+		name     string
+		ifMatch  string
+		lostRace bool
+		want     int
+	}{
+		{"stale If-Match", `"2"`, false, http.StatusPreconditionFailed},
+		{"current If-Match, written meanwhile", `"3"`, true, http.StatusPreconditionFailed},
+		{"no If-Match, written meanwhile", "", true, http.StatusConflict},
+		{"current If-Match", `"3"`, false, http.StatusOK},
+	}
+
+	for _, h := range handlers {
+		for _, tt := range cases {
+			t.Run(h.name+"/"+tt.name, func(t *testing.T) {
+				fake := useFakeDB(t)
+				useTenant(t, "u1", Tenant{})
+				reminder := patchFixture()
+				reminder.Version = 3
+				fake.returns("reminders", []Reminder{reminder})
+				if tt.lostRace {
+					fake.affected = func(*gorm.Statement) int64 { return 0 }
+				}
+
+				headers := map[string]string{"X-User-ID": "u1"}
+				if tt.ifMatch != "" {
+					headers["If-Match"] = tt.ifMatch
+				}
+				w := serve(h.handler, h.method, "r1", h.body, headers)
+				if w.Code != tt.want {
+					t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+				}
+				if tt.want == http.StatusOK {
+					return
+				}
+
+				if tt.ifMatch == `"2"` && (len(fake.executed("UPDATE")) != 0 || len(fake.executed("DELETE")) != 0) {
+					t.Errorf("a stale request was written: %v", fake.statements)
+				}
+				if len(fake.executed(`INSERT INTO "reminder_histories"`)) != 0 || len(fake.executed("COMMIT")) != 0 {
+					t.Errorf("a refused write was committed: %v", fake.statements)
+				}
+			})
+		}
+	}
+}
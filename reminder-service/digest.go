@@ -0,0 +1,85 @@
+// digest.go - Claiming reminders for a user's digest
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DigestRequest DTO - the subscription a digest is for and the period it covers
+type DigestRequest struct {
+	DigestID string    `json:"digest_id" binding:"required"`
+	Channel  string    `json:"channel" binding:"required,oneof=email sms"`
+	Address  string    `json:"address" binding:"required"` // the subscriber's email or phone
+	From     time.Time `json:"from" binding:"required"`
+	To       time.Time `json:"to" binding:"required"`
+}
+
+// claimDigestReminders moves the user's reminders due in the digest's period to processing and
+// returns them, so the scheduler sends them in the digest instead of one by one. The scheduler
+// then marks them sent (delivered_by digest), or back to pending if the digest couldn't go out.
+// Only reminders that would go to the subscriber alone, on the digest's channel and address, are
+// claimed: anyone else they notify (other addresses, contacts, groups, collaborators) still gets
+// them on their own, and each of the user's subscriptions claims just what's addressed to it.
+// Critical reminders are left to go out on their own.
+func claimDigestReminders(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req DigestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reminders := []Reminder{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := digestCandidates(tx, userID, req).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Order("date_time asc").
+			Find(&reminders).Error
+		if err != nil {
+			return err
+		}
+		for i := range reminders {
+			before := reminders[i]
+			setStatus(&reminders[i], StatusProcessing, "")
+			if err := updateVersioned(tx, &reminders[i], "status", "attempts"); err != nil {
+				return err
+			}
+			if err := recordHistory(tx, auditFrom(c), "status", &before, &reminders[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim reminders for digest"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reminders)
+}
+
+// digestCandidates narrows a reminder query to those the digest may claim
+func digestCandidates(tx *gorm.DB, userID string, req DigestRequest) *gorm.DB {
+	query := tx.Where("user_id = ? AND status IN ? AND priority <> ? AND date_time >= ? AND date_time < ?",
+		userID, []string{StatusPending, StatusSnoozed}, "critical", req.From, req.To).
+		Where("notification_type = ?", req.Channel)
+	if req.Channel == "email" {
+		query = query.Where("LOWER(email) = LOWER(?)", strings.TrimSpace(req.Address))
+	} else {
+		query = query.Where("phone = ?", strings.TrimSpace(req.Address))
+	}
+	return query.
+		Where("COALESCE(contact_ids, '') IN ('', '[]') AND COALESCE(group_ids, '') IN ('', '[]')").
+		Where("NOT EXISTS (SELECT 1 FROM shares WHERE shares.notify AND (shares.reminder_id = reminders.id OR shares.list_id = reminders.list_id))")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestClaimDigestReminders(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		address string
+		want    string
+	}{
+		{"email", `{"digest_id": "d1", "channel": "email", "address": " Me@Example.com ", "from": "2026-03-02T08:00:00Z", "to": "2026-03-03T08:00:00Z"}`,
+			"Me@Example.com", "LOWER(email) = LOWER($"},
+		{"sms", `{"digest_id": "d2", "channel": "sms", "address": "+15550100", "from": "2026-03-02T08:00:00Z", "to": "2026-03-09T08:00:00Z"}`,
+			"+15550100", "phone = $"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeDB(t)
+			fake.returns("reminders", []Reminder{{ID: "r1", UserID: "u1", Status: StatusPending, Version: 1}})
+
+			w := serve(claimDigestReminders, "POST", "", tt.body, map[string]string{"X-User-ID": "u1"})
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+			}
+			var claimed []Reminder
+			json.Unmarshal(w.Body.Bytes(), &claimed)
+			if len(claimed) != 1 || claimed[0].Status != StatusProcessing {
+				t.Errorf("claimed = %+v, want r1 processing", claimed)
+			}
+
+			selects := fake.executed(`SELECT * FROM "reminders"`, "FOR UPDATE")
+			if len(selects) != 1 {
+				t.Fatalf("selects = %v", fake.statements)
+			}
+			sql := selects[0].SQL
+			for _, fragment := range []string{
+				"user_id = $1", "notification_type = $", tt.want,
+				"COALESCE(contact_ids, '') IN ('', '[]') AND COALESCE(group_ids, '') IN ('', '[]')",
+				"NOT EXISTS (SELECT 1 FROM shares WHERE shares.notify",
+			} {
+				if !strings.Contains(sql, fragment) {
+					t.Errorf("SQL = %s, missing %s", sql, fragment)
+				}
+			}
+			var channel, address bool
+			for _, v := range selects[0].Vars {
+				channel = channel || v == tt.name
+				address = address || v == tt.address
+			}
+			if !channel || !address {
+				t.Errorf("vars = %v, want the subscription's channel %s and address %s", selects[0].Vars, tt.name, tt.address)
+			}
+			if len(fake.executed(`UPDATE "reminders"`)) != 1 || len(fake.executed("COMMIT")) != 1 {
+				t.Errorf("claim wasn't written: %v", fake.statements)
+			}
+		})
+	}
+}
+
+func TestClaimDigestRemindersNeedsSubscription(t *testing.T) {
+	for _, body := range []string{
+		`{"from": "2026-03-02T08:00:00Z", "to": "2026-03-03T08:00:00Z"}`,
+		`{"digest_id": "d1", "channel": "push", "address": "x", "from": "2026-03-02T08:00:00Z", "to": "2026-03-03T08:00:00Z"}`,
+	} {
+		useFakeDB(t)
+		if w := serve(claimDigestReminders, "POST", "", body, map[string]string{"X-User-ID": "u1"}); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, w.Code)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// feeds.go - Secret-token calendar subscription feeds
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CalendarFeed model - one subscription URL per user; whoever holds the token can read the feed
+type CalendarFeed struct {
+	UserID    string    `json:"user_id" gorm:"primaryKey"`
+	Token     string    `json:"token" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CalendarFeedResponse DTO
+type CalendarFeedResponse struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	WebcalURL string    `json:"webcal_url"` // opens straight in most calendar apps
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// getCalendarFeed returns the user's feed URL, creating the feed on first use
+func getCalendarFeed(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var feed CalendarFeed
+	err := db.Where("user_id = ?", userID).First(&feed).Error
+	if err == gorm.ErrRecordNotFound {
+		token, err := newFeedToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create feed"})
+			return
+		}
+		feed = CalendarFeed{UserID: userID, Token: token}
+		err = db.Create(&feed).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, feedResponse(c, feed))
+}
+
+// rotateCalendarFeed replaces the token; subscriptions using the old URL stop working
+func rotateCalendarFeed(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	token, err := newFeedToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate feed token"})
+		return
+	}
+
+	var feed CalendarFeed
+	err = db.Where("user_id = ?", userID).First(&feed).Error
+	if err == gorm.ErrRecordNotFound {
+		feed = CalendarFeed{UserID: userID, Token: token}
+		err = db.Create(&feed).Error
+	} else if err == nil {
+		feed.Token = token
+		err = db.Save(&feed).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate feed token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, feedResponse(c, feed))
+}
+
+// serveCalendarFeed is the public, read-only feed calendar apps poll (no user_id, the token identifies the user)
+func serveCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	var feed CalendarFeed
+	if err := db.Where("token = ?", token).First(&feed).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Feed not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch feed"})
+		return
+	}
+
+	component := c.DefaultQuery("component", "vevent")
+	if component != "vevent" && component != "vtodo" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid component, use vevent or vtodo"})
+		return
+	}
+
+	// The ETag comes from what changed, so a poll that matches never loads the reminders
+	etag, err := feedETag(feed, component)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch feed"})
+		return
+	}
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if match := c.GetHeader("If-None-Match"); match != "" && (match == etag || match == "*") {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	var reminders []Reminder
+	if err := db.Preload("Tags").Where("user_id = ?", feed.UserID).Order("date_time asc, id asc").Find(&reminders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminders"})
+		return
+	}
+
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(buildCalendar(reminders, component).Serialize()))
+}
+
+// feedETag fingerprints the feed from row counts and last-modified times of the user's reminders and tags
+func feedETag(feed CalendarFeed, component string) (string, error) {
+	var stats struct {
+		Count        int64
+		LastModified *time.Time
+	}
+	if err := db.Model(&Reminder{}).Select("COUNT(*) AS count, MAX(updated_at) AS last_modified").
+		Where("user_id = ?", feed.UserID).Scan(&stats).Error; err != nil {
+		return "", err
+	}
+
+	var tagsModified *time.Time
+	if err := db.Model(&Tag{}).Select("MAX(updated_at)").Where("user_id = ?", feed.UserID).Scan(&tagsModified).Error; err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%s|%s",
+		feed.Token, component, stats.Count, formatFeedTime(stats.LastModified), formatFeedTime(tagsModified))))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+func formatFeedTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func newFeedToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// feedResponse builds the subscription URLs from FEED_BASE_URL, or from the request when it's unset
+func feedResponse(c *gin.Context, feed CalendarFeed) CalendarFeedResponse {
+	base := strings.TrimSuffix(os.Getenv("FEED_BASE_URL"), "/")
+	if base == "" {
+		scheme := c.GetHeader("X-Forwarded-Proto")
+		if scheme == "" {
+			scheme = "http"
+		}
+		base = fmt.Sprintf("%s://%s", scheme, c.Request.Host)
+	}
+	url := fmt.Sprintf("%s/api/reminders/feeds/%s.ics", base, feed.Token)
+
+	return CalendarFeedResponse{
+		Token:     feed.Token,
+		URL:       url,
+		WebcalURL: "webcal://" + strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://"),
+		UpdatedAt: feed.UpdatedAt,
+	}
+}
@@ -0,0 +1,174 @@
+// history.go - Append-only change history per reminder
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReminderHistory model - one row per change; rows are never updated or deleted,
+// and outlive the reminder itself when it's purged
+type ReminderHistory struct {
+	ID         string                 `json:"id" gorm:"primaryKey"`
+	ReminderID string                 `json:"reminder_id" gorm:"index;not null"`
+	UserID     string                 `json:"user_id" gorm:"index;not null"` // the reminder's owner
+	Action     string                 `json:"action" gorm:"not null"`        // create, update, status, retry, delete, restore, purge, import
+	Actor      string                 `json:"actor" gorm:"not null"`         // "user:<id>" or "service:<name>"
+	Changes    map[string]FieldChange `json:"changes,omitempty" gorm:"serializer:json;type:text"`
+	RequestID  string                 `json:"request_id,omitempty" gorm:"index"`
+	CreatedAt  time.Time              `json:"created_at" gorm:"index"`
+}
+
+// FieldChange is one field's value before and after a change
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// auditInfo says who made a change, and in which request
+type auditInfo struct {
+	Actor     string
+	RequestID string
+}
+
+// Changes the service makes on its own, such as trash retention
+var systemAudit = auditInfo{Actor: "service:reminder-service"}
+
+// Fields left out of diffs: they change on every write or are implied by the action
+var unauditedFields = map[string]bool{"created_at": true, "updated_at": true, "deleted_at": true, "version": true}
+
+// requestID tags every request with an X-Request-ID, keeping the caller's if it sent one
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-ID")
+		if id == "" {
+			id = uuid.New().String()
+		}
+		c.Set("request_id", id)
+		c.Header("X-Request-ID", id)
+		c.Next()
+	}
+}
+
+// auditFrom identifies the caller: a user (X-User-ID or user_id), or an internal service (X-Service-Name)
+func auditFrom(c *gin.Context) auditInfo {
+	info := auditInfo{RequestID: c.GetString("request_id")}
+	switch {
+	case c.GetHeader("X-User-ID") != "":
+		info.Actor = "user:" + c.GetHeader("X-User-ID")
+	case c.GetHeader("X-Service-Name") != "":
+		info.Actor = "service:" + c.GetHeader("X-Service-Name")
+	case c.Query("user_id") != "":
+		info.Actor = "user:" + c.Query("user_id")
+	default:
+		info.Actor = "service:unknown"
+	}
+	return info
+}
+
+// recordHistory appends a history row for a change from before to after (nil before for
+// a create or restore, nil after for a delete or purge). Updates that changed nothing are not recorded.
+func recordHistory(tx *gorm.DB, audit auditInfo, action string, before, after *Reminder) error {
+	reminder := after
+	if reminder == nil {
+		reminder = before
+	}
+
+	changes := diffReminders(before, after)
+	if before != nil && after != nil && len(changes) == 0 {
+		return nil
+	}
+
+	entry := ReminderHistory{
+		ID:         uuid.New().String(),
+		ReminderID: reminder.ID,
+		UserID:     reminder.UserID,
+		Action:     action,
+		Actor:      audit.Actor,
+		Changes:    changes,
+		RequestID:  audit.RequestID,
+	}
+	return tx.Create(&entry).Error
+}
+
+// diffReminders lists the fields that differ; for a create, the fields that were set. A delete
+// has no diff, the entry before it already shows the final state.
+func diffReminders(before, after *Reminder) map[string]FieldChange {
+	if after == nil {
+		return nil
+	}
+	oldFields, newFields := historyFields(before), historyFields(after)
+
+	changes := make(map[string]FieldChange)
+	for field, value := range newFields {
+		if !reflect.DeepEqual(oldFields[field], value) {
+			changes[field] = FieldChange{Before: oldFields[field], After: value}
+		}
+	}
+	for field, value := range oldFields {
+		if _, ok := newFields[field]; !ok {
+			changes[field] = FieldChange{Before: value, After: nil}
+		}
+	}
+	return changes
+}
+
+// historyFields flattens a reminder to its JSON fields, with tags as a list of names
+func historyFields(reminder *Reminder) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if reminder == nil {
+		return fields
+	}
+
+	raw, _ := json.Marshal(reminder)
+	json.Unmarshal(raw, &fields)
+	for field := range unauditedFields {
+		delete(fields, field)
+	}
+
+	delete(fields, "tags")
+	if len(reminder.Tags) > 0 {
+		names := make([]interface{}, 0, len(reminder.Tags))
+		for _, tag := range reminder.Tags {
+			names = append(names, tag.Name)
+		}
+		fields["tags"] = names
+	}
+	return fields
+}
+
+// getReminderHistory lists a reminder's changes, oldest first, to anyone who can see the reminder.
+// Works after a purge too, for the owner.
+func getReminderHistory(c *gin.Context) {
+	id, userID := c.Param("id"), getUserID(c)
+	if _, err := findReminder(db.Unscoped(), id, userID, permissionView); err != nil {
+		if err != gorm.ErrRecordNotFound {
+			respondReminderError(c, err)
+			return
+		}
+		// Purged: only the owner's history remains to go by
+		var count int64
+		db.Model(&ReminderHistory{}).Where("reminder_id = ? AND user_id = ?", id, userID).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
+			return
+		}
+	}
+
+	var entries []ReminderHistory
+	err := db.Where("reminder_id = ?", id).
+		Order("created_at asc, id asc").
+		Find(&entries).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
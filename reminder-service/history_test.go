@@ -0,0 +1,180 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDiffReminders(t *testing.T) {
+	before := patchFixture()
+	before.Tags = []Tag{{ID: "t1", Name: "health"}}
+	before.Version = 1
+
+	after := before
+	after.Title = "Dentist (moved)"
+	after.Email = ""
+	after.Tags = []Tag{{ID: "t1", Name: "health"}, {ID: "t2", Name: "errands"}}
+	after.Version = 2
+	after.UpdatedAt = time.Now()
+
+	want := map[string]FieldChange{
+		"title": {Before: "Dentist", After: "Dentist (moved)"},
+		"email": {Before: "me@example.com", After: nil}, // omitempty drops it from the JSON
+		"tags":  {Before: []interface{}{"health"}, After: []interface{}{"health", "errands"}},
+	}
+	if got := diffReminders(&before, &after); !reflect.DeepEqual(got, want) {
+		t.Errorf("diff = %v\nwant %v", got, want)
+	}
+
+	if got := diffReminders(&before, &before); len(got) != 0 {
+		t.Errorf("diff of a reminder with itself = %v, want none", got)
+	}
+	if got := diffReminders(&before, nil); got != nil {
+		t.Errorf("diff for a delete = %v, want none", got)
+	}
+
+	created := diffReminders(nil, &after)
+	if created["title"].After != "Dentist (moved)" || created["title"].Before != nil {
+		t.Errorf("create diff title = %+v, want it set from nothing", created["title"])
+	}
+	for field := range unauditedFields {
+		if _, ok := created[field]; ok {
+			t.Errorf("create diff has unaudited field %s", field)
+		}
+	}
+}
+
+func TestRecordHistory(t *testing.T) {
+	fake := useFakeDB(t)
+	before := patchFixture()
+	audit := auditInfo{Actor: "user:u1", RequestID: "req-1"}
+
+	if err := recordHistory(db, audit, "update", &before, &before); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.statements) != 0 {
+		t.Errorf("an update that changed nothing was recorded: %v", fake.statements)
+	}
+
+	after := before
+	after.Status = StatusCancelled
+	if err := recordHistory(db, audit, "status", &before, &after); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordHistory(db, audit, "delete", &after, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	inserts := fake.executed(`INSERT INTO "reminder_histories"`)
+	if len(inserts) != 2 {
+		t.Fatalf("inserts = %v, want 2", fake.statements)
+	}
+	// id, reminder_id, user_id, action, actor, changes, request_id, created_at
+	status := inserts[0].Vars
+	if status[1] != "r1" || status[2] != "u1" || status[3] != "status" || status[4] != "user:u1" || status[6] != "req-1" {
+		t.Errorf("status entry = %v", status)
+	}
+	raw, err := status[5].(driver.Valuer).Value() // the JSON serializer
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes map[string]FieldChange
+	json.Unmarshal([]byte(fmt.Sprint(raw)), &changes)
+	if len(changes) != 1 || changes["status"].After != StatusCancelled {
+		t.Errorf("status entry changes = %s, want just the status", raw)
+	}
+	if deleted := inserts[1].Vars; deleted[1] != "r1" || deleted[3] != "delete" {
+		t.Errorf("delete entry = %v", deleted)
+	}
+}
+
+func TestAuditFrom(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		headers map[string]string
+		want    string
+	}{
+		{"user header", "/", map[string]string{"X-User-ID": "u1", "X-Service-Name": "scheduler-service"}, "user:u1"},
+		{"service", "/", map[string]string{"X-Service-Name": "scheduler-service"}, "service:scheduler-service"},
+		{"user query", "/?user_id=u2", nil, "user:u2"},
+		{"nobody", "/", nil, "service:unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", tt.target, nil)
+			for name, value := range tt.headers {
+				c.Request.Header.Set(name, value)
+			}
+			c.Set("request_id", "req-1")
+			if got := auditFrom(c); got.Actor != tt.want || got.RequestID != "req-1" {
+				t.Errorf("auditFrom = %+v, want actor %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetReminderHistory(t *testing.T) {
+	entries := []ReminderHistory{
+		{ID: "h1", ReminderID: "r1", UserID: "u1", Action: "create"},
+		{ID: "h2", ReminderID: "r1", UserID: "u1", Action: "purge"},
+	}
+
+	tests := []struct {
+		name      string
+		reminders []Reminder
+		want      int
+	}{
+		{"visible reminder", []Reminder{{ID: "r1", UserID: "u1"}}, http.StatusOK},
+		{"someone else's", []Reminder{{ID: "r1", UserID: "u2"}}, http.StatusForbidden},
+		{"purged, still the owner's history", nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeDB(t)
+			useTenant(t, "u1", Tenant{})
+			fake.returns("reminders", tt.reminders)
+			fake.returns("reminder_histories", entries)
+
+			w := serve(getReminderHistory, "GET", "r1", "", map[string]string{"X-User-ID": "u1"})
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var got []ReminderHistory
+			json.Unmarshal(w.Body.Bytes(), &got)
+			if len(got) != 2 || got[0].ID != "h1" {
+				t.Errorf("history = %+v", got)
+			}
+			if len(fake.executed(`FROM "reminder_histories"`, "ORDER BY created_at asc")) != 1 {
+				t.Errorf("history wasn't listed oldest first: %v", fake.statements)
+			}
+		})
+	}
+}
+
+func TestGetReminderHistoryUnknown(t *testing.T) {
+	fake := useFakeDB(t)
+	useTenant(t, "u1", Tenant{})
+
+	if w := serve(getReminderHistory, "GET", "r1", "", map[string]string{"X-User-ID": "u1"}); w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+	counts := fake.executed(`SELECT count(*) FROM "reminder_histories"`, "user_id = $2")
+	if len(counts) != 1 || counts[0].Vars[1] != "u1" {
+		t.Errorf("purged history wasn't limited to the owner: %v", fake.statements)
+	}
+}
@@ -0,0 +1,629 @@
+// ical.go - iCalendar (RFC 5545) import and export
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	icalUIDDomain      = "reminder-system" // exported UIDs are "<reminder id>@reminder-system"
+	allDayReminderHour = 9                 // all-day items are reminded at 09:00 local time
+)
+
+// iCalendar PRIORITY runs from 1 (highest) to 9 (lowest)
+var icalPriorities = map[string]int{
+	"critical": 1,
+	"high":     3,
+	"normal":   5,
+	"low":      9,
+}
+
+// ImportResult reports what an import did, item by item
+type ImportResult struct {
+	Created int           `json:"created"`
+	Updated int           `json:"updated"`
+	Errors  []ImportError `json:"errors"`
+}
+
+// ImportError describes a calendar item that could not be imported
+type ImportError struct {
+	Index int    `json:"index"`          // position of the item in the file
+	Line  int    `json:"line,omitempty"` // line number, for CSV and JSON Lines
+	UID   string `json:"uid,omitempty"`
+	Error string `json:"error"`
+}
+
+// calendarItem is the part of a VEVENT or VTODO a reminder is built from
+type calendarItem struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time // when the reminder fires: the item's start/due time moved by its first alarm
+	Timezone    string    // IANA zone a wall-clock start time was given in; empty for UTC times
+	Recurrence  string
+	Priority    string
+	Categories  []string
+}
+
+func exportICS(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	component := c.DefaultQuery("component", "vevent")
+	if component != "vevent" && component != "vtodo" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid component, use vevent or vtodo"})
+		return
+	}
+
+	filter, err := parseReminderFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var reminders []Reminder
+	query := filter.Apply(accessibleReminders(db.Model(&Reminder{}), userID, permissionOwner))
+	if err := query.Preload("Tags").Order("date_time asc, id asc").Find(&reminders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminders"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="reminders.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(buildCalendar(reminders, component).Serialize()))
+}
+
+// buildCalendar renders reminders as VEVENTs or VTODOs, each with a display alarm at its due time
+func buildCalendar(reminders []Reminder, component string) *ics.Calendar {
+	cal := ics.NewCalendar()
+	cal.SetProductId("-//Reminder System//Reminders//EN")
+	cal.SetMethod(ics.MethodPublish)
+	cal.SetXWRCalName("Reminders")
+
+	for _, reminder := range reminders {
+		uid := reminder.ExternalUID
+		if uid == "" {
+			uid = reminder.ID + "@" + icalUIDDomain
+		}
+
+		// A repeating reminder is exported as its whole series
+		start := reminder.DateTime
+		if reminder.Recurrence != "" && reminder.RecurrenceStart != nil {
+			start = *reminder.RecurrenceStart
+		}
+
+		var item *ics.ComponentBase
+		var alarm *ics.VAlarm
+		if component == "vtodo" {
+			todo := cal.AddTodo(uid)
+			todo.SetDueAt(start)
+			todo.SetStatus(icalTodoStatus(reminder.Status))
+			item, alarm = &todo.ComponentBase, todo.AddAlarm()
+		} else {
+			event := cal.AddEvent(uid)
+			item, alarm = &event.ComponentBase, event.AddAlarm()
+		}
+
+		item.SetDtStampTime(reminder.UpdatedAt)
+		item.SetCreatedTime(reminder.CreatedAt)
+		item.SetModifiedAt(reminder.UpdatedAt)
+		item.SetStartAt(start)
+		item.SetSummary(reminder.Title)
+		if reminder.Description != "" {
+			item.SetDescription(reminder.Description)
+		}
+		if reminder.Recurrence != "" {
+			item.AddRrule(reminder.Recurrence)
+		}
+		if p, ok := icalPriorities[reminder.Priority]; ok {
+			item.SetProperty(ics.ComponentPropertyPriority, strconv.Itoa(p))
+		}
+		for _, tag := range reminder.Tags {
+			item.AddCategory(tag.Name)
+		}
+
+		alarm.SetAction(ics.ActionDisplay)
+		alarm.SetTrigger("PT0S")
+		alarm.SetProperty(ics.ComponentPropertyDescription, reminder.Title)
+	}
+
+	return cal
+}
+
+func icalTodoStatus(status string) ics.ObjectStatus {
+	switch status {
+	case "sent", "acknowledged":
+		return ics.ObjectStatusCompleted
+	case "processing":
+		return ics.ObjectStatusInProcess
+	case "cancelled", "expired":
+		return ics.ObjectStatusCancelled
+	default:
+		return ics.ObjectStatusNeedsAction
+	}
+}
+
+// importICS creates reminders from an uploaded .ics file.
+// Calendars don't say how to notify, so notification_type, email, phone, priority and list_id
+// come from query parameters; floating times are read in the timezone query parameter (default UTC).
+// Items already imported (same UID) or previously exported from here are updated in place.
+func importICS(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	defaults := CreateReminderRequest{
+		NotificationType: c.Query("notification_type"),
+		Email:            c.Query("email"),
+		Phone:            c.Query("phone"),
+		Priority:         c.Query("priority"),
+		ListID:           c.Query("list_id"),
+	}
+	var listID *string
+	if defaults.ListID != "" {
+		list, err := findReminderList(defaults.ListID, userID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "List not found"})
+			return
+		}
+		applyListDefaults(&defaults, list)
+		listID = &list.ID
+	}
+
+	switch defaults.NotificationType {
+	case "email":
+		if defaults.Email == "" && len(defaults.ContactIDs) == 0 && len(defaults.GroupIDs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required for email notifications"})
+			return
+		}
+	case "sms":
+		if defaults.Phone == "" && len(defaults.ContactIDs) == 0 && len(defaults.GroupIDs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Phone is required for SMS notifications"})
+			return
+		}
+	case "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "notification_type is required"})
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid notification_type, use email or sms"})
+		return
+	}
+	if _, ok := icalPriorities[defaults.Priority]; defaults.Priority != "" && !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid priority, use low, normal, high or critical"})
+		return
+	}
+
+	loc, err := time.LoadLocation(c.DefaultQuery("timezone", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid timezone"})
+		return
+	}
+
+	body, err := importBody(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer body.Close()
+
+	cal, err := ics.ParseCalendar(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid calendar: %v", err)})
+		return
+	}
+
+	result := ImportResult{Errors: []ImportError{}}
+	now := time.Now()
+	index := 0
+	importItem := func(cb *ics.ComponentBase, alarms []*ics.VAlarm, timeProps ...ics.ComponentProperty) {
+		uid := cb.Id()
+		item, err := parseCalendarItem(cb, alarms, loc, timeProps...)
+		if err == nil {
+			var created bool
+			created, err = saveImportedItem(userID, item, defaults, listID, now, auditFrom(c))
+			if err == nil && created {
+				result.Created++
+			} else if err == nil {
+				result.Updated++
+			}
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, ImportError{Index: index, UID: uid, Error: err.Error()})
+		}
+		index++
+	}
+
+	for _, event := range cal.Events() {
+		importItem(&event.ComponentBase, event.Alarms(), ics.ComponentPropertyDtStart)
+	}
+	for _, todo := range cal.Todos() {
+		importItem(&todo.ComponentBase, todo.Alarms(), ics.ComponentPropertyDue, ics.ComponentPropertyDtStart)
+	}
+
+	log.Printf("Imported calendar for user %s: %d created, %d updated, %d errors", userID, result.Created, result.Updated, len(result.Errors))
+	c.JSON(http.StatusOK, result)
+}
+
+// parseCalendarItem reads a VEVENT or VTODO; the first of timeProps present is its due time
+func parseCalendarItem(cb *ics.ComponentBase, alarms []*ics.VAlarm, loc *time.Location, timeProps ...ics.ComponentProperty) (calendarItem, error) {
+	item := calendarItem{UID: cb.Id()}
+
+	if prop := cb.GetProperty(ics.ComponentPropertySummary); prop != nil {
+		item.Summary = strings.TrimSpace(prop.Value)
+	}
+	if item.Summary == "" {
+		return item, fmt.Errorf("item has no summary")
+	}
+	if prop := cb.GetProperty(ics.ComponentPropertyDescription); prop != nil {
+		item.Description = prop.Value
+	}
+	if prop := cb.GetProperty(ics.ComponentPropertyStatus); prop != nil {
+		switch ics.ObjectStatus(strings.ToUpper(prop.Value)) {
+		case ics.ObjectStatusCancelled:
+			return item, fmt.Errorf("item is cancelled")
+		case ics.ObjectStatusCompleted:
+			return item, fmt.Errorf("item is already completed")
+		}
+	}
+	if cb.HasProperty(ics.ComponentPropertyRecurrenceId) {
+		// Overrides of single occurrences would replace the whole series
+		return item, fmt.Errorf("modified occurrences of a recurring item are not supported")
+	}
+	// A series is kept as its RRULE alone, so extra or skipped dates would be lost
+	if cb.HasProperty(ics.ComponentPropertyExdate) {
+		return item, fmt.Errorf("excluded dates (EXDATE) of a recurring item are not supported")
+	}
+	if cb.HasProperty(ics.ComponentPropertyRdate) {
+		return item, fmt.Errorf("extra dates (RDATE) of a recurring item are not supported")
+	}
+
+	var timeProp *ics.IANAProperty
+	for _, p := range timeProps {
+		if timeProp = cb.GetProperty(p); timeProp != nil {
+			break
+		}
+	}
+	if timeProp == nil {
+		return item, fmt.Errorf("item has no start or due time")
+	}
+	start, err := parseICalTime(timeProp, loc)
+	if err != nil {
+		return item, err
+	}
+	// Local times repeat at the same wall-clock time across DST changes (see wallclock.go)
+	if zone := start.Location().String(); !strings.HasSuffix(strings.TrimSpace(timeProp.Value), "Z") && zone != "UTC" {
+		item.Timezone = zone
+	}
+
+	// Fire when the calendar's own alarm would
+	for _, alarm := range alarms {
+		trigger := alarm.GetProperty(ics.ComponentPropertyTrigger)
+		if trigger == nil {
+			continue
+		}
+		if values := trigger.ICalParameters[string(ics.ParameterValue)]; len(values) > 0 && strings.EqualFold(values[0], "DATE-TIME") {
+			continue
+		}
+		offset, err := parseICalDuration(trigger.Value)
+		if err != nil {
+			return item, err
+		}
+		start = start.Add(offset)
+		break
+	}
+	item.Start = start
+
+	if prop := cb.GetProperty(ics.ComponentPropertyRrule); prop != nil {
+		rule, err := normalizeRecurrence(prop.Value)
+		if err != nil {
+			return item, err
+		}
+		item.Recurrence = rule
+	}
+
+	if prop := cb.GetProperty(ics.ComponentPropertyPriority); prop != nil {
+		if p, err := strconv.Atoi(prop.Value); err == nil && p > 0 {
+			switch {
+			case p <= 2:
+				item.Priority = "critical"
+			case p <= 4:
+				item.Priority = "high"
+			case p <= 6:
+				item.Priority = "normal"
+			default:
+				item.Priority = "low"
+			}
+		}
+	}
+
+	for _, prop := range cb.GetProperties(ics.ComponentPropertyCategories) {
+		for _, name := range strings.Split(prop.Value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				item.Categories = append(item.Categories, name)
+			}
+		}
+	}
+
+	return item, nil
+}
+
+// saveImportedItem creates a reminder for the item, or updates the one imported from or exported as the same UID
+func saveImportedItem(userID string, item calendarItem, defaults CreateReminderRequest, listID *string, now time.Time, audit auditInfo) (bool, error) {
+	dueAt := item.Start
+	var recurrenceStart *time.Time
+	if item.Recurrence != "" {
+		start := item.Start
+		recurrenceStart = &start
+		if dueAt.Before(now) {
+			series := Reminder{Recurrence: item.Recurrence, DateTime: start, RecurrenceStart: &start, Timezone: item.Timezone}
+			syncLocalTime(&series)
+			next, ok := nextOccurrence(series, now)
+			if !ok {
+				return false, fmt.Errorf("recurring item has no occurrences left")
+			}
+			dueAt = next
+		}
+	}
+
+	var existing Reminder
+	query := db.Where("user_id = ? AND external_uid = ?", userID, item.UID)
+	if id, ok := strings.CutSuffix(item.UID, "@"+icalUIDDomain); ok {
+		query = db.Where("user_id = ? AND (external_uid = ? OR id = ?)", userID, item.UID, id)
+	}
+	err := query.Preload("Tags").First(&existing).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return false, fmt.Errorf("failed to look up existing reminder")
+	}
+	found := err == nil
+
+	// Past one-off items would fire straight away
+	if dueAt.Before(now) && !found {
+		return false, fmt.Errorf("item is in the past")
+	}
+
+	reminder := existing
+	if !found {
+		// Calendar items carry no team, so without the organization they're imported as personal
+		tenant, err := userTenant(userID)
+		if err != nil {
+			log.Printf("Error looking up organization for user %s, importing as personal: %v", userID, err)
+			tenant = Tenant{}
+		}
+		reminder = Reminder{
+			ID:               uuid.New().String(),
+			UserID:           userID,
+			OrgID:            tenant.OrgID,
+			NotificationType: defaults.NotificationType,
+			Email:            defaults.Email,
+			Phone:            defaults.Phone,
+			ContactIDs:       defaults.ContactIDs,
+			GroupIDs:         defaults.GroupIDs,
+			ListID:           listID,
+			Priority:         defaults.Priority,
+			ExternalUID:      item.UID,
+			Status:           "pending",
+		}
+	}
+	reminder.Title = item.Summary
+	reminder.Description = item.Description
+	reminder.Recurrence = item.Recurrence
+	reminder.RecurrenceStart = recurrenceStart
+	if item.Recurrence != "" {
+		reminder.Timezone = item.Timezone
+	}
+	if item.Priority != "" && defaults.Priority == "" {
+		reminder.Priority = item.Priority
+	}
+	if reminder.Priority == "" {
+		reminder.Priority = "normal"
+	}
+	if !dueAt.Equal(reminder.DateTime) {
+		reminder.DateTime = dueAt
+		// A rescheduled reminder that already went out (or missed its time) is due again;
+		// one being sent right now, or cancelled or acknowledged by the user, keeps its status
+		if dueAt.After(now) {
+			switch reminder.Status {
+			case StatusSent, StatusFailed, StatusExpired, StatusSnoozed:
+				setStatus(&reminder, StatusPending, "")
+			}
+		}
+	}
+
+	syncLocalTime(&reminder)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		tags, err := findOrCreateTags(tx, userID, item.Categories)
+		if err != nil {
+			return err
+		}
+		if !found {
+			reminder.Tags = tags
+			if err := tx.Create(&reminder).Error; err != nil {
+				return err
+			}
+			return recordHistory(tx, audit, "import", nil, &reminder)
+		}
+		if err := updateVersioned(tx, &reminder); err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			if err := tx.Model(&reminder).Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+			reminder.Tags = tags
+		}
+		return recordHistory(tx, audit, "import", &existing, &reminder)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to save reminder")
+	}
+
+	return !found, nil
+}
+
+// parseICalTime reads a DATE or DATE-TIME value, honouring TZID; floating times are read in loc
+func parseICalTime(prop *ics.IANAProperty, loc *time.Location) (time.Time, error) {
+	if tzid := prop.ICalParameters[string(ics.ParameterTzid)]; len(tzid) > 0 {
+		l, err := icalLocation(tzid[0])
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc = l
+	}
+
+	value := strings.TrimSpace(prop.Value)
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		if t, err := time.Parse("20060102T150405Z", value); err == nil {
+			return t, nil
+		}
+	case len(value) == len("20060102"):
+		if t, err := time.ParseInLocation("20060102", value, loc); err == nil {
+			return t.Add(allDayReminderHour * time.Hour), nil
+		}
+	default:
+		if t, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date-time %q", value)
+}
+
+// icalLocation resolves a TZID: an IANA name, a Windows name as Outlook and Exchange write them,
+// or an IANA name behind a vendor prefix ("/mozilla.org/20070129_1/Europe/Berlin")
+func icalLocation(tzid string) (*time.Location, error) {
+	name := strings.TrimSpace(strings.Trim(tzid, `"`))
+	if iana, ok := windowsZones[name]; ok {
+		name = iana
+	}
+	if loc, err := time.LoadLocation(name); err == nil && name != "" {
+		return loc, nil
+	}
+	// Try ever shorter suffixes of a prefixed name: "20070129_1/Europe/Berlin", "Europe/Berlin"
+	for i := strings.Index(name, "/"); i >= 0; i = strings.Index(name, "/") {
+		name = name[i+1:]
+		if loc, err := time.LoadLocation(name); err == nil && strings.Contains(name, "/") {
+			return loc, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown time zone %q", tzid)
+}
+
+// windowsZones maps Windows time zone names to IANA zones (the CLDR "001" territory mapping)
+var windowsZones = map[string]string{
+	"Dateline Standard Time":          "Etc/GMT+12",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Mountain Standard Time":          "America/Denver",
+	"Central America Standard Time":   "America/Guatemala",
+	"Central Standard Time":           "America/Chicago",
+	"Central Standard Time (Mexico)":  "America/Mexico_City",
+	"Canada Central Standard Time":    "America/Regina",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"Eastern Standard Time":           "America/New_York",
+	"US Eastern Standard Time":        "America/Indianapolis",
+	"Atlantic Standard Time":          "America/Halifax",
+	"SA Western Standard Time":        "America/La_Paz",
+	"Newfoundland Standard Time":      "America/St_Johns",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"Argentina Standard Time":         "America/Buenos_Aires",
+	"UTC":                             "UTC",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Romance Standard Time":           "Europe/Paris",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"FLE Standard Time":               "Europe/Kiev",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"Egypt Standard Time":             "Africa/Cairo",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"Turkey Standard Time":            "Europe/Istanbul",
+	"Russian Standard Time":           "Europe/Moscow",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"Pakistan Standard Time":          "Asia/Karachi",
+	"India Standard Time":             "Asia/Calcutta",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"China Standard Time":             "Asia/Shanghai",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"Korea Standard Time":             "Asia/Seoul",
+	"AUS Central Standard Time":       "Australia/Darwin",
+	"E. Australia Standard Time":      "Australia/Brisbane",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+}
+
+// parseICalDuration parses an RFC 5545 duration such as "-PT15M" or "P1DT2H"
+func parseICalDuration(s string) (time.Duration, error) {
+	value := strings.TrimSpace(s)
+	sign := time.Duration(1)
+	if strings.HasPrefix(value, "-") {
+		sign, value = -1, value[1:]
+	} else {
+		value = strings.TrimPrefix(value, "+")
+	}
+	if !strings.HasPrefix(value, "P") || len(value) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var d time.Duration
+	inTime := false
+	num := ""
+	for _, r := range value[1:] {
+		if r >= '0' && r <= '9' {
+			num += string(r)
+			continue
+		}
+		if r == 'T' && !inTime && num == "" {
+			inTime = true
+			continue
+		}
+
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		var unit time.Duration
+		switch {
+		case r == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case r == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case r == 'H' && inTime:
+			unit = time.Hour
+		case r == 'M' && inTime:
+			unit = time.Minute
+		case r == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d += time.Duration(n) * unit
+		num = ""
+	}
+	if num != "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	return sign * d, nil
+}
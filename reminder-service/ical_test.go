@@ -0,0 +1,183 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// parseEvent parses a calendar holding one VEVENT, given by its property lines
+func parseEvent(t *testing.T, lines ...string) *ics.VEvent {
+	t.Helper()
+	body := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\nBEGIN:VEVENT\r\n" +
+		strings.Join(lines, "\r\n") + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	cal, err := ics.ParseCalendar(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("parse calendar: %v", err)
+	}
+	events := cal.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	return events[0]
+}
+
+func TestParseICalTime(t *testing.T) {
+	mustLoadLocation(t, "America/New_York")
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name     string
+		line     string
+		want     string
+		wantZone string
+		wantErr  bool
+	}{
+		{"UTC", "DTSTART:20260310T140000Z", "2026-03-10T14:00:00Z", "UTC", false},
+		{"floating is read in the import zone", "DTSTART:20260310T090000", "2026-03-10T09:00:00+01:00", "Europe/Berlin", false},
+		{"all-day at the reminder hour", "DTSTART;VALUE=DATE:20260310", "2026-03-10T09:00:00+01:00", "Europe/Berlin", false},
+		{"IANA TZID", "DTSTART;TZID=America/New_York:20260310T090000", "2026-03-10T09:00:00-04:00", "America/New_York", false},
+		{"Windows TZID", "DTSTART;TZID=Eastern Standard Time:20260310T090000", "2026-03-10T09:00:00-04:00", "America/New_York", false},
+		{"quoted Windows TZID", `DTSTART;TZID="W. Europe Standard Time":20260310T090000`, "2026-03-10T09:00:00+01:00", "Europe/Berlin", false},
+		{"vendor-prefixed TZID", "DTSTART;TZID=/mozilla.org/20070129_1/America/New_York:20260310T090000", "2026-03-10T09:00:00-04:00", "America/New_York", false},
+		{"unknown TZID", "DTSTART;TZID=Mars Standard Time:20260310T090000", "", "", true},
+		{"garbage", "DTSTART:tomorrow", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prop := parseEvent(t, "UID:1", tt.line).GetProperty(ics.ComponentPropertyDtStart)
+			got, err := parseICalTime(prop, berlin)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseICalTime(%s) = %s, want an error", tt.line, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseICalTime(%s): %v", tt.line, err)
+			}
+			if got.Format(time.RFC3339) != tt.want || got.Location().String() != tt.wantZone {
+				t.Errorf("parseICalTime(%s) = %s in %s, want %s in %s", tt.line, got.Format(time.RFC3339), got.Location(), tt.want, tt.wantZone)
+			}
+		})
+	}
+}
+
+func TestParseICalDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"PT0S", 0, false},
+		{"-PT15M", -15 * time.Minute, false},
+		{"+PT1H30M", 90 * time.Minute, false},
+		{"P1DT2H", 26 * time.Hour, false},
+		{"-P1W", -7 * 24 * time.Hour, false},
+		{"PT", 0, true},
+		{"P1H", 0, true},  // hours need the T
+		{"PT1D", 0, true}, // days don't take it
+		{"PT15", 0, true},
+		{"15M", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseICalDuration(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseICalDuration(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseCalendarItem(t *testing.T) {
+	mustLoadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name    string
+		lines   []string
+		alarm   string // TRIGGER of a VALARM, if any
+		want    calendarItem
+		wantErr string
+	}{
+		{
+			name:  "one-off UTC event",
+			lines: []string{"UID:a", "SUMMARY: Dentist ", "DTSTART:20260310T140000Z", "PRIORITY:2", "CATEGORIES:health, errands"},
+			want:  calendarItem{UID: "a", Summary: "Dentist", Start: time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC), Priority: "critical", Categories: []string{"health", "errands"}},
+		},
+		{
+			name:  "first alarm moves the start",
+			lines: []string{"UID:b", "SUMMARY:Call", "DTSTART:20260310T140000Z"},
+			alarm: "-PT15M",
+			want:  calendarItem{UID: "b", Summary: "Call", Start: time.Date(2026, 3, 10, 13, 45, 0, 0, time.UTC)},
+		},
+		{
+			name:  "recurring event keeps its zone",
+			lines: []string{"UID:c", "SUMMARY:Standup", "DTSTART;TZID=Eastern Standard Time:20260302T090000", "RRULE:FREQ=DAILY"},
+			want:  calendarItem{UID: "c", Summary: "Standup", Start: time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC), Timezone: "America/New_York", Recurrence: "FREQ=DAILY"},
+		},
+		{
+			name:    "no summary",
+			lines:   []string{"UID:d", "DTSTART:20260310T140000Z"},
+			wantErr: "no summary",
+		},
+		{
+			name:    "no start",
+			lines:   []string{"UID:e", "SUMMARY:x"},
+			wantErr: "no start or due time",
+		},
+		{
+			name:    "cancelled",
+			lines:   []string{"UID:f", "SUMMARY:x", "DTSTART:20260310T140000Z", "STATUS:CANCELLED"},
+			wantErr: "cancelled",
+		},
+		{
+			name:    "modified occurrence",
+			lines:   []string{"UID:g", "SUMMARY:x", "DTSTART:20260310T140000Z", "RECURRENCE-ID:20260310T140000Z"},
+			wantErr: "modified occurrences",
+		},
+		{
+			name:    "excluded dates",
+			lines:   []string{"UID:h", "SUMMARY:x", "DTSTART:20260310T140000Z", "RRULE:FREQ=DAILY", "EXDATE:20260311T140000Z"},
+			wantErr: "EXDATE",
+		},
+		{
+			name:    "extra dates",
+			lines:   []string{"UID:i", "SUMMARY:x", "DTSTART:20260310T140000Z", "RRULE:FREQ=DAILY", "RDATE:20260320T140000Z"},
+			wantErr: "RDATE",
+		},
+		{
+			name:    "bad rule",
+			lines:   []string{"UID:j", "SUMMARY:x", "DTSTART:20260310T140000Z", "RRULE:FREQ=SOMETIMES"},
+			wantErr: "invalid recurrence",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := tt.lines
+			if tt.alarm != "" {
+				lines = append(lines, "BEGIN:VALARM", "ACTION:DISPLAY", "TRIGGER:"+tt.alarm, "END:VALARM")
+			}
+			event := parseEvent(t, lines...)
+
+			got, err := parseCalendarItem(&event.ComponentBase, event.Alarms(), time.UTC, ics.ComponentPropertyDtStart)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCalendarItem: %v", err)
+			}
+			if got.UID != tt.want.UID || got.Summary != tt.want.Summary || !got.Start.Equal(tt.want.Start) ||
+				got.Timezone != tt.want.Timezone || got.Recurrence != tt.want.Recurrence || got.Priority != tt.want.Priority ||
+				strings.Join(got.Categories, ",") != strings.Join(tt.want.Categories, ",") {
+				t.Errorf("parseCalendarItem = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
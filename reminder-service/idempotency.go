@@ -0,0 +1,232 @@
+// idempotency.go - Idempotency-Key support for POST endpoints
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	idempotencyTTL       = 24 * time.Hour
+	maxIdempotencyKeyLen = 255
+)
+
+// how long a repeat waits for the first request to finish
+var idempotencyWait = 10 * time.Second
+
+// IdempotencyKey model - the stored outcome of a POST made with an Idempotency-Key
+type IdempotencyKey struct {
+	UserID      string            `json:"user_id" gorm:"primaryKey"`
+	Key         string            `json:"key" gorm:"column:idempotency_key;primaryKey;size:255"`
+	RequestHash string            `json:"request_hash" gorm:"not null"` // method, path and body
+	StatusCode  int               `json:"status_code"`                  // 0 while the first request is still running
+	Headers     map[string]string `json:"headers" gorm:"serializer:json;type:text"`
+	Response    []byte            `json:"-"`
+	ExpiresAt   time.Time         `json:"expires_at" gorm:"index"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// IdempotencyStore keeps the claimed keys and their responses
+type IdempotencyStore interface {
+	Claim(entry IdempotencyKey) (bool, error) // false if the key is already in use
+	Find(userID, key string) (IdempotencyKey, error)
+	Complete(entry IdempotencyKey) error // stores the response of a claimed key
+	Release(userID, key string) error
+	DeleteExpired() (int64, error)
+}
+
+var idempotencyStore IdempotencyStore = postgresIdempotencyStore{}
+
+// Response headers replayed along with the body
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// recordingWriter keeps a copy of the response body as it's written
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotent makes POSTs carrying an Idempotency-Key safe to repeat: the first successful response
+// is stored for 24 hours and replayed for the same key, while the same key with a different request
+// is rejected with 422. Error responses aren't stored, so a corrected request can reuse its key.
+func idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.New()
+		io.WriteString(sum, c.Request.Method+" "+c.Request.URL.RequestURI()+"\n")
+		sum.Write(body)
+		hash := hex.EncodeToString(sum.Sum(nil))
+
+		userID := getUserID(c)
+		claimed, err := idempotencyStore.Claim(IdempotencyKey{
+			UserID: userID, Key: key, RequestHash: hash, ExpiresAt: time.Now().Add(idempotencyTTL),
+		})
+		if err != nil {
+			log.Printf("Error claiming idempotency key: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check Idempotency-Key"})
+			return
+		}
+		if !claimed {
+			replayIdempotentResponse(c, userID, key, hash)
+			return
+		}
+
+		// A handler that panics never gets to store its response; let the key go rather than
+		// answer every retry with "still in progress" until it expires
+		finished := false
+		defer func() {
+			if !finished {
+				releaseIdempotencyKey(userID, key)
+			}
+		}()
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		finished = true
+
+		if writer.Status() >= http.StatusBadRequest {
+			releaseIdempotencyKey(userID, key)
+			return
+		}
+
+		headers := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := writer.Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		err = idempotencyStore.Complete(IdempotencyKey{
+			UserID: userID, Key: key, StatusCode: writer.Status(), Headers: headers, Response: writer.body.Bytes(),
+		})
+		if err != nil {
+			log.Printf("Error storing idempotent response: %v", err)
+		}
+	}
+}
+
+// releaseIdempotencyKey frees a key whose request failed, so it can be retried
+func releaseIdempotencyKey(userID, key string) {
+	if err := idempotencyStore.Release(userID, key); err != nil {
+		log.Printf("Error releasing idempotency key: %v", err)
+	}
+}
+
+// replayIdempotentResponse answers a repeated key with the stored response, waiting a little
+// if the first request is still running
+func replayIdempotentResponse(c *gin.Context, userID, key, hash string) {
+	deadline := time.Now().Add(idempotencyWait)
+	for {
+		entry, err := idempotencyStore.Find(userID, key)
+		if err != nil {
+			// The first request failed and released the key
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "The original request failed, retry it"})
+			return
+		}
+
+		if entry.RequestHash != hash {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+			return
+		}
+
+		if entry.StatusCode != 0 {
+			for name, value := range entry.Headers {
+				c.Header(name, value)
+			}
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(entry.StatusCode, entry.Headers["Content-Type"], entry.Response)
+			c.Abort()
+			return
+		}
+
+		if time.Now().After(deadline) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still in progress"})
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// runIdempotencyCleanup deletes expired idempotency keys hourly
+func runIdempotencyCleanup() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := idempotencyStore.DeleteExpired()
+		if err != nil {
+			log.Printf("Error deleting expired idempotency keys: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Deleted %d expired idempotency keys", deleted)
+		}
+		<-ticker.C
+	}
+}
+
+type postgresIdempotencyStore struct{}
+
+func (postgresIdempotencyStore) Claim(entry IdempotencyKey) (bool, error) {
+	// An expired key is free to use again
+	if err := db.Where("user_id = ? AND idempotency_key = ? AND expires_at < ?", entry.UserID, entry.Key, time.Now()).
+		Delete(&IdempotencyKey{}).Error; err != nil {
+		return false, err
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (postgresIdempotencyStore) Find(userID, key string) (IdempotencyKey, error) {
+	var entry IdempotencyKey
+	err := db.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&entry).Error
+	return entry, err
+}
+
+func (postgresIdempotencyStore) Complete(entry IdempotencyKey) error {
+	return db.Model(&IdempotencyKey{UserID: entry.UserID, Key: entry.Key}).
+		Select("status_code", "headers", "response").
+		Updates(&entry).Error
+}
+
+func (postgresIdempotencyStore) Release(userID, key string) error {
+	return db.Where("user_id = ? AND idempotency_key = ?", userID, key).Delete(&IdempotencyKey{}).Error
+}
+
+func (postgresIdempotencyStore) DeleteExpired() (int64, error) {
+	result := db.Where("expires_at < ?", time.Now()).Delete(&IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
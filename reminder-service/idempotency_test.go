@@ -0,0 +1,202 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// memoryIdempotencyStore stands in for Postgres
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]IdempotencyKey
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{entries: make(map[string]IdempotencyKey)}
+}
+
+func (s *memoryIdempotencyStore) Claim(entry IdempotencyKey) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := entry.UserID + "/" + entry.Key
+	if existing, ok := s.entries[id]; ok && existing.ExpiresAt.After(time.Now()) {
+		return false, nil
+	}
+	s.entries[id] = entry
+	return true, nil
+}
+
+func (s *memoryIdempotencyStore) Find(userID, key string) (IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[userID+"/"+key]
+	if !ok {
+		return entry, gorm.ErrRecordNotFound
+	}
+	return entry, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(entry IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := entry.UserID + "/" + entry.Key
+	stored := s.entries[id]
+	stored.StatusCode, stored.Headers, stored.Response = entry.StatusCode, entry.Headers, entry.Response
+	s.entries[id] = stored
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(userID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, userID+"/"+key)
+	return nil
+}
+
+func (s *memoryIdempotencyStore) DeleteExpired() (int64, error) {
+	return 0, nil
+}
+
+// idempotentRouter serves POST /things behind idempotent(), counting the handler's calls
+func idempotentRouter(t *testing.T, handler gin.HandlerFunc) (*gin.Engine, *memoryIdempotencyStore) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := newMemoryIdempotencyStore()
+	previous := idempotencyStore
+	idempotencyStore = store
+	t.Cleanup(func() { idempotencyStore = previous })
+
+	router := gin.New()
+	router.Use(gin.RecoveryWithWriter(io.Discard), idempotent())
+	router.POST("/things", handler)
+	return router, store
+}
+
+func postThing(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", "u1")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	var calls int32
+	router, _ := idempotentRouter(t, func(c *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.Header("Location", "/things/1")
+		c.JSON(http.StatusCreated, gin.H{"id": "1", "call": n})
+	})
+
+	first := postThing(router, "k1", `{"title":"a"}`)
+	second := postThing(router, "k1", `{"title":"a"}`)
+
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" || second.Header().Get("Location") != "/things/1" {
+		t.Errorf("replay headers = %v", second.Header())
+	}
+
+	// Without a key nothing is deduplicated
+	postThing(router, "", `{"title":"a"}`)
+	postThing(router, "", `{"title":"a"}`)
+	if calls != 3 {
+		t.Errorf("handler ran %d times, want 3", calls)
+	}
+}
+
+func TestIdempotentKeyReusedWithDifferentBody(t *testing.T) {
+	router, _ := idempotentRouter(t, func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": "1"})
+	})
+
+	postThing(router, "k1", `{"title":"a"}`)
+	if w := postThing(router, "k1", `{"title":"b"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key with another body = %d, want 422", w.Code)
+	}
+}
+
+func TestIdempotentErrorReleasesKey(t *testing.T) {
+	var calls int32
+	router, _ := idempotentRouter(t, func(c *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bad"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": "1"})
+	})
+
+	postThing(router, "k1", `{}`)
+	if w := postThing(router, "k1", `{}`); w.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("retry after an error = %d after %d calls, want 201 after 2", w.Code, calls)
+	}
+}
+
+func TestIdempotentPanicReleasesKey(t *testing.T) {
+	var calls int32
+	router, store := idempotentRouter(t, func(c *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			panic("boom")
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": "1"})
+	})
+
+	if w := postThing(router, "k1", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("panicking handler = %d, want 500", w.Code)
+	}
+	if _, err := store.Find("u1", "k1"); err != gorm.ErrRecordNotFound {
+		t.Fatalf("key after a panic: err = %v, want it released", err)
+	}
+	if w := postThing(router, "k1", `{}`); w.Code != http.StatusCreated {
+		t.Errorf("retry after a panic = %d, want 201", w.Code)
+	}
+}
+
+func TestIdempotentStillInProgress(t *testing.T) {
+	previousWait := idempotencyWait
+	idempotencyWait = 200 * time.Millisecond
+	t.Cleanup(func() { idempotencyWait = previousWait })
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router, _ := idempotentRouter(t, func(c *gin.Context) {
+		close(started)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"id": "1"})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postThing(router, "k1", `{}`) }()
+	<-started
+
+	// The repeat gives up waiting for the first request
+	if w := postThing(router, "k1", `{}`); w.Code != http.StatusConflict {
+		t.Errorf("repeat while in progress = %d, want 409", w.Code)
+	}
+
+	close(release)
+	first := <-done
+
+	// Once it's done, repeats get its response
+	if w := postThing(router, "k1", `{}`); w.Code != http.StatusCreated || w.Body.String() != first.Body.String() {
+		t.Errorf("repeat after completion = %d %s, want %d %s", w.Code, w.Body, first.Code, first.Body)
+	}
+}
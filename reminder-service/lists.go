@@ -0,0 +1,284 @@
+// lists.go - Reminder lists and folders
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReminderList model - a user-defined list ("Work", "Family"); a list with a parent sits in that folder.
+// Defaults are applied to reminders created in the list when the request leaves them out.
+type ReminderList struct {
+	ID                      string    `json:"id" gorm:"primaryKey"`
+	UserID                  string    `json:"user_id" gorm:"index;not null"`
+	Name                    string    `json:"name" gorm:"not null"`
+	Color                   string    `json:"color,omitempty"`
+	ParentID                *string   `json:"parent_id,omitempty" gorm:"index"`
+	DefaultNotificationType string    `json:"default_notification_type,omitempty"`
+	DefaultEmail            string    `json:"default_email,omitempty"`
+	DefaultPhone            string    `json:"default_phone,omitempty"`
+	DefaultContactIDs       []string  `json:"default_contact_ids,omitempty" gorm:"serializer:json;type:text"`
+	DefaultGroupIDs         []string  `json:"default_group_ids,omitempty" gorm:"serializer:json;type:text"`
+	DefaultLeadTimes        []int     `json:"default_lead_times,omitempty" gorm:"serializer:json;type:text"` // minutes before the due time
+	CreatedAt               time.Time `json:"created_at"`
+	UpdatedAt               time.Time `json:"updated_at"`
+}
+
+// ReminderListRequest DTO
+type ReminderListRequest struct {
+	Name                    string   `json:"name"`
+	Color                   string   `json:"color" binding:"omitempty,hexcolor"`
+	ParentID                *string  `json:"parent_id"`
+	DefaultNotificationType string   `json:"default_notification_type" binding:"omitempty,oneof=email sms"`
+	DefaultEmail            string   `json:"default_email"`
+	DefaultPhone            string   `json:"default_phone"`
+	DefaultContactIDs       []string `json:"default_contact_ids"`
+	DefaultGroupIDs         []string `json:"default_group_ids"`
+	DefaultLeadTimes        []int    `json:"default_lead_times" binding:"omitempty,dive,min=1"`
+}
+
+func listReminderLists(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	// Including lists shared with the user
+	var lists []ReminderList
+	err := db.Where("user_id = ? OR id IN (?)", userID,
+		db.Model(&Share{}).Select("list_id").Where("user_id = ? AND list_id IS NOT NULL", userID)).
+		Order("name asc").Find(&lists).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, lists)
+}
+
+func getReminderList(c *gin.Context) {
+	list, err := findSharedList(c.Param("id"), getUserID(c))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func createReminderList(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req ReminderListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	list := ReminderList{
+		ID:     uuid.New().String(),
+		UserID: userID,
+	}
+	applyListRequest(&list, req)
+
+	if list.ParentID != nil {
+		if _, err := findReminderList(*list.ParentID, userID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent list not found"})
+			return
+		}
+	}
+
+	if err := db.Create(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create list"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, list)
+}
+
+func updateReminderList(c *gin.Context) {
+	userID := getUserID(c)
+
+	var req ReminderListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := findReminderList(c.Param("id"), userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch list"})
+		return
+	}
+
+	applyListRequest(&list, req)
+
+	if list.ParentID != nil {
+		if *list.ParentID == list.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A list cannot be its own parent"})
+			return
+		}
+		if _, err := findReminderList(*list.ParentID, userID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent list not found"})
+			return
+		}
+		inside, err := isListAncestor(list.ID, *list.ParentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch list"})
+			return
+		}
+		if inside {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A list cannot be moved into one of its own sublists"})
+			return
+		}
+	}
+
+	if err := db.Save(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func deleteReminderList(c *gin.Context) {
+	id := c.Param("id")
+
+	// Reminders and child lists are kept, just no longer filed under this list
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, getUserID(c)).Delete(&ReminderList{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// Including trashed reminders, so a restore doesn't bring back a dangling list
+		if err := tx.Unscoped().Model(&Reminder{}).Where("list_id = ?", id).
+			Updates(map[string]interface{}{"list_id": nil, "version": gorm.Expr("version + 1")}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("list_id = ?", id).Delete(&Share{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&ReminderList{}).Where("parent_id = ?", id).Update("parent_id", nil).Error
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "List deleted successfully"})
+}
+
+// isListAncestor reports whether ancestorID is listID or one of the folders above it
+func isListAncestor(ancestorID, listID string) (bool, error) {
+	seen := make(map[string]bool)
+	for id := listID; !seen[id]; {
+		if id == ancestorID {
+			return true, nil
+		}
+		seen[id] = true
+
+		var list ReminderList
+		err := db.Select("id", "parent_id").Where("id = ?", id).First(&list).Error
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if list.ParentID == nil {
+			return false, nil
+		}
+		id = *list.ParentID
+	}
+	// Already a cycle above; don't make it worse
+	return true, nil
+}
+
+func findReminderList(id, userID string) (ReminderList, error) {
+	var list ReminderList
+	err := db.Where("id = ? AND user_id = ?", id, userID).First(&list).Error
+	return list, err
+}
+
+func applyListRequest(list *ReminderList, req ReminderListRequest) {
+	if req.Name != "" {
+		list.Name = req.Name
+	}
+	if req.Color != "" {
+		list.Color = req.Color
+	}
+	if req.ParentID != nil {
+		// An empty parent moves the list back to the top level
+		if *req.ParentID == "" {
+			list.ParentID = nil
+		} else {
+			list.ParentID = req.ParentID
+		}
+	}
+	if req.DefaultNotificationType != "" {
+		list.DefaultNotificationType = req.DefaultNotificationType
+	}
+	if req.DefaultEmail != "" {
+		list.DefaultEmail = req.DefaultEmail
+	}
+	if req.DefaultPhone != "" {
+		list.DefaultPhone = req.DefaultPhone
+	}
+	if req.DefaultContactIDs != nil {
+		list.DefaultContactIDs = req.DefaultContactIDs
+	}
+	if req.DefaultGroupIDs != nil {
+		list.DefaultGroupIDs = req.DefaultGroupIDs
+	}
+	if req.DefaultLeadTimes != nil {
+		list.DefaultLeadTimes = req.DefaultLeadTimes
+	}
+}
+
+// applyListDefaults fills fields the create request left out from the list's defaults
+func applyListDefaults(req *CreateReminderRequest, list ReminderList) {
+	if req.NotificationType == "" {
+		req.NotificationType = list.DefaultNotificationType
+	}
+	if req.Email == "" && len(req.ContactIDs) == 0 && len(req.GroupIDs) == 0 {
+		req.Email = list.DefaultEmail
+	}
+	if req.Phone == "" && len(req.ContactIDs) == 0 && len(req.GroupIDs) == 0 {
+		req.Phone = list.DefaultPhone
+	}
+	if req.ContactIDs == nil && req.GroupIDs == nil {
+		req.ContactIDs = list.DefaultContactIDs
+		req.GroupIDs = list.DefaultGroupIDs
+	}
+	if req.LeadTimes == nil {
+		req.LeadTimes = list.DefaultLeadTimes
+	}
+}
@@ -2,6 +2,7 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -11,42 +12,95 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// serviceName is who this service is in its service token and X-Service-Name (see auth.go)
+const serviceName = "reminder-service"
+
 // Reminder model
 type Reminder struct {
-	ID               string    `json:"id" gorm:"primaryKey"`
-	UserID           string    `json:"user_id" gorm:"index"`
-	Title            string    `json:"title" gorm:"not null"`
-	Description      string    `json:"description"`
-	DateTime         time.Time `json:"datetime" gorm:"column:date_time;not null"`
-	NotificationType string    `json:"notification_type" gorm:"not null"` // email or sms
-	Email            string    `json:"email,omitempty"`
-	Phone            string    `json:"phone,omitempty"`
-	Status           string    `json:"status" gorm:"default:'pending'"` // pending, processing, sent, failed
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID                string         `json:"id" gorm:"primaryKey"`
+	UserID            string         `json:"user_id" gorm:"index;index:idx_reminders_user_date,priority:1;index:idx_reminders_user_status_date,priority:1"`
+	OrgID             string         `json:"org_id,omitempty" gorm:"index;not null;default:''"`  // the owner's organization; empty for personal reminders
+	TeamID            string         `json:"team_id,omitempty" gorm:"index;not null;default:''"` // a team in the owner's organization that can see it; empty keeps it to the owner and their shares
+	Title             string         `json:"title" gorm:"not null"`
+	Description       string         `json:"description"`
+	DateTime          time.Time      `json:"datetime" gorm:"column:date_time;not null;index:idx_reminders_user_date,priority:2;index:idx_reminders_user_status_date,priority:3;index:idx_reminders_status_date,priority:2"`
+	NotificationType  string         `json:"notification_type" gorm:"not null"` // email or sms
+	Email             string         `json:"email,omitempty"`
+	Phone             string         `json:"phone,omitempty"`
+	ContactIDs        []string       `json:"contact_ids,omitempty" gorm:"serializer:json;type:text"` // resolved via user-service at send time
+	GroupIDs          []string       `json:"group_ids,omitempty" gorm:"serializer:json;type:text"`
+	ListID            *string        `json:"list_id,omitempty" gorm:"index"`
+	Tags              []Tag          `json:"tags,omitempty" gorm:"many2many:reminder_tags;"`
+	Recurrence        string         `json:"recurrence,omitempty"`                                          // RRULE, e.g. "FREQ=WEEKLY;BYDAY=MO"
+	RecurrenceStart   *time.Time     `json:"recurrence_start,omitempty"`                                    // first occurrence (DTSTART) of the series
+	Timezone          string         `json:"timezone,omitempty"`                                            // IANA zone or "floating" for a wall-clock time; empty for a fixed instant (see wallclock.go)
+	LocalTime         string         `json:"local_time,omitempty"`                                          // wall-clock time of the series start (or due time) in Timezone
+	CatchUp           string         `json:"catch_up,omitempty"`                                            // fire, grace or expire when found past due; empty for the user's default
+	GraceMinutes      int            `json:"grace_minutes,omitempty"`                                       // how late a grace reminder may still fire; 0 for the user's default
+	DeliveredBy       string         `json:"delivered_by,omitempty"`                                        // "digest" if its last occurrence went out in a digest rather than on its own
+	PublishedChannels []string       `json:"published_channels,omitempty" gorm:"serializer:json;type:text"` // channels this occurrence already went out on before a failure; a retry skips them
+	Collaborators     []string       `json:"collaborators,omitempty" gorm:"-"`
+	ExternalUID       string         `json:"external_uid,omitempty" gorm:"index"`                                                                                        // UID of the calendar item this was imported from
+	Priority          string         `json:"priority" gorm:"default:'normal'"`                                                                                           // low, normal, high, critical
+	Status            string         `json:"status" gorm:"default:'pending';index:idx_reminders_user_status_date,priority:2;index:idx_reminders_status_date,priority:1"` // see status.go
+	Attempts          int            `json:"attempts" gorm:"default:0"`                                                                                                  // times the scheduler has picked it up
+	LastError         string         `json:"last_error,omitempty"`                                                                                                       // why the last delivery failed
+	FailedAt          *time.Time     `json:"failed_at,omitempty"`
+	RequeuedAt        *time.Time     `json:"requeued_at,omitempty"`             // last put back in the queue by hand (retried, reinstated); the scheduler's catch-up lateness counts from here
+	DeletedAt         gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"` // set while the reminder is in the trash
+	Version           int            `json:"version" gorm:"not null;default:1"` // bumped on every write, served as the ETag
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
 }
 
 // CreateReminderRequest DTO
 type CreateReminderRequest struct {
-	Title            string `json:"title" binding:"required"`
-	Description      string `json:"description"`
-	DateTime         string `json:"datetime" binding:"required"`
-	NotificationType string `json:"notification_type" binding:"required,oneof=email sms"`
-	Email            string `json:"email"`
-	Phone            string `json:"phone"`
+	Title            string   `json:"title" binding:"required"`
+	Description      string   `json:"description"`
+	DateTime         string   `json:"datetime"`                                              // RFC3339; or give when instead
+	When             string   `json:"when"`                                                  // natural language, e.g. "tomorrow 9am" or "every weekday at 8:30"
+	Timezone         string   `json:"timezone"`                                              // keep the time as wall-clock in this IANA zone, or "floating"; also the zone when is read in
+	NotificationType string   `json:"notification_type" binding:"omitempty,oneof=email sms"` // may come from the list's defaults
+	Email            string   `json:"email"`
+	Phone            string   `json:"phone"`
+	ContactIDs       []string `json:"contact_ids"`
+	GroupIDs         []string `json:"group_ids"`
+	Priority         string   `json:"priority" binding:"omitempty,oneof=low normal high critical"`
+	ListID           string   `json:"list_id"`
+	TeamID           string   `json:"team_id"`                                   // one of the user's teams, to let it see the reminder
+	Tags             []string `json:"tags"`                                      // tag names, created on first use
+	Recurrence       string   `json:"recurrence"`                                // RRULE; the reminder repeats after each send
+	LeadTimes        []int    `json:"lead_times" binding:"omitempty,dive,min=1"` // minutes; an early reminder is created for each
+	CatchUp          string   `json:"catch_up" binding:"omitempty,oneof=fire grace expire"`
+	GraceMinutes     int      `json:"grace_minutes" binding:"omitempty,min=1"`
 }
 
 // UpdateReminderRequest DTO
 type UpdateReminderRequest struct {
-	Title            string `json:"title"`
-	Description      string `json:"description"`
-	DateTime         string `json:"datetime"`
-	NotificationType string `json:"notification_type" binding:"omitempty,oneof=email sms"`
-	Email            string `json:"email"`
-	Phone            string `json:"phone"`
-	Status           string `json:"status" binding:"omitempty,oneof=pending processing sent failed"`
+	Title             string   `json:"title"`
+	Description       string   `json:"description"`
+	DateTime          string   `json:"datetime"`
+	NotificationType  string   `json:"notification_type" binding:"omitempty,oneof=email sms"`
+	Email             string   `json:"email"`
+	Phone             string   `json:"phone"`
+	ContactIDs        []string `json:"contact_ids"`
+	GroupIDs          []string `json:"group_ids"`
+	Priority          string   `json:"priority" binding:"omitempty,oneof=low normal high critical"`
+	ListID            *string  `json:"list_id"` // empty string removes the reminder from its list
+	TeamID            *string  `json:"team_id"` // empty string hides the reminder from its team again
+	Tags              []string `json:"tags"`
+	Timezone          *string  `json:"timezone"`                                             // IANA zone or "floating"; empty string makes the time a fixed instant
+	Recurrence        *string  `json:"recurrence"`                                           // empty string stops the reminder repeating
+	CatchUp           *string  `json:"catch_up" binding:"omitempty,oneof=fire grace expire"` // empty string goes back to the user's default
+	GraceMinutes      *int     `json:"grace_minutes" binding:"omitempty,min=0"`              // 0 goes back to the user's default
+	Status            string   `json:"status" binding:"omitempty,oneof=pending processing sent failed cancelled snoozed acknowledged expired"`
+	Error             string   `json:"error"`                                         // delivery error, recorded with status failed
+	ExpectedStatus    string   `json:"expected_status"`                               // only change the status if it's still this
+	DeliveredBy       string   `json:"delivered_by" binding:"omitempty,oneof=digest"` // with status sent
+	PublishedChannels []string `json:"published_channels"`                            // with status failed: channels that went out before the failure
 }
 
 // RabbitMQ message structure
@@ -60,31 +114,107 @@ type ReminderNotification struct {
 	Phone            string    `json:"phone,omitempty"`
 }
 
-var db *gorm.DB
+var (
+	db             *gorm.DB
+	userServiceURL string
+)
 
 func main() {
+	// Load the token signing key
+	initAuth()
+
+	// Timezones and organizations come from the user service
+	userServiceURL = os.Getenv("USER_SERVICE_URL")
+	if userServiceURL == "" {
+		userServiceURL = "http://user-service:8084"
+	}
+
 	// Initialize database
 	initDB()
 
+	// Purge reminders that have sat in the trash past the retention period
+	go runTrashRetention()
+
+	// Forget idempotency keys after 24 hours
+	go runIdempotencyCleanup()
+
 	// Initialize Gin router
 	router := gin.Default()
+	router.Use(requestID())
 
 	// CORS is handled by the API Gateway (nginx)
 
 	// Health check
 	router.GET("/health", healthCheck)
 
+	// Calendar feeds are public - the token in the URL is the credential
+	router.GET("/api/reminders/feeds/:token", serveCalendarFeed)
+
+	// Everything else needs a bearer token; internal endpoints need a service (or admin) token
+	internal := requireRole(roleService, roleAdmin)
+
 	// Reminder routes
 	api := router.Group("/api/reminders")
+	api.Use(authMiddleware(), idempotent()) // Idempotency-Key on POSTs
 	{
 		api.GET("", listReminders)
+		api.GET("/search", searchReminders)
+		api.GET("/export.ics", exportICS)
+		api.POST("/import", importReminders)
+		api.GET("/export", exportReminders)
+		api.POST("/batch", batchReminders)
+		api.POST("/parse", parseWhen)                                 // Preview a natural-language when
+		api.POST("/timezone-change", internal, moveFloatingReminders) // From user-service
+		api.POST("/tenant-change", internal, moveToTenant)            // From user-service
+		api.POST("/tenant-dissolve", internal, dissolveTenant)        // From user-service
+		api.POST("/digest", internal, claimDigestReminders)           // For scheduler service
+		api.GET("/failed", listFailedReminders)
+		api.POST("/retry", retryReminders)
+		api.POST("/:id/retry", retryReminder)
+		api.GET("/feed", getCalendarFeed)
+		api.POST("/feed/rotate", rotateCalendarFeed)
 		api.GET("/:id", getReminder)
 		api.POST("", createReminder)
 		api.PUT("/:id", updateReminder)
-		api.DELETE("/:id", deleteReminder)
-		api.GET("/pending", getPendingReminders)                // For scheduler service
-		api.POST("/reset-stuck", resetStuckProcessingReminders) // For scheduler service
-		api.PUT("/:id/status", updateReminderStatusOnly)        // For scheduler service - status only updates
+		api.PATCH("/:id", patchReminder)   // JSON Merge Patch
+		api.DELETE("/:id", deleteReminder) // Moves it to the trash
+		api.GET("/trash", listTrash)
+		api.POST("/:id/restore", restoreReminder)
+		api.GET("/:id/history", getReminderHistory)
+		api.GET("/shared", listSharedWithMe) // Shares other users have given the caller
+		api.GET("/:id/shares", listReminderShares)
+		api.POST("/:id/shares", createReminderShare)
+		api.DELETE("/:id/shares/:shareId", deleteReminderShare)
+		api.DELETE("/trash/:id", purgeReminder)
+		api.DELETE("/trash", emptyTrash)
+		api.GET("/pending", internal, getPendingReminders)                 // For scheduler service
+		api.POST("/reset-stuck", internal, resetStuckProcessingReminders)  // For scheduler service
+		api.PUT("/:id/status", internal, updateReminderStatusOnly)         // For scheduler service - status only updates
+		api.POST("/:id/delivery-failure", internal, reportDeliveryFailure) // For notification service
+	}
+
+	// List routes
+	lists := router.Group("/api/lists")
+	lists.Use(authMiddleware())
+	{
+		lists.GET("", listReminderLists)
+		lists.GET("/:id", getReminderList)
+		lists.POST("", createReminderList)
+		lists.PUT("/:id", updateReminderList)
+		lists.DELETE("/:id", deleteReminderList)
+		lists.GET("/:id/shares", listListShares)
+		lists.POST("/:id/shares", createListShare)
+		lists.DELETE("/:id/shares/:shareId", deleteListShare)
+	}
+
+	// Tag routes
+	tags := router.Group("/api/tags")
+	tags.Use(authMiddleware())
+	{
+		tags.GET("", listTags)
+		tags.POST("", createTag)
+		tags.PUT("/:id", updateTag)
+		tags.DELETE("/:id", deleteTag)
 	}
 
 	// Start server
@@ -112,15 +242,26 @@ func initDB() {
 	}
 
 	// Auto migrate the schema
-	if err := db.AutoMigrate(&Reminder{}); err != nil {
+	if err := db.AutoMigrate(&Reminder{}, &ReminderList{}, &Tag{}, &CalendarFeed{}, &ReminderHistory{}, &IdempotencyKey{}, &Share{}); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+	initSearch()
 
 	log.Println("Database connected and migrated successfully")
 }
 
 // CORS middleware removed - handled by API Gateway
 
+// getUserID returns the calling user from header or query; for user tokens authMiddleware has set
+// both from the token
+func getUserID(c *gin.Context) string {
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		userID = c.Query("user_id")
+	}
+	return userID
+}
+
 func healthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "healthy",
@@ -136,29 +277,32 @@ func listReminders(c *gin.Context) {
 		return
 	}
 
-	var reminders []Reminder
-	if err := db.Where("user_id = ?", userID).Order("date_time asc").Find(&reminders).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminders"})
+	filter, err := parseReminderFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, reminders)
+	// Including reminders shared with the user
+	respondWithPage(c, filter.Apply(accessibleReminders(db.Model(&Reminder{}), userID, permissionView)), opts)
 }
 
 func getReminder(c *gin.Context) {
 	id := c.Param("id")
 	userID := c.Query("user_id")
 
-	var reminder Reminder
-	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&reminder).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminder"})
+	reminder, err := findReminder(db.Preload("Tags"), id, userID, permissionView)
+	if err != nil {
+		respondReminderError(c, err)
 		return
 	}
 
+	c.Header("ETag", reminderETag(&reminder))
 	c.JSON(http.StatusOK, reminder)
 }
 
@@ -169,53 +313,198 @@ func createReminder(c *gin.Context) {
 		return
 	}
 
-	// Validate notification type requirements
-	if req.NotificationType == "email" && req.Email == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required for email notifications"})
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
 		return
 	}
-	if req.NotificationType == "sms" && req.Phone == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Phone is required for SMS notifications"})
+
+	reminder, err := newReminder(&req, userID)
+	if err == errTenantUnavailable {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
 		return
 	}
-
-	// Parse datetime
-	datetime, err := time.Parse(time.RFC3339, req.DateTime)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid datetime format. Use RFC3339"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Get user ID from header or query (in production, extract from JWT)
-	userID := c.GetHeader("X-User-ID")
-	if userID == "" {
-		userID = c.Query("user_id")
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return insertReminder(tx, &reminder, req, auditFrom(c), "create")
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reminder"})
+		return
 	}
-	if userID == "" {
-		userID = "default-user" // For testing
+
+	// TODO: Publish event to RabbitMQ for scheduler service
+	// publishReminderCreated(reminder)
+
+	c.Header("ETag", reminderETag(&reminder))
+	c.JSON(http.StatusCreated, reminder)
+}
+
+// newReminder validates a create request and builds the reminder it describes.
+// Shared by createReminder and bulk import so both apply the same rules.
+func newReminder(req *CreateReminderRequest, userID string) (Reminder, error) {
+	// Fill in whatever the request left out from the list's defaults
+	var listID *string
+	if req.ListID != "" {
+		list, err := findReminderList(req.ListID, userID)
+		if err != nil {
+			return Reminder{}, fmt.Errorf("List not found")
+		}
+		applyListDefaults(req, list)
+		listID = &list.ID
+	}
+
+	if req.NotificationType == "" {
+		return Reminder{}, fmt.Errorf("notification_type is required")
+	}
+
+	// Validate notification type requirements (contacts and groups are resolved at send time)
+	hasContacts := len(req.ContactIDs) > 0 || len(req.GroupIDs) > 0
+	if req.NotificationType == "email" && req.Email == "" && !hasContacts {
+		return Reminder{}, fmt.Errorf("Email is required for email notifications")
+	}
+	if req.NotificationType == "sms" && req.Phone == "" && !hasContacts {
+		return Reminder{}, fmt.Errorf("Phone is required for SMS notifications")
+	}
+
+	// A wall-clock reminder is read in its own zone
+	if err := validateTimezone(req.Timezone); err != nil {
+		return Reminder{}, err
+	}
+	var zoneLoc *time.Location
+	if req.Timezone != "" {
+		zoneLoc = reminderLocation(Reminder{UserID: userID, Timezone: req.Timezone})
+	}
+
+	// Parse datetime, or the natural-language when (which may also say how it repeats)
+	var datetime time.Time
+	switch {
+	case req.When != "" && req.DateTime != "":
+		return Reminder{}, fmt.Errorf("Give either datetime or when, not both")
+	case req.When != "":
+		loc := zoneLoc
+		if loc == nil {
+			loc = userTimezone(userID)
+		}
+		parsed, err := parseNaturalTime(req.When, time.Now().In(loc))
+		if err != nil {
+			return Reminder{}, err
+		}
+		if parsed.Recurrence != "" {
+			if req.Recurrence != "" {
+				return Reminder{}, fmt.Errorf("when already says how the reminder repeats, leave out recurrence")
+			}
+			req.Recurrence = parsed.Recurrence
+		}
+		datetime = parsed.DateTime
+	case req.DateTime == "":
+		return Reminder{}, fmt.Errorf("datetime or when is required")
+	default:
+		var err error
+		if datetime, err = parseReminderTime(req.DateTime, zoneLoc); err != nil {
+			return Reminder{}, err
+		}
+	}
+
+	recurrence, err := normalizeRecurrence(req.Recurrence)
+	if err != nil {
+		return Reminder{}, err
+	}
+
+	tenant, err := userTenant(userID)
+	if err != nil {
+		// Without a team to check it can still be saved, as a personal reminder
+		if req.TeamID != "" {
+			log.Printf("Error looking up organization for user %s: %v", userID, err)
+			return Reminder{}, errTenantUnavailable
+		}
+		tenant = Tenant{}
+	}
+	if req.TeamID != "" && !inTeam(tenant, req.TeamID) {
+		return Reminder{}, fmt.Errorf("Team not found")
 	}
 
 	reminder := Reminder{
 		ID:               uuid.New().String(),
 		UserID:           userID,
+		OrgID:            tenant.OrgID,
+		TeamID:           req.TeamID,
 		Title:            req.Title,
 		Description:      req.Description,
 		DateTime:         datetime,
 		NotificationType: req.NotificationType,
 		Email:            req.Email,
 		Phone:            req.Phone,
+		ContactIDs:       req.ContactIDs,
+		GroupIDs:         req.GroupIDs,
+		ListID:           listID,
+		Recurrence:       recurrence,
+		Timezone:         req.Timezone,
+		CatchUp:          req.CatchUp,
+		GraceMinutes:     req.GraceMinutes,
+		Priority:         req.Priority,
 		Status:           "pending",
 	}
+	if reminder.Priority == "" {
+		reminder.Priority = "normal"
+	}
+	if reminder.Recurrence != "" {
+		reminder.RecurrenceStart = &datetime
+	}
+	syncLocalTime(&reminder)
 
-	if err := db.Create(&reminder).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reminder"})
-		return
+	return reminder, nil
+}
+
+// Early reminders are reminders of their own, titled after the one they lead (as the UI names them)
+const (
+	earlyTitlePrefix       = "⏰ Early Reminder: "
+	earlyDescriptionPrefix = "This is an early reminder for: "
+)
+
+// insertReminder saves a new reminder with its tags and the early reminders for its lead times,
+// recording each in the history under the given action
+func insertReminder(tx *gorm.DB, reminder *Reminder, req CreateReminderRequest, audit auditInfo, action string) error {
+	tags, err := findOrCreateTags(tx, reminder.UserID, req.Tags)
+	if err != nil {
+		return err
 	}
+	reminder.Tags = tags
 
-	// TODO: Publish event to RabbitMQ for scheduler service
-	// publishReminderCreated(reminder)
+	if err := tx.Create(reminder).Error; err != nil {
+		return err
+	}
+	if err := recordHistory(tx, audit, action, nil, reminder); err != nil {
+		return err
+	}
 
-	c.JSON(http.StatusCreated, reminder)
+	// Early reminders, matching what the UI creates for "remind me earlier"
+	for _, minutes := range req.LeadTimes {
+		early := *reminder
+		early.ID = uuid.New().String()
+		early.Title = earlyTitlePrefix + reminder.Title
+		early.Description = earlyDescriptionPrefix + reminder.Description
+		early.DateTime = reminder.DateTime.Add(-time.Duration(minutes) * time.Minute)
+		if early.DateTime.Before(time.Now()) {
+			continue
+		}
+		if early.Recurrence != "" {
+			// Repeats on the same rule, shifted by the lead time
+			start := early.DateTime
+			early.RecurrenceStart = &start
+		}
+		syncLocalTime(&early)
+		if err := tx.Create(&early).Error; err != nil {
+			return err
+		}
+		if err := recordHistory(tx, audit, action, nil, &early); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func updateReminder(c *gin.Context) {
@@ -230,27 +519,38 @@ func updateReminder(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	// Delivery details are reported by the scheduler and notification services, never by users
+	if userID != "" && (req.Error != "" || req.DeliveredBy != "" || req.PublishedChannels != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error, delivered_by and published_channels are set by the delivery services"})
+		return
+	}
 
 	var reminder Reminder
 	var err error
 
 	// If no user_id provided (scheduler service), just find by ID
 	if userID == "" {
-		err = db.Where("id = ?", id).First(&reminder).Error
+		err = db.Preload("Tags").Where("id = ?", id).First(&reminder).Error
 	} else {
-		// Regular user update, check user ownership
-		err = db.Where("id = ? AND user_id = ?", id, userID).First(&reminder).Error
+		// Regular user update: the owner, or a collaborator with edit permission
+		reminder, err = findReminder(db.Preload("Tags"), id, userID, permissionEdit)
 	}
 
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminder"})
+		respondReminderError(c, err)
 		return
 	}
 
+	if !checkIfMatch(c, &reminder) {
+		return
+	}
+	if req.ExpectedStatus != "" && reminder.Status != req.ExpectedStatus {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Reminder is %s, not %s", reminder.Status, req.ExpectedStatus)})
+		return
+	}
+
+	before := reminder
+
 	// Update fields
 	if req.Title != "" {
 		reminder.Title = req.Title
@@ -258,13 +558,43 @@ func updateReminder(c *gin.Context) {
 	if req.Description != "" {
 		reminder.Description = req.Description
 	}
+	if req.Timezone != nil {
+		if err := validateTimezone(*req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		reminder.Timezone = *req.Timezone
+	}
 	if req.DateTime != "" {
-		datetime, err := time.Parse(time.RFC3339, req.DateTime)
+		var loc *time.Location
+		if reminder.Timezone != "" {
+			loc = reminderLocation(reminder)
+		}
+		datetime, err := parseReminderTime(req.DateTime, loc)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid datetime format"})
 			return
 		}
 		reminder.DateTime = datetime
+		// Re-anchor the series when the user moves it; the scheduler's quiet-hours
+		// deferrals (no user_id) and snoozes only move this one occurrence
+		if reminder.Recurrence != "" && userID != "" && req.Status != StatusSnoozed {
+			reminder.RecurrenceStart = &datetime
+		}
+	}
+	if req.Recurrence != nil {
+		recurrence, err := normalizeRecurrence(*req.Recurrence)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		reminder.Recurrence = recurrence
+		if recurrence == "" {
+			reminder.RecurrenceStart = nil
+		} else {
+			start := reminder.DateTime
+			reminder.RecurrenceStart = &start
+		}
 	}
 	if req.NotificationType != "" {
 		reminder.NotificationType = req.NotificationType
@@ -275,15 +605,88 @@ func updateReminder(c *gin.Context) {
 	if req.Phone != "" {
 		reminder.Phone = req.Phone
 	}
+	if req.ContactIDs != nil {
+		reminder.ContactIDs = req.ContactIDs
+	}
+	if req.GroupIDs != nil {
+		reminder.GroupIDs = req.GroupIDs
+	}
+	if req.Priority != "" {
+		reminder.Priority = req.Priority
+	}
+	if req.CatchUp != nil {
+		reminder.CatchUp = *req.CatchUp
+	}
+	if req.GraceMinutes != nil {
+		reminder.GraceMinutes = *req.GraceMinutes
+	}
+	if req.ListID != nil {
+		if *req.ListID == "" {
+			reminder.ListID = nil
+		} else {
+			if _, err := findReminderList(*req.ListID, reminder.UserID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "List not found"})
+				return
+			}
+			reminder.ListID = req.ListID
+		}
+	}
+	if req.TeamID != nil {
+		if *req.TeamID != "" && !memberOfTeam(reminder.UserID, *req.TeamID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Team not found"})
+			return
+		}
+		reminder.TeamID = *req.TeamID
+	}
 	if req.Status != "" {
-		reminder.Status = req.Status
+		// Users may only make some moves themselves; requests without a user come from the scheduler
+		if err := applyStatusChange(&reminder, req.Status, req.Error, req.DateTime != "", userID != ""); err != nil {
+			if _, ok := err.(transitionError); ok {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if reminder.Status == StatusSent && userID == "" {
+			reminder.DeliveredBy = req.DeliveredBy
+		}
+		if reminder.Status == StatusFailed && userID == "" {
+			addPublishedChannels(&reminder, req.PublishedChannels)
+		}
 	}
+	syncLocalTime(&reminder)
 
-	if err := db.Save(&reminder).Error; err != nil {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Only lands if nobody else wrote the reminder since it was read
+		if err := updateVersioned(tx, &reminder); err != nil {
+			return err
+		}
+		// A provided tag list replaces the reminder's tags
+		if req.Tags != nil {
+			tags, err := findOrCreateTags(tx, reminder.UserID, req.Tags)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&reminder).Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+			reminder.Tags = tags
+		}
+		return recordHistory(tx, auditFrom(c), "update", &before, &reminder)
+	})
+	if err == errVersionConflict {
+		respondConflict(c)
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reminder"})
 		return
 	}
 
+	db.Preload("Tags").First(&reminder, "id = ?", reminder.ID)
+
+	c.Header("ETag", reminderETag(&reminder))
 	c.JSON(http.StatusOK, reminder)
 }
 
@@ -294,14 +697,33 @@ func deleteReminder(c *gin.Context) {
 		userID = c.Query("user_id")
 	}
 
-	result := db.Where("id = ? AND user_id = ?", id, userID).Delete(&Reminder{})
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reminder"})
+	// Only the owner can delete; collaborators can remove themselves from the share instead
+	reminder, err := findReminder(db, id, userID, permissionOwner)
+	if err != nil {
+		respondReminderError(c, err)
+		return
+	}
+
+	if !checkIfMatch(c, &reminder) {
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("version = ?", reminder.Version).Delete(&reminder)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return errVersionConflict
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		return recordHistory(tx, auditFrom(c), "delete", &reminder, nil)
+	})
+	if err == errVersionConflict {
+		respondConflict(c)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reminder"})
 		return
 	}
 
@@ -313,12 +735,17 @@ func getPendingReminders(c *gin.Context) {
 	now := time.Now()
 	var reminders []Reminder
 
-	// Get reminders that are due now and still pending (not processing, sent, or failed)
-	if err := db.Where("status = ? AND date_time <= ?", "pending", now).Find(&reminders).Error; err != nil {
+	// Get reminders that are due now and still waiting: pending, or snoozed until now
+	if err := db.Where("status IN ? AND date_time <= ?", []string{StatusPending, StatusSnoozed}, now).Find(&reminders).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending reminders"})
 		return
 	}
 
+	// Collaborators who asked to be notified get each reminder too
+	if err := notifiedCollaborators(reminders); err != nil {
+		log.Printf("Error fetching collaborators for pending reminders: %v", err)
+	}
+
 	c.JSON(http.StatusOK, reminders)
 }
 
@@ -326,23 +753,43 @@ func resetStuckProcessingReminders(c *gin.Context) {
 	// Reset reminders that have been in "processing" status for more than 5 minutes
 	fiveMinutesAgo := time.Now().Add(-5 * time.Minute)
 
-	result := db.Model(&Reminder{}).
-		Where("status = ? AND updated_at < ?", "processing", fiveMinutesAgo).
-		Update("status", "pending")
+	// Reset one at a time so each gets its own history entry
+	var count int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var stuck []Reminder
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ? AND updated_at < ?", "processing", fiveMinutesAgo).
+			Find(&stuck).Error
+		if err != nil {
+			return err
+		}
+		for _, reminder := range stuck {
+			before := reminder
+			setStatus(&reminder, StatusPending, "")
+			if err := updateVersioned(tx, &reminder, "status"); err != nil {
+				return err
+			}
+			if err := recordHistory(tx, auditFrom(c), "status", &before, &reminder); err != nil {
+				return err
+			}
+		}
+		count = int64(len(stuck))
+		return nil
+	})
 
-	if result.Error != nil {
-		log.Printf("Error resetting stuck processing reminders: %v", result.Error)
+	if err != nil {
+		log.Printf("Error resetting stuck processing reminders: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset stuck reminders"})
 		return
 	}
 
-	if result.RowsAffected > 0 {
-		log.Printf("Reset %d stuck processing reminders", result.RowsAffected)
+	if count > 0 {
+		log.Printf("Reset %d stuck processing reminders", count)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Stuck reminders reset successfully",
-		"count":   result.RowsAffected,
+		"count":   count,
 	})
 }
 func updateReminderStatusOnly(c *gin.Context) {
@@ -350,7 +797,9 @@ func updateReminderStatusOnly(c *gin.Context) {
 	id := c.Param("id")
 
 	var req struct {
-		Status string `json:"status" binding:"required,oneof=pending processing sent failed"`
+		Status         string `json:"status" binding:"required,oneof=pending processing sent failed cancelled snoozed acknowledged expired"`
+		Error          string `json:"error"`           // delivery error, recorded with status failed
+		ExpectedStatus string `json:"expected_status"` // only change the status if it's still this
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -358,14 +807,40 @@ func updateReminderStatusOnly(c *gin.Context) {
 		return
 	}
 
-	result := db.Model(&Reminder{}).Where("id = ?", id).Update("status", req.Status)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reminder status"})
+	var reminder Reminder
+	if err := db.Where("id = ?", id).First(&reminder).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminder"})
+		return
+	}
+
+	if req.ExpectedStatus != "" && reminder.Status != req.ExpectedStatus {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Reminder is %s, not %s", reminder.Status, req.ExpectedStatus)})
+		return
+	}
+
+	before := reminder
+	if err := transitionStatus(&reminder, req.Status, req.Error, false); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		return
 	}
+	advanceRecurrence(&reminder)
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, &reminder, "status", "date_time", "attempts", "last_error", "failed_at", "published_channels"); err != nil {
+			return err
+		}
+		return recordHistory(tx, auditFrom(c), "status", &before, &reminder)
+	})
+	if err == errVersionConflict {
+		respondConflict(c)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reminder status"})
 		return
 	}
 
@@ -0,0 +1,544 @@
+// naturaltime.go - Natural-language due times ("tomorrow 9am", "every weekday at 8:30")
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParsedTime is what a phrase means: the (first) due time, and a recurrence rule if it repeats
+type ParsedTime struct {
+	DateTime   time.Time
+	Recurrence string
+}
+
+// Default times of day for phrases that name a date but no time
+const tonightHour = 20
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var rruleDays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+var monthNames = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+// Named times of day
+var namedTimes = map[string]int{
+	"noon": 12, "midday": 12, "midnight": 0,
+	"morning": 9, "afternoon": 15, "evening": 18, "night": tonightHour,
+}
+
+var durationUnits = map[string]time.Duration{
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+}
+
+// Calendar units, counted in days so "in a week" keeps the time of day across a DST change
+var dayUnits = map[string]int{
+	"d": 1, "day": 1, "days": 1,
+	"w": 7, "week": 7, "weeks": 7,
+}
+
+// Words that carry no meaning of their own ("on friday", "at the weekend")
+var fillerWords = map[string]bool{"at": true, "on": true, "the": true, "of": true, "and": true}
+
+var (
+	clockPattern   = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+	isoDatePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	dayPattern     = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th)?$`)
+	compactPattern = regexp.MustCompile(`^(\d+)([a-z]+)$`)
+)
+
+// parseNaturalTime reads a phrase relative to now, in now's location. Supported forms:
+//
+//	in 2 hours, in 30 minutes, in 1h30m, in a week
+//	today 5pm, tonight, tomorrow 9am, friday 17:00, next friday at 5pm
+//	march 14, 14 march 2026 at noon, 2026-03-14 9:30am
+//	9am (the next 9am), at noon
+//	every day at 8, every weekday at 8:30, every monday and thursday, every other week, every 3 months
+func parseNaturalTime(text string, now time.Time) (ParsedTime, error) {
+	tokens := tokenizeNaturalTime(text)
+	if len(tokens) == 0 {
+		return ParsedTime{}, fmt.Errorf("Nothing to parse")
+	}
+
+	switch tokens[0] {
+	case "in":
+		due, err := parseRelative(tokens[1:], now)
+		return ParsedTime{DateTime: due}, err
+	case "every":
+		return parseRecurring(tokens[1:], now)
+	}
+
+	due, err := parseAbsolute(tokens, now)
+	if err != nil {
+		return ParsedTime{}, err
+	}
+	if !due.After(now) {
+		return ParsedTime{}, fmt.Errorf("%q is in the past", text)
+	}
+	return ParsedTime{DateTime: due}, nil
+}
+
+func tokenizeNaturalTime(text string) []string {
+	text = strings.ToLower(text)
+	text = strings.NewReplacer(",", " ", "a.m.", "am", "p.m.", "pm").Replace(text)
+
+	var tokens []string
+	for _, token := range strings.Fields(text) {
+		// "9 am" reads as "9am"
+		if (token == "am" || token == "pm") && len(tokens) > 0 {
+			tokens[len(tokens)-1] += token
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// parseRelative reads "2 hours", "a week", "1 hour and 30 minutes", "1h30m" or "half an hour"
+func parseRelative(tokens []string, now time.Time) (time.Time, error) {
+	var total time.Duration
+	days, months := 0, 0
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token == "and" {
+			continue
+		}
+		if token == "half" && i+2 < len(tokens) && (tokens[i+1] == "an" || tokens[i+1] == "a") && tokens[i+2] == "hour" {
+			total += 30 * time.Minute
+			i += 2
+			continue
+		}
+
+		// Compact forms: 30m, 2h, 1h30m
+		if d, err := time.ParseDuration(token); err == nil && d > 0 {
+			total += d
+			continue
+		}
+		if m := compactPattern.FindStringSubmatch(token); m != nil {
+			if unit, ok := dayUnits[m[2]]; ok {
+				n, _ := strconv.Atoi(m[1])
+				days += n * unit
+				continue
+			}
+		}
+
+		n := 0
+		switch token {
+		case "a", "an", "one":
+			n = 1
+		default:
+			var err error
+			if n, err = strconv.Atoi(token); err != nil || n <= 0 {
+				return time.Time{}, fmt.Errorf("Expected a number after \"in\", got %q", token)
+			}
+		}
+		if i+1 >= len(tokens) {
+			return time.Time{}, fmt.Errorf("Missing unit after %q", token)
+		}
+		i++
+		unit := tokens[i]
+		if unit == "month" || unit == "months" {
+			months += n
+			continue
+		}
+		if d, ok := dayUnits[unit]; ok {
+			days += n * d
+			continue
+		}
+		d, ok := durationUnits[unit]
+		if !ok {
+			return time.Time{}, fmt.Errorf("Unknown unit %q", unit)
+		}
+		total += time.Duration(n) * d
+	}
+
+	if total == 0 && days == 0 && months == 0 {
+		return time.Time{}, fmt.Errorf("Missing duration after \"in\"")
+	}
+	return now.AddDate(0, months, days).Add(total), nil
+}
+
+// dateSpec is the date part of a phrase, resolved once the time of day is known
+type dateSpec struct {
+	date        time.Time     // a fixed calendar date, or
+	weekday     *time.Weekday // the next such weekday
+	skipToday   bool          // "next friday" said on a friday means a week today
+	defaultHour int
+}
+
+// parseAbsolute reads a date and/or a time of day, in either order
+func parseAbsolute(tokens []string, now time.Time) (time.Time, error) {
+	var date *dateSpec
+	hour, minute := -1, 0
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if fillerWords[token] || token == "this" {
+			continue
+		}
+
+		if spec, used, err := parseDate(tokens[i:], now); err != nil {
+			return time.Time{}, err
+		} else if used > 0 {
+			if date != nil {
+				return time.Time{}, fmt.Errorf("More than one date given")
+			}
+			date = spec
+			i += used - 1
+			continue
+		}
+
+		if h, m, ok := parseClock(token, i > 0 && tokens[i-1] == "at"); ok {
+			if hour >= 0 {
+				return time.Time{}, fmt.Errorf("More than one time given")
+			}
+			hour, minute = h, m
+			continue
+		}
+
+		return time.Time{}, fmt.Errorf("Don't understand %q", token)
+	}
+
+	if date == nil && hour < 0 {
+		return time.Time{}, fmt.Errorf("No date or time found")
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if date == nil {
+		// A bare time means the next time it comes round
+		due := atTime(today, hour, minute)
+		if !due.After(now) {
+			due = atTime(today.AddDate(0, 0, 1), hour, minute)
+		}
+		return due, nil
+	}
+
+	if hour < 0 {
+		hour = date.defaultHour
+	}
+	if date.weekday == nil {
+		return atTime(date.date, hour, minute), nil
+	}
+
+	days := (int(*date.weekday) - int(today.Weekday()) + 7) % 7
+	due := atTime(today.AddDate(0, 0, days), hour, minute)
+	if (days == 0 && date.skipToday) || !due.After(now) {
+		due = atTime(today.AddDate(0, 0, days+7), hour, minute)
+	}
+	return due, nil
+}
+
+// parseDate reads a date at the start of tokens, returning how many tokens it used (0 if none)
+func parseDate(tokens []string, now time.Time) (*dateSpec, int, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	spec := &dateSpec{defaultHour: allDayReminderHour}
+
+	switch token := tokens[0]; {
+	case token == "today":
+		spec.date = today
+		return spec, 1, nil
+	case token == "tonight":
+		spec.date, spec.defaultHour = today, tonightHour
+		return spec, 1, nil
+	case token == "tomorrow":
+		spec.date = today.AddDate(0, 0, 1)
+		return spec, 1, nil
+	case token == "day" && len(tokens) >= 3 && tokens[1] == "after" && tokens[2] == "tomorrow":
+		spec.date = today.AddDate(0, 0, 2)
+		return spec, 3, nil
+
+	case token == "next" && len(tokens) >= 2:
+		if weekday, ok := weekdayNames[tokens[1]]; ok {
+			spec.weekday, spec.skipToday = &weekday, true
+			return spec, 2, nil
+		}
+		if tokens[1] == "week" {
+			spec.date = today.AddDate(0, 0, 7)
+			return spec, 2, nil
+		}
+		return nil, 0, fmt.Errorf("Don't understand \"next %s\"", tokens[1])
+
+	case isoDatePattern.MatchString(token):
+		date, err := time.ParseInLocation("2006-01-02", token, now.Location())
+		if err != nil {
+			return nil, 0, fmt.Errorf("Invalid date %q", token)
+		}
+		spec.date = date
+		return spec, 1, nil
+	}
+
+	if weekday, ok := weekdayNames[tokens[0]]; ok {
+		spec.weekday = &weekday
+		return spec, 1, nil
+	}
+
+	// "march 14", "march 14th 2026", "14 march", "14th of march 2026"
+	var month time.Month
+	day, used := 0, 0
+	if m, ok := monthNames[tokens[0]]; ok && len(tokens) >= 2 {
+		if d := dayPattern.FindStringSubmatch(tokens[1]); d != nil {
+			month, used = m, 2
+			day, _ = strconv.Atoi(d[1])
+		}
+	} else if d := dayPattern.FindStringSubmatch(tokens[0]); d != nil {
+		next := 1
+		if next < len(tokens) && tokens[next] == "of" {
+			next++
+		}
+		if next < len(tokens) {
+			if m, ok := monthNames[tokens[next]]; ok {
+				month, used = m, next+1
+				day, _ = strconv.Atoi(d[1])
+			}
+		}
+	}
+	if used == 0 {
+		return nil, 0, nil
+	}
+
+	year, explicitYear := today.Year(), false
+	if used < len(tokens) && len(tokens[used]) == 4 {
+		if y, err := strconv.Atoi(tokens[used]); err == nil {
+			year, explicitYear = y, true
+			used++
+		}
+	}
+
+	date := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	if date.Month() != month {
+		return nil, 0, fmt.Errorf("%s has no day %d", month, day)
+	}
+	// A date without a year that has already gone this year means next year's
+	if !explicitYear && date.Before(today) {
+		date = date.AddDate(1, 0, 0)
+	}
+	spec.date = date
+	return spec, used, nil
+}
+
+// parseClock reads "9am", "9:30pm", "17:00", "noon" and friends. A lone number is only
+// taken as an hour (24-hour clock) straight after "at".
+func parseClock(token string, afterAt bool) (int, int, bool) {
+	if hour, ok := namedTimes[token]; ok {
+		return hour, 0, true
+	}
+
+	m := clockPattern.FindStringSubmatch(token)
+	if m == nil || (m[2] == "" && m[3] == "" && !afterAt) {
+		return 0, 0, false
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	if minute > 59 {
+		return 0, 0, false
+	}
+
+	switch m[3] {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		hour %= 12
+		if m[3] == "pm" {
+			hour += 12
+		}
+	default:
+		if hour > 23 {
+			return 0, 0, false
+		}
+	}
+	return hour, minute, true
+}
+
+// parseRecurring reads what follows "every": a period ("day", "other week", "3 months"),
+// or days ("weekday", "weekend", "monday and thursday"), then an optional time
+func parseRecurring(tokens []string, now time.Time) (ParsedTime, error) {
+	interval := 1
+	freq := ""
+	var days []time.Weekday
+	hour, minute := allDayReminderHour, 0
+	timeGiven := false
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if fillerWords[token] {
+			continue
+		}
+
+		if n, err := strconv.Atoi(token); err == nil && n > 0 && freq == "" && len(days) == 0 {
+			interval = n
+			continue
+		}
+		if token == "other" && freq == "" {
+			interval = 2
+			continue
+		}
+
+		switch strings.TrimSuffix(token, "s") {
+		case "day":
+			freq = "DAILY"
+			continue
+		case "week":
+			freq = "WEEKLY"
+			continue
+		case "month":
+			freq = "MONTHLY"
+			continue
+		case "year":
+			freq = "YEARLY"
+			continue
+		case "weekday":
+			days = append(days, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday)
+			continue
+		case "weekend":
+			days = append(days, time.Saturday, time.Sunday)
+			continue
+		}
+		if weekday, ok := weekdayNames[strings.TrimSuffix(token, "s")]; ok {
+			days = append(days, weekday)
+			continue
+		}
+
+		if h, m, ok := parseClock(token, i > 0 && tokens[i-1] == "at"); ok {
+			if timeGiven {
+				return ParsedTime{}, fmt.Errorf("More than one time given")
+			}
+			hour, minute, timeGiven = h, m, true
+			continue
+		}
+
+		return ParsedTime{}, fmt.Errorf("Don't understand %q", token)
+	}
+
+	if freq == "" && len(days) == 0 {
+		return ParsedTime{}, fmt.Errorf("Say how often, e.g. \"every day\" or \"every monday\"")
+	}
+	if len(days) > 0 {
+		if freq != "" && freq != "WEEKLY" {
+			return ParsedTime{}, fmt.Errorf("Days of the week only go with weekly repeats")
+		}
+		freq = "WEEKLY"
+	}
+
+	rule := "FREQ=" + freq
+	if interval > 1 {
+		rule += fmt.Sprintf(";INTERVAL=%d", interval)
+	}
+
+	// The first occurrence is the next matching day at the time; with no days, the next time it comes round
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var first time.Time
+	if len(days) == 0 {
+		first = atTime(today, hour, minute)
+		if !first.After(now) {
+			first = atTime(today.AddDate(0, 0, 1), hour, minute)
+		}
+	} else {
+		var byDay []string
+		seen := make(map[time.Weekday]bool)
+		for _, day := range days {
+			if !seen[day] {
+				seen[day] = true
+				byDay = append(byDay, rruleDays[day])
+			}
+		}
+		rule += ";BYDAY=" + strings.Join(byDay, ",")
+
+		for offset := 0; offset <= 7; offset++ {
+			candidate := atTime(today.AddDate(0, 0, offset), hour, minute)
+			if seen[candidate.Weekday()] && candidate.After(now) {
+				first = candidate
+				break
+			}
+		}
+	}
+
+	return ParsedTime{DateTime: first, Recurrence: rule}, nil
+}
+
+func atTime(day time.Time, hour, minute int) time.Time {
+	// inZone settles times that DST skips or repeats
+	return inZone(time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, time.UTC), day.Location())
+}
+
+// ParseRequest DTO
+type ParseRequest struct {
+	Text     string `json:"text" binding:"required"`
+	Timezone string `json:"timezone"` // defaults to the user's preference
+}
+
+// parseWhen previews how a phrase would be read, before a reminder is saved with it
+func parseWhen(c *gin.Context) {
+	var req ParseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, err := resolveTimezone(req.Timezone, getUserID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	parsed, err := parseNaturalTime(req.Text, time.Now().In(loc))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"text":     req.Text,
+		"timezone": loc.String(),
+		"datetime": parsed.DateTime.Format(time.RFC3339),
+	}
+	if parsed.Recurrence != "" {
+		// The first few occurrences, so the user can check the rule means what they meant
+		occurrences := []string{parsed.DateTime.Format(time.RFC3339)}
+		series := Reminder{Recurrence: parsed.Recurrence, DateTime: parsed.DateTime}
+		next := parsed.DateTime
+		for len(occurrences) < 5 {
+			var ok bool
+			if next, ok = nextOccurrence(series, next); !ok {
+				break
+			}
+			occurrences = append(occurrences, next.In(loc).Format(time.RFC3339))
+		}
+		response["recurrence"] = parsed.Recurrence
+		response["occurrences"] = occurrences
+	}
+
+	c.JSON(http.StatusOK, response)
+}
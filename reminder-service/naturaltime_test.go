@@ -0,0 +1,221 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tuesday 10 March 2026, 14:00 UTC
+var naturalNow = time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC)
+
+func TestParseNaturalTime(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		// Relative
+		{"in 2 hours", "2026-03-10T16:00:00Z"},
+		{"in 30 minutes", "2026-03-10T14:30:00Z"},
+		{"in 1h30m", "2026-03-10T15:30:00Z"},
+		{"in 1 hour and 30 minutes", "2026-03-10T15:30:00Z"},
+		{"in half an hour", "2026-03-10T14:30:00Z"},
+		{"in a week", "2026-03-17T14:00:00Z"},
+		{"in 2 days", "2026-03-12T14:00:00Z"},
+		{"in 3d", "2026-03-13T14:00:00Z"},
+		{"in 1 month", "2026-04-10T14:00:00Z"},
+		{"In 2 Hours", "2026-03-10T16:00:00Z"},
+
+		// Named days
+		{"today 5pm", "2026-03-10T17:00:00Z"},
+		{"tonight", "2026-03-10T20:00:00Z"},
+		{"tomorrow", "2026-03-11T09:00:00Z"},
+		{"tomorrow 9am", "2026-03-11T09:00:00Z"},
+		{"tomorrow at 9:30 p.m.", "2026-03-11T21:30:00Z"},
+		{"9 am tomorrow", "2026-03-11T09:00:00Z"},
+		{"day after tomorrow", "2026-03-12T09:00:00Z"},
+		{"next week", "2026-03-17T09:00:00Z"},
+
+		// Weekdays, rolling over to next week once today's time has gone
+		{"friday 17:00", "2026-03-13T17:00:00Z"},
+		{"on friday", "2026-03-13T09:00:00Z"},
+		{"next friday at 5pm", "2026-03-13T17:00:00Z"},
+		{"tuesday 3pm", "2026-03-10T15:00:00Z"},
+		{"tuesday 9am", "2026-03-17T09:00:00Z"},
+		{"next tuesday 3pm", "2026-03-17T15:00:00Z"},
+		{"tue at noon", "2026-03-17T12:00:00Z"},
+
+		// Bare times: the next time they come round
+		{"3pm", "2026-03-10T15:00:00Z"},
+		{"9am", "2026-03-11T09:00:00Z"},
+		{"at 17", "2026-03-10T17:00:00Z"},
+		{"at noon", "2026-03-11T12:00:00Z"},
+		{"midnight", "2026-03-11T00:00:00Z"},
+		{"this evening", "2026-03-10T18:00:00Z"},
+
+		// Calendar dates
+		{"march 14", "2026-03-14T09:00:00Z"},
+		{"march 14th 2026", "2026-03-14T09:00:00Z"},
+		{"14th of march 2027 at noon", "2027-03-14T12:00:00Z"},
+		{"march 1", "2027-03-01T09:00:00Z"},
+		{"2026-03-14 9:30am", "2026-03-14T09:30:00Z"},
+		{"dec 25, 8am", "2026-12-25T08:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := parseNaturalTime(tt.text, naturalNow)
+			if err != nil {
+				t.Fatalf("parseNaturalTime(%q): %v", tt.text, err)
+			}
+			if got.Recurrence != "" {
+				t.Errorf("recurrence = %q, want none", got.Recurrence)
+			}
+			if s := got.DateTime.Format(time.RFC3339); s != tt.want {
+				t.Errorf("parseNaturalTime(%q) = %s, want %s", tt.text, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNaturalTimeRecurring(t *testing.T) {
+	tests := []struct {
+		text      string
+		wantFirst string
+		wantRule  string
+	}{
+		{"every day at 8", "2026-03-11T08:00:00Z", "FREQ=DAILY"},
+		{"every day at 6pm", "2026-03-10T18:00:00Z", "FREQ=DAILY"},
+		{"every weekday at 8:30", "2026-03-11T08:30:00Z", "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		{"every monday and thursday", "2026-03-12T09:00:00Z", "FREQ=WEEKLY;BYDAY=MO,TH"},
+		{"every weekend at 10am", "2026-03-14T10:00:00Z", "FREQ=WEEKLY;BYDAY=SA,SU"},
+		{"every tuesday at 3pm", "2026-03-10T15:00:00Z", "FREQ=WEEKLY;BYDAY=TU"},
+		{"every tuesdays at 9am", "2026-03-17T09:00:00Z", "FREQ=WEEKLY;BYDAY=TU"},
+		{"every other week", "2026-03-11T09:00:00Z", "FREQ=WEEKLY;INTERVAL=2"},
+		{"every 3 months", "2026-03-11T09:00:00Z", "FREQ=MONTHLY;INTERVAL=3"},
+		{"every year", "2026-03-11T09:00:00Z", "FREQ=YEARLY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := parseNaturalTime(tt.text, naturalNow)
+			if err != nil {
+				t.Fatalf("parseNaturalTime(%q): %v", tt.text, err)
+			}
+			if s := got.DateTime.Format(time.RFC3339); s != tt.wantFirst {
+				t.Errorf("first occurrence = %s, want %s", s, tt.wantFirst)
+			}
+			if got.Recurrence != tt.wantRule {
+				t.Errorf("recurrence = %q, want %q", got.Recurrence, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestParseNaturalTimeErrors(t *testing.T) {
+	tests := []struct {
+		text    string
+		wantErr string
+	}{
+		{"", "Nothing to parse"},
+		{"   ", "Nothing to parse"},
+		{"in", "Missing duration"},
+		{"in 5", "Missing unit"},
+		{"in 2 fortnights", "Unknown unit"},
+		{"in soon", "Expected a number"},
+		{"yesterday", "Don't understand"},
+		{"today 9am", "in the past"},
+		{"tomorrow 9am 5pm", "More than one time"},
+		{"friday march 14", "More than one date"},
+		{"february 30", "has no day 30"},
+		{"next blursday", "Don't understand \"next blursday\""},
+		{"13pm", "Don't understand"},
+		{"9:75", "Don't understand"},
+		// A lone number could be a day, an hour or a count; it's only an hour after "at"
+		{"17", "Don't understand"},
+		{"tomorrow 9", "Don't understand"},
+		{"every", "Say how often"},
+		{"every 2", "Say how often"},
+		{"every monday 9am 5pm", "More than one time"},
+		{"every month on monday", "Days of the week only go with weekly repeats"},
+		{"every fortnight", "Don't understand"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := parseNaturalTime(tt.text, naturalNow)
+			if err == nil {
+				t.Fatalf("parseNaturalTime(%q) = %s, want an error", tt.text, got.DateTime.Format(time.RFC3339))
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseNaturalTime(%q) error = %q, want one containing %q", tt.text, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseNaturalTimeInZone(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	tokyo := mustLoadLocation(t, "Asia/Tokyo")
+
+	// Saturday 7 March 2026, noon in New York, the day before clocks spring forward
+	beforeDST := time.Date(2026, 3, 7, 12, 0, 0, 0, newYork)
+	// 20:00 UTC on the 10th is already 05:00 on the 11th in Tokyo
+	tokyoMorning := time.Date(2026, 3, 10, 20, 0, 0, 0, time.UTC).In(tokyo)
+
+	tests := []struct {
+		name string
+		text string
+		now  time.Time
+		want string
+	}{
+		{"times are read in now's zone", "tomorrow 9am", beforeDST, "2026-03-08T09:00:00-04:00"},
+		{"calendar days keep the time of day across DST", "in a day", beforeDST, "2026-03-08T12:00:00-04:00"},
+		{"hours are elapsed time", "in 24 hours", beforeDST, "2026-03-08T13:00:00-04:00"},
+		{"a time DST skips moves forward", "tomorrow 2:30am", beforeDST, "2026-03-08T03:30:00-04:00"},
+		{"recurrence starts in the zone", "every day at 8", beforeDST, "2026-03-08T08:00:00-04:00"},
+		{"tomorrow is the zone's tomorrow", "tomorrow 9am", tokyoMorning, "2026-03-12T09:00:00+09:00"},
+		{"today is the zone's today", "today 11:30pm", tokyoMorning, "2026-03-11T23:30:00+09:00"},
+		{"weekdays are the zone's weekdays", "wednesday 8am", tokyoMorning, "2026-03-11T08:00:00+09:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNaturalTime(tt.text, tt.now)
+			if err != nil {
+				t.Fatalf("parseNaturalTime(%q): %v", tt.text, err)
+			}
+			if s := got.DateTime.Format(time.RFC3339); s != tt.want {
+				t.Errorf("parseNaturalTime(%q) = %s, want %s", tt.text, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		token        string
+		afterAt      bool
+		hour, minute int
+		ok           bool
+	}{
+		{"9am", false, 9, 0, true},
+		{"12am", false, 0, 0, true},
+		{"12pm", false, 12, 0, true},
+		{"9:30pm", false, 21, 30, true},
+		{"17:00", false, 17, 0, true},
+		{"noon", false, 12, 0, true},
+		{"17", true, 17, 0, true},
+		{"17", false, 0, 0, false},
+		{"0am", false, 0, 0, false},
+		{"24:00", false, 0, 0, false},
+		{"9:60", false, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		hour, minute, ok := parseClock(tt.token, tt.afterAt)
+		if ok != tt.ok || (ok && (hour != tt.hour || minute != tt.minute)) {
+			t.Errorf("parseClock(%q, %v) = %d:%02d %v, want %d:%02d %v", tt.token, tt.afterAt, hour, minute, ok, tt.hour, tt.minute, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// patch.go - PATCH /api/reminders/:id with JSON Merge Patch (RFC 7396)
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Fields a patch may not touch, they're managed by the service
+var readOnlyFields = map[string]bool{
+	"id": true, "user_id": true, "recurrence_start": true, "local_time": true, "external_uid": true, "attempts": true,
+	"last_error": true, "failed_at": true, "requeued_at": true, "delivered_by": true, "deleted_at": true, "version": true, "created_at": true, "updated_at": true,
+}
+
+// patchReminder applies a merge patch: present fields are set, null clears a field (or resets it
+// to its default), absent fields are left alone. The patched reminder is validated as a whole.
+func patchReminder(c *gin.Context) {
+	if mediaType, _, err := mime.ParseMediaType(c.ContentType()); err != nil ||
+		(mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Use Content-Type application/merge-patch+json"})
+		return
+	}
+
+	var patch map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Patch must be a JSON object"})
+		return
+	}
+
+	userID := getUserID(c)
+	var reminder Reminder
+	var err error
+	if userID != "" {
+		reminder, err = findReminder(db.Preload("Tags"), c.Param("id"), userID, permissionEdit)
+	} else {
+		err = db.Preload("Tags").Where("id = ?", c.Param("id")).First(&reminder).Error
+	}
+	if err != nil {
+		respondReminderError(c, err)
+		return
+	}
+
+	if !checkIfMatch(c, &reminder) {
+		return
+	}
+
+	before := reminder
+	tagNames, err := applyMergePatch(&reminder, patch, userID != "")
+	if err == nil {
+		err = validateReminder(&reminder)
+	}
+	if err != nil {
+		if _, ok := err.(transitionError); ok {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, &reminder); err != nil {
+			return err
+		}
+		if tagNames != nil {
+			tags, err := findOrCreateTags(tx, reminder.UserID, *tagNames)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&reminder).Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+			reminder.Tags = tags
+		}
+		return recordHistory(tx, auditFrom(c), "update", &before, &reminder)
+	})
+	if err == errVersionConflict {
+		respondConflict(c)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reminder"})
+		return
+	}
+
+	db.Preload("Tags").First(&reminder, "id = ?", reminder.ID)
+
+	c.Header("ETag", reminderETag(&reminder))
+	c.JSON(http.StatusOK, reminder)
+}
+
+// applyMergePatch sets the patched fields on the reminder. Tags are returned rather than applied
+// (nil when the patch leaves them alone) since they live in their own table.
+func applyMergePatch(reminder *Reminder, patch map[string]json.RawMessage, byUser bool) (*[]string, error) {
+	var tagNames *[]string
+	var status string
+	datetimeChanged := false
+
+	// The timezone goes first, since a local datetime in the same patch is read in it
+	if raw, ok := patch["timezone"]; ok {
+		if err := patchString(raw, &reminder.Timezone, true); err != nil {
+			return nil, fmt.Errorf("invalid timezone: %v", err)
+		}
+		if err := validateTimezone(reminder.Timezone); err != nil {
+			return nil, err
+		}
+	}
+
+	for field, raw := range patch {
+		if readOnlyFields[field] {
+			return nil, fmt.Errorf("%s cannot be changed", field)
+		}
+		isNull := string(raw) == "null"
+
+		var err error
+		switch field {
+		case "title":
+			err = patchString(raw, &reminder.Title, false)
+		case "description":
+			err = patchString(raw, &reminder.Description, true)
+		case "notification_type":
+			err = patchString(raw, &reminder.NotificationType, false)
+		case "email":
+			err = patchString(raw, &reminder.Email, true)
+		case "phone":
+			err = patchString(raw, &reminder.Phone, true)
+		case "priority":
+			reminder.Priority = "normal"
+			if !isNull {
+				err = json.Unmarshal(raw, &reminder.Priority)
+			}
+		case "catch_up":
+			err = patchString(raw, &reminder.CatchUp, true)
+		case "grace_minutes":
+			reminder.GraceMinutes = 0
+			if !isNull {
+				err = json.Unmarshal(raw, &reminder.GraceMinutes)
+			}
+		case "contact_ids":
+			reminder.ContactIDs = nil
+			if !isNull {
+				err = json.Unmarshal(raw, &reminder.ContactIDs)
+			}
+		case "group_ids":
+			reminder.GroupIDs = nil
+			if !isNull {
+				err = json.Unmarshal(raw, &reminder.GroupIDs)
+			}
+		case "tags":
+			names := []string{}
+			if !isNull {
+				err = json.Unmarshal(raw, &names)
+			}
+			tagNames = &names
+		case "list_id":
+			reminder.ListID = nil
+			if !isNull {
+				var listID string
+				if err = json.Unmarshal(raw, &listID); err == nil && listID != "" {
+					if _, findErr := findReminderList(listID, reminder.UserID); findErr != nil {
+						return nil, fmt.Errorf("List not found")
+					}
+					reminder.ListID = &listID
+				}
+			}
+		case "team_id":
+			reminder.TeamID = ""
+			if !isNull {
+				var teamID string
+				if err = json.Unmarshal(raw, &teamID); err == nil && teamID != "" {
+					if !memberOfTeam(reminder.UserID, teamID) {
+						return nil, fmt.Errorf("Team not found")
+					}
+					reminder.TeamID = teamID
+				}
+			}
+		case "recurrence":
+			var rule string
+			if err = patchString(raw, &rule, true); err == nil {
+				reminder.Recurrence, err = normalizeRecurrence(rule)
+			}
+		case "datetime":
+			var value string
+			if err = patchString(raw, &value, false); err == nil {
+				var loc *time.Location
+				if reminder.Timezone != "" {
+					loc = reminderLocation(*reminder)
+				}
+				reminder.DateTime, err = parseReminderTime(value, loc)
+				if err != nil {
+					return nil, err
+				}
+				datetimeChanged = true
+			}
+		case "status":
+			err = patchString(raw, &status, false)
+		case "timezone":
+			// Applied above
+		default:
+			return nil, fmt.Errorf("unknown field %s", field)
+		}
+		if err == errNotRemovable {
+			return nil, fmt.Errorf("%s cannot be removed", field)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", field, err)
+		}
+	}
+
+	// Keep the series anchored to the reminder's time, as a PUT does; a snooze only moves this occurrence
+	if _, ok := patch["recurrence"]; ok || (datetimeChanged && status != StatusSnoozed) {
+		if reminder.Recurrence == "" {
+			reminder.RecurrenceStart = nil
+		} else {
+			start := reminder.DateTime
+			reminder.RecurrenceStart = &start
+		}
+	}
+
+	if status != "" {
+		if err := applyStatusChange(reminder, status, "", datetimeChanged, byUser); err != nil {
+			return nil, err
+		}
+	}
+
+	syncLocalTime(reminder)
+
+	return tagNames, nil
+}
+
+var errNotRemovable = fmt.Errorf("field cannot be removed")
+
+// patchString sets a string field; null clears it where that's allowed
+func patchString(raw json.RawMessage, field *string, nullable bool) error {
+	if string(raw) == "null" {
+		if !nullable {
+			return errNotRemovable
+		}
+		*field = ""
+		return nil
+	}
+	return json.Unmarshal(raw, field)
+}
+
+// validateReminder checks the rules a whole reminder must satisfy, whatever changed
+func validateReminder(reminder *Reminder) error {
+	if reminder.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if reminder.NotificationType != "email" && reminder.NotificationType != "sms" {
+		return fmt.Errorf("notification_type must be email or sms")
+	}
+	switch reminder.Priority {
+	case "low", "normal", "high", "critical":
+	default:
+		return fmt.Errorf("priority must be low, normal, high or critical")
+	}
+	switch reminder.CatchUp {
+	case "", "fire", "grace", "expire":
+	default:
+		return fmt.Errorf("catch_up must be fire, grace or expire")
+	}
+	if reminder.GraceMinutes < 0 {
+		return fmt.Errorf("grace_minutes cannot be negative")
+	}
+
+	hasContacts := len(reminder.ContactIDs) > 0 || len(reminder.GroupIDs) > 0
+	if reminder.NotificationType == "email" && reminder.Email == "" && !hasContacts {
+		return fmt.Errorf("Email is required for email notifications")
+	}
+	if reminder.NotificationType == "sms" && reminder.Phone == "" && !hasContacts {
+		return fmt.Errorf("Phone is required for SMS notifications")
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func patchFixture() Reminder {
+	return Reminder{
+		ID:               "r1",
+		UserID:           "u1",
+		Title:            "Dentist",
+		Description:      "Bring the insurance card",
+		DateTime:         time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC),
+		NotificationType: "sms",
+		Email:            "me@example.com",
+		Phone:            "+15550100",
+		Priority:         "high",
+		CatchUp:          "grace",
+		GraceMinutes:     30,
+		ContactIDs:       []string{"c1"},
+		Status:           StatusPending,
+	}
+}
+
+func parsePatch(t *testing.T, body string) map[string]json.RawMessage {
+	t.Helper()
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &patch); err != nil {
+		t.Fatalf("bad test patch %s: %v", body, err)
+	}
+	return patch
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		check func(t *testing.T, r Reminder)
+	}{
+		{
+			name:  "absent fields are left alone",
+			patch: `{"title": "Dentist (moved)"}`,
+			check: func(t *testing.T, r Reminder) {
+				if r.Title != "Dentist (moved)" || r.Description != "Bring the insurance card" || r.Phone != "+15550100" {
+					t.Errorf("got title %q, description %q, phone %q", r.Title, r.Description, r.Phone)
+				}
+			},
+		},
+		{
+			name:  "null clears a string",
+			patch: `{"description": null}`,
+			check: func(t *testing.T, r Reminder) {
+				if r.Description != "" {
+					t.Errorf("description = %q, want cleared", r.Description)
+				}
+			},
+		},
+		{
+			name:  "empty string is a value, not a clear of anything else",
+			patch: `{"description": ""}`,
+			check: func(t *testing.T, r Reminder) {
+				if r.Description != "" || r.Title != "Dentist" {
+					t.Errorf("description %q, title %q", r.Description, r.Title)
+				}
+			},
+		},
+		{
+			name:  "switch to email while blanking the phone",
+			patch: `{"notification_type": "email", "phone": null}`,
+			check: func(t *testing.T, r Reminder) {
+				if r.NotificationType != "email" || r.Phone != "" {
+					t.Errorf("notification_type %q, phone %q", r.NotificationType, r.Phone)
+				}
+			},
+		},
+		{
+			name:  "null resets to the default",
+			patch: `{"priority": null, "grace_minutes": null, "catch_up": null}`,
+			check: func(t *testing.T, r Reminder) {
+				if r.Priority != "normal" || r.GraceMinutes != 0 || r.CatchUp != "" {
+					t.Errorf("priority %q, grace_minutes %d, catch_up %q", r.Priority, r.GraceMinutes, r.CatchUp)
+				}
+			},
+		},
+		{
+			name:  "null clears a list",
+			patch: `{"contact_ids": null}`,
+			check: func(t *testing.T, r Reminder) {
+				if r.ContactIDs != nil {
+					t.Errorf("contact_ids = %v, want cleared", r.ContactIDs)
+				}
+			},
+		},
+		{
+			name:  "datetime is read in the reminder's zone",
+			patch: `{"timezone": "UTC", "datetime": "2026-03-11T08:30:00"}`,
+			check: func(t *testing.T, r Reminder) {
+				want := time.Date(2026, 3, 11, 8, 30, 0, 0, time.UTC)
+				if !r.DateTime.Equal(want) || r.LocalTime != "2026-03-11T08:30:00" {
+					t.Errorf("datetime %s, local_time %q", r.DateTime, r.LocalTime)
+				}
+			},
+		},
+		{
+			name:  "users may cancel",
+			patch: `{"status": "cancelled"}`,
+			check: func(t *testing.T, r Reminder) {
+				if r.Status != StatusCancelled {
+					t.Errorf("status = %s, want cancelled", r.Status)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reminder := patchFixture()
+			tags, err := applyMergePatch(&reminder, parsePatch(t, tt.patch), true)
+			if err != nil {
+				t.Fatalf("applyMergePatch: %v", err)
+			}
+			if tags != nil {
+				t.Errorf("tags = %v, want nil when the patch leaves them alone", *tags)
+			}
+			tt.check(t, reminder)
+		})
+	}
+}
+
+func TestApplyMergePatchTags(t *testing.T) {
+	reminder := patchFixture()
+	tags, err := applyMergePatch(&reminder, parsePatch(t, `{"tags": ["work", "health"]}`), true)
+	if err != nil || tags == nil || len(*tags) != 2 {
+		t.Fatalf("tags = %v, err %v; want [work health]", tags, err)
+	}
+
+	tags, err = applyMergePatch(&reminder, parsePatch(t, `{"tags": null}`), true)
+	if err != nil || tags == nil || len(*tags) != 0 {
+		t.Fatalf("null tags = %v, err %v; want an empty list", tags, err)
+	}
+}
+
+func TestApplyMergePatchErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		wantErr string
+	}{
+		{"read-only id", `{"id": "other"}`, "id cannot be changed"},
+		{"read-only user_id", `{"user_id": "someone-else"}`, "user_id cannot be changed"},
+		{"read-only version", `{"version": 7}`, "version cannot be changed"},
+		{"read-only attempts", `{"attempts": 0}`, "attempts cannot be changed"},
+		{"unknown field", `{"colour": "red"}`, "unknown field colour"},
+		{"title can't be removed", `{"title": null}`, "title cannot be removed"},
+		{"notification_type can't be removed", `{"notification_type": null}`, "notification_type cannot be removed"},
+		{"wrong type", `{"grace_minutes": "ten"}`, "invalid grace_minutes"},
+		{"bad timezone", `{"timezone": "Mars/Olympus"}`, "Invalid timezone"},
+		{"bad datetime", `{"datetime": "next tuesday"}`, "Invalid datetime"},
+		{"snooze needs a future datetime", `{"status": "snoozed"}`, "Snooze needs a datetime in the future"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reminder := patchFixture()
+			_, err := applyMergePatch(&reminder, parsePatch(t, tt.patch), true)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyMergePatchStatus(t *testing.T) {
+	// Users can't make the scheduler's moves
+	reminder := patchFixture()
+	_, err := applyMergePatch(&reminder, parsePatch(t, `{"status": "processing"}`), true)
+	if _, ok := err.(transitionError); !ok {
+		t.Errorf("user pending -> processing: err = %v, want a transitionError", err)
+	}
+
+	// Services can
+	reminder = patchFixture()
+	if _, err := applyMergePatch(&reminder, parsePatch(t, `{"status": "processing"}`), false); err != nil {
+		t.Errorf("service pending -> processing: %v", err)
+	}
+}
+
+// Sending or expiring an occurrence moves a series on, as a PUT does
+func TestApplyMergePatchAdvancesSeries(t *testing.T) {
+	for _, status := range []string{StatusSent, StatusExpired} {
+		reminder := patchFixture()
+		reminder.Status = StatusProcessing
+		if status == StatusExpired {
+			reminder.Status = StatusPending
+		}
+		reminder.DateTime = time.Now().Add(-time.Hour).Truncate(time.Second)
+		reminder.Recurrence = "FREQ=DAILY"
+		start := reminder.DateTime.AddDate(0, 0, -7)
+		reminder.RecurrenceStart = &start
+
+		if _, err := applyMergePatch(&reminder, parsePatch(t, `{"status": "`+status+`"}`), false); err != nil {
+			t.Fatalf("%s: %v", status, err)
+		}
+		if reminder.Status != StatusPending || !reminder.DateTime.After(time.Now()) {
+			t.Errorf("%s: status %s, due %s; want pending at the next occurrence", status, reminder.Status, reminder.DateTime)
+		}
+	}
+}
+
+func TestValidateReminder(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(r *Reminder)
+		wantErr string
+	}{
+		{"valid", func(r *Reminder) {}, ""},
+		{"email channel without an email", func(r *Reminder) {
+			r.NotificationType, r.Email, r.ContactIDs = "email", "", nil
+		}, "Email is required"},
+		{"sms channel without a phone", func(r *Reminder) {
+			r.Phone, r.ContactIDs = "", nil
+		}, "Phone is required"},
+		{"contacts stand in for an address", func(r *Reminder) {
+			r.Phone = ""
+		}, ""},
+		{"groups stand in for an address", func(r *Reminder) {
+			r.Phone, r.ContactIDs, r.GroupIDs = "", nil, []string{"g1"}
+		}, ""},
+		{"no title", func(r *Reminder) { r.Title = "" }, "title is required"},
+		{"unknown channel", func(r *Reminder) { r.NotificationType = "fax" }, "notification_type must be"},
+		{"unknown priority", func(r *Reminder) { r.Priority = "urgent" }, "priority must be"},
+		{"unknown catch-up", func(r *Reminder) { r.CatchUp = "later" }, "catch_up must be"},
+		{"negative grace", func(r *Reminder) { r.GraceMinutes = -5 }, "grace_minutes cannot be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reminder := patchFixture()
+			tt.modify(&reminder)
+			err := validateReminder(&reminder)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("err = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyMergePatchTeam(t *testing.T) {
+	useTenant(t, "u1", Tenant{OrgID: "o1", TeamIDs: []string{"t1"}})
+
+	reminder := patchFixture()
+	if _, err := applyMergePatch(&reminder, parsePatch(t, `{"team_id": "t1"}`), true); err != nil || reminder.TeamID != "t1" {
+		t.Fatalf("team_id = %q, err %v; want t1", reminder.TeamID, err)
+	}
+	if _, err := applyMergePatch(&reminder, parsePatch(t, `{"team_id": "t2"}`), true); err == nil || !strings.Contains(err.Error(), "Team not found") {
+		t.Errorf("someone else's team: err = %v, want Team not found", err)
+	}
+	if _, err := applyMergePatch(&reminder, parsePatch(t, `{"team_id": null}`), true); err != nil || reminder.TeamID != "" {
+		t.Errorf("null team_id = %q, err %v; want private again", reminder.TeamID, err)
+	}
+}
@@ -0,0 +1,247 @@
+// query.go - Filtering, sorting and cursor pagination for reminder listings
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 100 // when a cursor is given without a limit
+	maxPageSize     = 500
+)
+
+// Columns reminders can be sorted by
+var sortColumns = map[string]string{
+	"datetime":   "date_time",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"title":      "title",
+}
+
+// ReminderFilter narrows down a user's reminders
+type ReminderFilter struct {
+	Status           []string   `json:"status,omitempty"`
+	NotificationType string     `json:"notification_type,omitempty"`
+	From             *time.Time `json:"from,omitempty"` // due at or after
+	To               *time.Time `json:"to,omitempty"`   // due before
+	Query            string     `json:"q,omitempty"`    // substring of title or description
+	ListID           string     `json:"list_id,omitempty"`
+	Tag              string     `json:"tag,omitempty"` // tag name or ID
+}
+
+// ListOptions controls ordering and paging of a listing
+type ListOptions struct {
+	Sort   string
+	Desc   bool
+	Limit  int // 0 = everything, in one response
+	Cursor *pageCursor
+}
+
+// pageCursor marks the last row of the previous page (keyset pagination)
+type pageCursor struct {
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// parseReminderFilter reads filters from query parameters:
+// status (comma-separated), notification_type, from, to (RFC3339), q, list_id and tag
+func parseReminderFilter(c *gin.Context) (ReminderFilter, error) {
+	var filter ReminderFilter
+
+	if status := c.Query("status"); status != "" {
+		for _, s := range strings.Split(status, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				filter.Status = append(filter.Status, s)
+			}
+		}
+	}
+	filter.NotificationType = c.Query("notification_type")
+	filter.Query = strings.TrimSpace(c.Query("q"))
+	filter.ListID = c.Query("list_id")
+	filter.Tag = c.Query("tag")
+
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from, use RFC3339")
+		}
+		filter.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to, use RFC3339")
+		}
+		filter.To = &t
+	}
+
+	return filter, nil
+}
+
+// parseListOptions reads sort (datetime, created_at, updated_at, title), order (asc, desc), limit and cursor.
+// Paging is opt-in: without a limit or cursor the whole listing comes back, as it always has.
+func parseListOptions(c *gin.Context) (ListOptions, error) {
+	opts := ListOptions{Sort: "datetime"}
+
+	if sort := c.Query("sort"); sort != "" {
+		if _, ok := sortColumns[sort]; !ok {
+			return opts, fmt.Errorf("invalid sort, use one of datetime, created_at, updated_at, title")
+		}
+		opts.Sort = sort
+	}
+
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		return opts, fmt.Errorf("invalid order, use asc or desc")
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return opts, fmt.Errorf("invalid limit")
+		}
+		if n > maxPageSize {
+			n = maxPageSize
+		}
+		opts.Limit = n
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return opts, fmt.Errorf("invalid cursor")
+		}
+		var pc pageCursor
+		if err := json.Unmarshal(raw, &pc); err != nil || pc.ID == "" {
+			return opts, fmt.Errorf("invalid cursor")
+		}
+		opts.Cursor = &pc
+		if opts.Limit == 0 {
+			opts.Limit = defaultPageSize
+		}
+	}
+
+	return opts, nil
+}
+
+// Apply adds the filter conditions to a query
+func (f ReminderFilter) Apply(query *gorm.DB) *gorm.DB {
+	if len(f.Status) > 0 {
+		query = query.Where("status IN ?", f.Status)
+	}
+	if f.NotificationType != "" {
+		query = query.Where("notification_type = ?", f.NotificationType)
+	}
+	if f.From != nil {
+		query = query.Where("date_time >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("date_time < ?", *f.To)
+	}
+	if f.Query != "" {
+		pattern := "%" + escapeLike(f.Query) + "%"
+		query = query.Where("(title ILIKE ? OR description ILIKE ?)", pattern, pattern)
+	}
+	if f.ListID != "" {
+		query = query.Where("list_id = ?", f.ListID)
+	}
+	if f.Tag != "" {
+		query = query.Where("id IN (?)", db.Table("reminder_tags").
+			Select("reminder_tags.reminder_id").
+			Joins("JOIN tags ON tags.id = reminder_tags.tag_id").
+			Where("tags.id = ? OR tags.name = ?", f.Tag, f.Tag))
+	}
+	return query
+}
+
+// Apply adds ordering, the cursor position and the page size to a query
+func (o ListOptions) Apply(query *gorm.DB) (*gorm.DB, error) {
+	column := sortColumns[o.Sort]
+	direction, comparison := "asc", ">"
+	if o.Desc {
+		direction, comparison = "desc", "<"
+	}
+
+	if o.Cursor != nil {
+		var value interface{} = o.Cursor.Value
+		if column != "title" {
+			t, err := time.Parse(time.RFC3339Nano, o.Cursor.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cursor")
+			}
+			value = t
+		}
+		query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, comparison), value, o.Cursor.ID)
+	}
+
+	query = query.Order(fmt.Sprintf("%s %s, id %s", column, direction, direction))
+	if o.Limit == 0 {
+		return query, nil
+	}
+	// Fetch one extra row to know whether there is a next page
+	return query.Limit(o.Limit + 1), nil
+}
+
+// respondWithPage writes one page of the query's reminders. Paging metadata goes in
+// headers (X-Total-Count, X-Next-Cursor) so the body stays a plain list.
+func respondWithPage(c *gin.Context, base *gorm.DB, opts ListOptions) {
+	var total int64
+	if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count reminders"})
+		return
+	}
+
+	query, err := opts.Apply(base.Session(&gorm.Session{}))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var reminders []Reminder
+	if err := query.Preload("Tags").Find(&reminders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminders"})
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	if opts.Limit > 0 && len(reminders) > opts.Limit {
+		reminders = reminders[:opts.Limit]
+		c.Header("X-Next-Cursor", opts.NextCursor(reminders[len(reminders)-1]))
+	}
+
+	c.JSON(http.StatusOK, reminders)
+}
+
+// NextCursor encodes the position after the last reminder of a page
+func (o ListOptions) NextCursor(last Reminder) string {
+	pc := pageCursor{ID: last.ID}
+	switch o.Sort {
+	case "created_at":
+		pc.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		pc.Value = last.UpdatedAt.Format(time.RFC3339Nano)
+	case "title":
+		pc.Value = last.Title
+	default:
+		pc.Value = last.DateTime.Format(time.RFC3339Nano)
+	}
+
+	raw, _ := json.Marshal(pc)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func queryContext(rawQuery string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/reminders?"+rawQuery, nil)
+	return c
+}
+
+func TestParseListOptions(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantSort  string
+		wantDesc  bool
+		wantLimit int
+		wantErr   string
+	}{
+		{"", "datetime", false, 0, ""},
+		{"sort=title&order=desc&limit=20", "title", true, 20, ""},
+		{"limit=100000", "datetime", false, maxPageSize, ""},
+		{"sort=priority", "", false, 0, "invalid sort"},
+		{"order=sideways", "", false, 0, "invalid order"},
+		{"limit=0", "", false, 0, "invalid limit"},
+		{"limit=ten", "", false, 0, "invalid limit"},
+		{"cursor=not-base64!", "", false, 0, "invalid cursor"},
+		{"cursor=e30", "", false, 0, "invalid cursor"}, // {} has no id
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			opts, err := parseListOptions(queryContext(tt.query))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseListOptions: %v", err)
+			}
+			if opts.Sort != tt.wantSort || opts.Desc != tt.wantDesc || opts.Limit != tt.wantLimit {
+				t.Errorf("options = %+v, want sort %s desc %v limit %d", opts, tt.wantSort, tt.wantDesc, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	last := Reminder{
+		ID:        "r2",
+		Title:     "Dentist, again",
+		DateTime:  time.Date(2026, 3, 10, 9, 0, 0, 123456789, time.UTC),
+		CreatedAt: time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC),
+	}
+
+	for _, sort := range []string{"datetime", "created_at", "updated_at", "title"} {
+		t.Run(sort, func(t *testing.T) {
+			cursor := ListOptions{Sort: sort}.NextCursor(last)
+			opts, err := parseListOptions(queryContext("sort=" + sort + "&cursor=" + cursor))
+			if err != nil {
+				t.Fatalf("parseListOptions: %v", err)
+			}
+			if opts.Cursor == nil || opts.Cursor.ID != "r2" {
+				t.Fatalf("cursor = %+v, want the position of r2", opts.Cursor)
+			}
+			// A cursor alone pages with the default size
+			if opts.Limit != defaultPageSize {
+				t.Errorf("limit = %d, want %d", opts.Limit, defaultPageSize)
+			}
+		})
+	}
+
+	// Times keep their full precision, or rows sharing a second would be skipped
+	opts, _ := parseListOptions(queryContext("cursor=" + ListOptions{Sort: "datetime"}.NextCursor(last)))
+	if opts.Cursor.Value != "2026-03-10T09:00:00.123456789Z" {
+		t.Errorf("cursor value = %s, want nanoseconds kept", opts.Cursor.Value)
+	}
+}
+
+func TestListOptionsApply(t *testing.T) {
+	useDryRunDB(t)
+	cursor := &pageCursor{Value: "2026-03-10T09:00:00Z", ID: "r2"}
+
+	tests := []struct {
+		name    string
+		opts    ListOptions
+		want    []string
+		wantErr bool
+	}{
+		{"unpaginated", ListOptions{Sort: "datetime"},
+			[]string{`ORDER BY date_time asc, id asc`}, false},
+		{"ties on the sort column are broken by id", ListOptions{Sort: "datetime", Limit: 10, Cursor: cursor},
+			[]string{`(date_time, id) > ($1, $2)`, `ORDER BY date_time asc, id asc`, `LIMIT $3`}, false},
+		{"descending pages go the other way", ListOptions{Sort: "datetime", Desc: true, Limit: 10, Cursor: cursor},
+			[]string{`(date_time, id) < ($1, $2)`, `ORDER BY date_time desc, id desc`}, false},
+		{"titles compare as text", ListOptions{Sort: "title", Limit: 10, Cursor: &pageCursor{Value: "B", ID: "r2"}},
+			[]string{`(title, id) > ($1, $2)`}, false},
+		{"a bad time in the cursor", ListOptions{Sort: "created_at", Limit: 10, Cursor: &pageCursor{Value: "B", ID: "r2"}},
+			nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := tt.opts.Apply(db.Model(&Reminder{}))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			stmt := query.Find(&[]Reminder{}).Statement
+			sql := stmt.SQL.String()
+			for _, want := range tt.want {
+				if !strings.Contains(sql, want) {
+					t.Errorf("SQL = %s\nwant it to contain %s", sql, want)
+				}
+			}
+			if tt.opts.Limit > 0 && stmt.Vars[len(stmt.Vars)-1] != tt.opts.Limit+1 {
+				t.Errorf("limit = %v, want one extra row to spot the next page", stmt.Vars[len(stmt.Vars)-1])
+			}
+		})
+	}
+}
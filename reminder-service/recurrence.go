@@ -0,0 +1,66 @@
+// recurrence.go - Repeating reminders (RFC 5545 RRULE)
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+)
+
+// normalizeRecurrence validates an RRULE ("FREQ=WEEKLY;BYDAY=MO") and strips an optional "RRULE:" prefix
+func normalizeRecurrence(rule string) (string, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return "", nil
+	}
+	if _, err := rrule.StrToROption(rule); err != nil {
+		return "", fmt.Errorf("invalid recurrence: %v", err)
+	}
+	return rule, nil
+}
+
+// nextOccurrence returns the series' first occurrence after the given time, or false once the series has ended
+func nextOccurrence(reminder Reminder, after time.Time) (time.Time, bool) {
+	opt, err := rrule.StrToROption(reminder.Recurrence)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if reminder.Timezone != "" && reminder.LocalTime != "" {
+		return nextWallClockOccurrence(reminder, opt, after)
+	}
+	opt.Dtstart = reminder.DateTime
+	if reminder.RecurrenceStart != nil {
+		opt.Dtstart = *reminder.RecurrenceStart
+	}
+
+	rule, err := rrule.NewRRule(*opt)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	next := rule.After(after, false)
+	return next, !next.IsZero()
+}
+
+// advanceRecurrence moves a repeating reminder that was just sent (or expired unsent) on to its next
+// occurrence. Occurrences that fell in the past while the reminder waited are skipped, not sent in a burst.
+func advanceRecurrence(reminder *Reminder) bool {
+	if reminder.Recurrence == "" || (reminder.Status != StatusSent && reminder.Status != StatusExpired) {
+		return false
+	}
+
+	after := reminder.DateTime
+	if now := time.Now(); now.After(after) {
+		after = now
+	}
+	next, ok := nextOccurrence(*reminder, after)
+	if !ok {
+		return false
+	}
+
+	reminder.DateTime = next
+	setStatus(reminder, StatusPending, "")
+	return true
+}
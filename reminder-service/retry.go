@@ -0,0 +1,182 @@
+// retry.go - Delivery failures, retries and the failed triage view
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RetryRequest DTO - same targeting as a batch
+type RetryRequest struct {
+	IDs    []string        `json:"ids"`
+	Filter *ReminderFilter `json:"filter"`
+}
+
+// DeliveryFailureRequest DTO
+type DeliveryFailureRequest struct {
+	Error string `json:"error" binding:"required"`
+}
+
+// retryReminder puts a failed reminder back in the queue; it goes out on the scheduler's next pass
+func retryReminder(c *gin.Context) {
+	reminder, err := findReminder(db, c.Param("id"), getUserID(c), permissionEdit)
+	if err != nil {
+		respondReminderError(c, err)
+		return
+	}
+
+	if reminder.Status != "failed" {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Only failed reminders can be retried, this one is %s", reminder.Status)})
+		return
+	}
+
+	// last_error stays so the triage view still shows why it failed before
+	before := reminder
+	setStatus(&reminder, StatusPending, "")
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, &reminder, "status", "requeued_at"); err != nil {
+			return err
+		}
+		return recordHistory(tx, auditFrom(c), "retry", &before, &reminder)
+	})
+	if err == errVersionConflict {
+		respondConflict(c)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry reminder"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reminder)
+}
+
+// retryReminders retries many failed reminders at once, as a batch retry operation
+func retryReminders(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req RetryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (len(req.IDs) == 0) == (req.Filter == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either ids or filter"})
+		return
+	}
+	if len(req.IDs) > maxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d ids per batch", maxBatchSize)})
+		return
+	}
+
+	// A filter only ever selects failed reminders here
+	if req.Filter != nil {
+		req.Filter.Status = []string{"failed"}
+	}
+
+	results, err := runBatch(userID, BatchRequest{Operation: "retry", IDs: req.IDs, Filter: req.Filter}, auditFrom(c))
+	if err == errBatchTooLarge {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Filter matches more than %d reminders, narrow it down", maxBatchSize)})
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrying reminders for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry reminders"})
+		return
+	}
+
+	retried := 0
+	for _, result := range results {
+		if result.Status == "ok" {
+			retried++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"retried": retried, "results": results})
+}
+
+// listFailedReminders is the triage view: failed reminders with their last error and attempt count,
+// most recent failures first unless sort/order say otherwise. Takes the same filters as the listing.
+func listFailedReminders(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	filter, err := parseReminderFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Status = []string{"failed"}
+
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Query("sort") == "" {
+		opts.Sort = "updated_at"
+		opts.Desc = c.DefaultQuery("order", "desc") == "desc"
+	}
+
+	respondWithPage(c, filter.Apply(accessibleReminders(db.Model(&Reminder{}), userID, permissionView)), opts)
+}
+
+// reportDeliveryFailure is called by the notification service when a message could not be
+// delivered after its retries. The scheduler has already marked the reminder sent by then.
+func reportDeliveryFailure(c *gin.Context) {
+	var req DeliveryFailureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var reminder Reminder
+	if err := db.Where("id = ?", c.Param("id")).First(&reminder).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminder"})
+		return
+	}
+
+	before := reminder
+	if reminder.Recurrence != "" || !canTransition(reminder.Status, StatusFailed, false) {
+		// The series has already moved on to its next occurrence, or the user has since
+		// acknowledged, cancelled or snoozed it; record the failure without changing that
+		now := time.Now()
+		reminder.LastError = req.Error
+		reminder.FailedAt = &now
+	} else {
+		setStatus(&reminder, "failed", req.Error)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, &reminder, "status", "last_error", "failed_at"); err != nil {
+			return err
+		}
+		return recordHistory(tx, auditFrom(c), "status", &before, &reminder)
+	})
+	if err == errVersionConflict {
+		respondConflict(c)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record delivery failure"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Delivery failure recorded"})
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// serve calls a handler directly, with the reminder ID as the :id parameter
+func serve(handler gin.HandlerFunc, method, id, body string, headers map[string]string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/api/reminders/"+id, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		c.Request.Header.Set(name, value)
+	}
+	c.Params = gin.Params{{Key: "id", Value: id}}
+	handler(c)
+	return w
+}
+
+func decodeReminder(t *testing.T, w *httptest.ResponseRecorder) Reminder {
+	t.Helper()
+	var reminder Reminder
+	if err := json.Unmarshal(w.Body.Bytes(), &reminder); err != nil {
+		t.Fatalf("decode %s: %v", w.Body.String(), err)
+	}
+	return reminder
+}
+
+func TestRetryReminder(t *testing.T) {
+	fake := useFakeDB(t)
+	useTenant(t, "u1", Tenant{})
+	fake.returns("reminders", batchFixtures()[:1])
+
+	w := serve(retryReminder, "POST", "r1", "", map[string]string{"X-User-ID": "u1"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	reminder := decodeReminder(t, w)
+	if reminder.Status != StatusPending || reminder.LastError != "smtp down" || reminder.Version != 4 {
+		t.Errorf("got status %s, last_error %q, version %d; want pending, the old error kept, version 4",
+			reminder.Status, reminder.LastError, reminder.Version)
+	}
+
+	updates := fake.executed(`UPDATE "reminders"`, "WHERE version = ")
+	if len(updates) != 1 || strings.Contains(updates[0].SQL, `"last_error"`) {
+		t.Errorf("updates = %v, want a versioned status update", updates)
+	}
+	history := fake.executed(`INSERT INTO "reminder_histories"`)
+	if len(history) != 1 || history[0].Vars[3] != "retry" || history[0].Vars[4] != "user:u1" {
+		t.Errorf("history = %v, want a retry by user:u1", history)
+	}
+}
+
+func TestRetryReminderRefused(t *testing.T) {
+	tests := []struct {
+		name     string
+		fixture  []Reminder
+		conflict bool
+		headers  map[string]string
+		want     int
+	}{
+		{"not failed", batchFixtures()[1:2], false, nil, http.StatusConflict},
+		{"not found", nil, false, nil, http.StatusNotFound},
+		{"someone else's", []Reminder{{ID: "r1", UserID: "u2", Status: StatusFailed}}, false, nil, http.StatusForbidden},
+		{"written meanwhile", batchFixtures()[:1], true, nil, http.StatusConflict},
+		{"written meanwhile, with If-Match", batchFixtures()[:1], true, map[string]string{"If-Match": `"3"`}, http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeDB(t)
+			useTenant(t, "u1", Tenant{})
+			fake.returns("reminders", tt.fixture)
+			if tt.conflict {
+				fake.affected = func(*gorm.Statement) int64 { return 0 }
+			}
+
+			headers := map[string]string{"X-User-ID": "u1"}
+			for name, value := range tt.headers {
+				headers[name] = value
+			}
+			if w := serve(retryReminder, "POST", "r1", "", headers); w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want != http.StatusOK && len(fake.executed(`INSERT INTO "reminder_histories"`)) != 0 {
+				t.Error("a refused retry was recorded in the history")
+			}
+		})
+	}
+}
+
+func TestRetryRemindersOnlyFailed(t *testing.T) {
+	fake := useFakeDB(t)
+	useTenant(t, "u1", Tenant{})
+	fake.returns("reminders", batchFixtures()[:1])
+
+	w := serve(retryReminders, "POST", "", `{"filter": {"status": ["pending"], "notification_type": "email"}}`, map[string]string{"X-User-ID": "u1"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+
+	selects := fake.executed(`SELECT * FROM "reminders"`, "status IN")
+	if len(selects) != 1 {
+		t.Fatalf("selects = %v", fake.statements)
+	}
+	var statuses []string
+	for _, v := range selects[0].Vars {
+		if s, ok := v.(string); ok && (s == StatusFailed || s == StatusPending) {
+			statuses = append(statuses, s)
+		}
+	}
+	if len(statuses) != 1 || statuses[0] != StatusFailed {
+		t.Errorf("filtered on statuses %v, want only failed", statuses)
+	}
+
+	var body struct {
+		Retried int `json:"retried"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Retried != 1 {
+		t.Errorf("retried = %d, want 1", body.Retried)
+	}
+}
+
+func TestRetryRemindersNeedsTargets(t *testing.T) {
+	for _, body := range []string{`{}`, `{"ids": ["r1"], "filter": {}}`} {
+		if w := serve(retryReminders, "POST", "", body, map[string]string{"X-User-ID": "u1"}); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, w.Code)
+		}
+	}
+}
+
+func TestReportDeliveryFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		reminder   Reminder
+		wantStatus string
+	}{
+		{"a sent one-off fails", Reminder{ID: "r1", UserID: "u1", Status: StatusSent}, StatusFailed},
+		{"a series has moved on", Reminder{ID: "r1", UserID: "u1", Status: StatusPending, Recurrence: "FREQ=DAILY"}, StatusPending},
+		{"the user acknowledged it", Reminder{ID: "r1", UserID: "u1", Status: StatusAcknowledged}, StatusAcknowledged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeDB(t)
+			fake.returns("reminders", []Reminder{tt.reminder})
+
+			w := serve(reportDeliveryFailure, "POST", "r1", `{"error": "mailbox full"}`, map[string]string{"X-Service-Name": "notification-service"})
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+			}
+
+			updates := fake.executed(`UPDATE "reminders"`)
+			if len(updates) != 1 {
+				t.Fatalf("updates = %v", fake.statements)
+			}
+			vars := updates[0].Vars
+			if vars[0] != tt.wantStatus || vars[1] != "mailbox full" {
+				t.Errorf("wrote status %v, last_error %v; want %s, mailbox full", vars[0], vars[1], tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// search.go - Full-text search across reminders
+package main
+
+import (
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Postgres marks matches with these private-use characters; the text around them is HTML-escaped
+// before they're turned into <mark> tags, so a title can't smuggle markup into a highlight
+const (
+	highlightStart = "\ue000"
+	highlightStop  = "\ue001"
+)
+
+var highlightMarkup = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+// SearchResult is a reminder matching a search, with its rank and highlighted snippets.
+// Highlights are HTML: the reminder's text escaped, with matches wrapped in <mark>.
+type SearchResult struct {
+	Reminder
+	Rank                 float64 `json:"rank"`
+	TitleHighlight       string  `json:"title_highlight"`
+	DescriptionHighlight string  `json:"description_highlight"`
+}
+
+// initSearch creates the search column and indexes AutoMigrate doesn't know about:
+// a weighted tsvector over title + description, and trigram indexes for fuzzy matching
+func initSearch() {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`ALTER TABLE reminders ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+				setweight(to_tsvector('english', coalesce(description, '')), 'B')
+			) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_reminders_search ON reminders USING GIN (search_vector)`,
+		`CREATE INDEX IF NOT EXISTS idx_reminders_title_trgm ON reminders USING GIN (title gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_reminders_description_trgm ON reminders USING GIN (description gin_trgm_ops)`,
+	}
+
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			// Search is degraded but the rest of the service still works
+			log.Printf("Failed to set up full-text search: %v", err)
+			return
+		}
+	}
+}
+
+func searchReminders(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	q := c.Query("q")
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	limit := 20
+	if l := c.Query("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		if n > maxPageSize {
+			n = maxPageSize
+		}
+		limit = n
+	}
+
+	// Exact word matches rank by ts_rank; typos ("dentst") still match via trigram word similarity
+	selectors := "StartSel=" + highlightStart + ", StopSel=" + highlightStop
+	titleOptions := "HighlightAll=true, " + selectors
+	descriptionOptions := "MaxFragments=2, MinWords=5, MaxWords=20, " + selectors
+	var results []SearchResult
+	err := accessibleReminders(db.Model(&Reminder{}), userID, permissionView).
+		Select(`reminders.*,
+			ts_rank(search_vector, websearch_to_tsquery('english', ?)) + word_similarity(?, title) AS rank,
+			ts_headline('english', title, websearch_to_tsquery('english', ?), ?) AS title_highlight,
+			ts_headline('english', coalesce(description, ''), websearch_to_tsquery('english', ?), ?) AS description_highlight`,
+			q, q, q, titleOptions, q, descriptionOptions).
+		Where("(search_vector @@ websearch_to_tsquery('english', ?) OR ? <% title OR ? <% description)", q, q, q).
+		Order("rank desc").
+		Limit(limit).
+		Scan(&results).Error
+	if err != nil {
+		log.Printf("Error searching reminders: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search reminders"})
+		return
+	}
+
+	for i := range results {
+		results[i].TitleHighlight = escapeHighlight(results[i].TitleHighlight)
+		results[i].DescriptionHighlight = escapeHighlight(results[i].DescriptionHighlight)
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// escapeHighlight HTML-escapes a ts_headline snippet, then marks its matches
+func escapeHighlight(snippet string) string {
+	return highlightMarkup.Replace(html.EscapeString(snippet))
+}
@@ -0,0 +1,377 @@
+// shares.go - Sharing reminders and lists with other users, and the access checks that go with it
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Permissions, from least to most. Owner is never granted: only the reminder's owner may delete,
+// restore or purge it, or change who it's shared with.
+const (
+	permissionView  = "view"
+	permissionEdit  = "edit"
+	permissionOwner = "owner"
+)
+
+// Share permissions that satisfy each required permission
+var grantingPermissions = map[string][]string{
+	permissionView: {permissionView, permissionEdit},
+	permissionEdit: {permissionEdit},
+}
+
+var (
+	errForbidden   = errors.New("forbidden")
+	errOtherTenant = errors.New("belongs to another organization")
+)
+
+// Share model - gives another user access to one reminder, or to every reminder in a list.
+// A collaborator has at most one share per reminder or list.
+type Share struct {
+	ID         string  `json:"id" gorm:"primaryKey"`
+	OwnerID    string  `json:"owner_id" gorm:"index;not null"`
+	ReminderID *string `json:"reminder_id,omitempty" gorm:"uniqueIndex:idx_shares_reminder_user"`
+	ListID     *string `json:"list_id,omitempty" gorm:"uniqueIndex:idx_shares_list_user"`
+	// The collaborator
+	UserID     string    `json:"user_id" gorm:"index;not null;uniqueIndex:idx_shares_reminder_user;uniqueIndex:idx_shares_list_user"`
+	Permission string    `json:"permission" gorm:"not null"` // view or edit
+	Notify     bool      `json:"notify" gorm:"not null"`     // the collaborator gets the reminder's notifications too
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ShareRequest DTO
+type ShareRequest struct {
+	UserID     string `json:"user_id" binding:"required"`
+	Permission string `json:"permission" binding:"omitempty,oneof=view edit"` // defaults to view
+	Notify     *bool  `json:"notify"`                                         // defaults to true
+}
+
+// accessibleReminders narrows a reminder query to those the user has the permission on: their
+// own (personal ones they kept on joining an organization included), plus for view and edit those
+// in their organization shared with them directly or through their list, and for view those of
+// their teams. Organization admins can view the reminders of every team in the organization, but
+// not members' private ones. If the organization can't be looked up, only the user's own
+// reminders are safe to show.
+func accessibleReminders(query *gorm.DB, userID, permission string) *gorm.DB {
+	if permission == permissionOwner {
+		return query.Where("user_id = ?", userID)
+	}
+	tenant, err := userTenant(userID)
+	if err != nil {
+		log.Printf("Error fetching tenant for user %s, showing only their own reminders: %v", userID, err)
+		return query.Where("user_id = ?", userID)
+	}
+
+	if permission == permissionView && tenant.OrgID != "" && tenant.Role == orgRoleAdmin {
+		return query.Where("(user_id = ? OR (org_id = ? AND team_id <> ''))", userID, tenant.OrgID)
+	}
+	teams := []string{}
+	if permission == permissionView && tenant.OrgID != "" {
+		teams = append(teams, tenant.TeamIDs...)
+	}
+	granting := grantingPermissions[permission]
+	return query.Where("(user_id = ? OR (org_id = ? AND (team_id IN ? OR id IN (?) OR list_id IN (?))))", userID, tenant.OrgID, teams,
+		db.Model(&Share{}).Select("reminder_id").Where("user_id = ? AND permission IN ? AND reminder_id IS NOT NULL", userID, granting),
+		db.Model(&Share{}).Select("list_id").Where("user_id = ? AND permission IN ? AND list_id IS NOT NULL", userID, granting))
+}
+
+// findReminder loads a reminder the user may act on with the given permission. A reminder they
+// can't see at all isn't found; one they can see but not act on is errForbidden.
+func findReminder(query *gorm.DB, id, userID, permission string) (Reminder, error) {
+	var reminder Reminder
+	if err := accessibleReminders(query.Where("id = ?", id), userID, permissionView).First(&reminder).Error; err != nil {
+		return reminder, err
+	}
+	if !hasPermission(reminder, userID, permission) {
+		return reminder, errForbidden
+	}
+	return reminder, nil
+}
+
+// hasPermission reports whether the user has the permission on a reminder they can see
+func hasPermission(reminder Reminder, userID, permission string) bool {
+	if reminder.UserID == userID {
+		return true
+	}
+	if permission == permissionOwner {
+		return false
+	}
+	if permission == permissionView && reminder.OrgID != "" && reminder.TeamID != "" {
+		tenant, err := userTenant(userID)
+		if err == nil && tenant.OrgID == reminder.OrgID && (tenant.Role == orgRoleAdmin || inTeam(tenant, reminder.TeamID)) {
+			return true
+		}
+	}
+
+	query := db.Model(&Share{}).Where("user_id = ? AND permission IN ?", userID, grantingPermissions[permission])
+	if reminder.ListID != nil {
+		query = query.Where("(reminder_id = ? OR list_id = ?)", reminder.ID, *reminder.ListID)
+	} else {
+		query = query.Where("reminder_id = ?", reminder.ID)
+	}
+	var count int64
+	query.Count(&count)
+	return count > 0
+}
+
+// respondReminderError answers a failed findReminder
+func respondReminderError(c *gin.Context, err error) {
+	switch err {
+	case gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
+	case errForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to do that to this reminder"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminder"})
+	}
+}
+
+// notifiedCollaborators fills in, for each reminder, the collaborators who get its notifications
+func notifiedCollaborators(reminders []Reminder) error {
+	if len(reminders) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(reminders))
+	var listIDs []string
+	for _, reminder := range reminders {
+		ids = append(ids, reminder.ID)
+		if reminder.ListID != nil {
+			listIDs = append(listIDs, *reminder.ListID)
+		}
+	}
+
+	var shares []Share
+	query := db.Where("notify = ?", true)
+	if len(listIDs) > 0 {
+		query = query.Where("(reminder_id IN ? OR list_id IN ?)", ids, listIDs)
+	} else {
+		query = query.Where("reminder_id IN ?", ids)
+	}
+	if err := query.Find(&shares).Error; err != nil {
+		return err
+	}
+
+	for i := range reminders {
+		reminder := &reminders[i]
+		seen := map[string]bool{reminder.UserID: true}
+		for _, share := range shares {
+			onReminder := share.ReminderID != nil && *share.ReminderID == reminder.ID
+			onList := share.ListID != nil && reminder.ListID != nil && *share.ListID == *reminder.ListID
+			if (onReminder || onList) && !seen[share.UserID] {
+				seen[share.UserID] = true
+				reminder.Collaborators = append(reminder.Collaborators, share.UserID)
+			}
+		}
+	}
+	return nil
+}
+
+// listSharedWithMe lists the shares other users have given the caller
+func listSharedWithMe(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var shares []Share
+	if err := db.Where("user_id = ?", userID).Order("created_at desc").Find(&shares).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shares"})
+		return
+	}
+
+	c.JSON(http.StatusOK, shares)
+}
+
+// Reminder shares: anyone who can see the reminder can see who it's shared with; only the owner
+// can change that
+
+func listReminderShares(c *gin.Context) {
+	reminder, err := findReminder(db, c.Param("id"), getUserID(c), permissionView)
+	if err != nil {
+		respondReminderError(c, err)
+		return
+	}
+	respondShares(c, db.Where("reminder_id = ?", reminder.ID))
+}
+
+func createReminderShare(c *gin.Context) {
+	reminder, err := findReminder(db, c.Param("id"), getUserID(c), permissionOwner)
+	if err != nil {
+		respondReminderError(c, err)
+		return
+	}
+	saveShare(c, Share{OwnerID: reminder.UserID, ReminderID: &reminder.ID}, "reminder_id")
+}
+
+func deleteReminderShare(c *gin.Context) {
+	reminder, err := findReminder(db, c.Param("id"), getUserID(c), permissionView)
+	if err != nil {
+		respondReminderError(c, err)
+		return
+	}
+	removeShare(c, reminder.UserID, "reminder_id = ?", reminder.ID)
+}
+
+// List shares: the owner manages them, and collaborators on the list can see them
+
+func listListShares(c *gin.Context) {
+	list, err := findSharedList(c.Param("id"), getUserID(c))
+	if err != nil {
+		respondListError(c, err)
+		return
+	}
+	respondShares(c, db.Where("list_id = ?", list.ID))
+}
+
+func createListShare(c *gin.Context) {
+	list, err := findReminderList(c.Param("id"), getUserID(c))
+	if err != nil {
+		respondListError(c, err)
+		return
+	}
+	saveShare(c, Share{OwnerID: list.UserID, ListID: &list.ID}, "list_id")
+}
+
+func deleteListShare(c *gin.Context) {
+	list, err := findSharedList(c.Param("id"), getUserID(c))
+	if err != nil {
+		respondListError(c, err)
+		return
+	}
+	removeShare(c, list.UserID, "list_id = ?", list.ID)
+}
+
+// findSharedList loads a list the user owns or that's been shared with them
+func findSharedList(id, userID string) (ReminderList, error) {
+	var list ReminderList
+	err := db.Where("id = ? AND (user_id = ? OR id IN (?))", id, userID,
+		db.Model(&Share{}).Select("list_id").Where("user_id = ? AND list_id IS NOT NULL", userID)).
+		First(&list).Error
+	return list, err
+}
+
+func respondListError(c *gin.Context, err error) {
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch list"})
+}
+
+func respondShares(c *gin.Context, query *gorm.DB) {
+	var shares []Share
+	if err := query.Order("created_at asc").Find(&shares).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shares"})
+		return
+	}
+	c.JSON(http.StatusOK, shares)
+}
+
+// saveShare shares the target (the share's reminder_id or list_id) with a user, or changes the
+// permission of an existing share
+func saveShare(c *gin.Context, share Share, target string) {
+	var req ShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.UserID == share.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Can't share with yourself"})
+		return
+	}
+	if err := sameTenant(share.OwnerID, req.UserID); err != nil {
+		if err == errOtherTenant {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Can only share with users in your organization"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check organization"})
+		return
+	}
+
+	share.ID = uuid.New().String()
+	share.UserID = req.UserID
+	share.Permission = permissionView
+	share.Notify = true
+	updates := []string{"updated_at"}
+	if req.Permission != "" {
+		share.Permission = req.Permission
+		updates = append(updates, "permission")
+	}
+	if req.Notify != nil {
+		share.Notify = *req.Notify
+		updates = append(updates, "notify")
+	}
+
+	// An existing share only takes the fields given; the unique index settles concurrent requests
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: target}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns(updates),
+	}).Create(&share).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save share"})
+		return
+	}
+
+	var saved Share
+	targetID := share.ReminderID
+	if share.ListID != nil {
+		targetID = share.ListID
+	}
+	if err := db.Where(target+" = ? AND user_id = ?", *targetID, req.UserID).First(&saved).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch share"})
+		return
+	}
+
+	status := http.StatusOK
+	if saved.ID == share.ID {
+		status = http.StatusCreated
+	}
+	c.JSON(status, saved)
+}
+
+// sameTenant checks that two users belong to the same organization (or both to none)
+func sameTenant(userID, otherUserID string) error {
+	tenant, err := userTenant(userID)
+	if err != nil {
+		return err
+	}
+	other, err := userTenant(otherUserID)
+	if err != nil {
+		return err
+	}
+	if tenant.OrgID != other.OrgID {
+		return errOtherTenant
+	}
+	return nil
+}
+
+// removeShare deletes a share; the owner can remove anyone, a collaborator only themselves
+func removeShare(c *gin.Context, ownerID, target string, targetID string) {
+	query := db.Where(target+" AND id = ?", targetID, c.Param("shareId"))
+	if userID := getUserID(c); userID != ownerID {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	result := query.Delete(&Share{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete share"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Share deleted successfully"})
+}
@@ -0,0 +1,150 @@
+// status.go - Reminder status state machine
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Reminder statuses
+const (
+	StatusPending      = "pending"      // waiting to be due
+	StatusProcessing   = "processing"   // claimed by the scheduler, being sent
+	StatusSent         = "sent"         // handed to the notification service
+	StatusFailed       = "failed"       // couldn't be sent; can be retried
+	StatusCancelled    = "cancelled"    // called off by the user, never sent
+	StatusSnoozed      = "snoozed"      // put off by the user until its (moved) due time
+	StatusAcknowledged = "acknowledged" // seen by the user after it was sent
+	StatusExpired      = "expired"      // missed, and too late to still send
+)
+
+// statusTransitions lists every allowed move, with whether users may make it themselves;
+// the rest are only made by the scheduler and notification services
+var statusTransitions = map[string]map[string]bool{
+	StatusPending: {
+		StatusProcessing: false,
+		StatusExpired:    false,
+		StatusCancelled:  true,
+		StatusSnoozed:    true,
+	},
+	StatusProcessing: {
+		StatusSent:    false,
+		StatusFailed:  false,
+		StatusPending: false, // deferred for quiet hours, or reset after getting stuck
+	},
+	StatusSent: {
+		StatusPending:      false, // a repeating reminder moving on to its next occurrence
+		StatusFailed:       false, // delivery failed downstream
+		StatusAcknowledged: true,
+		StatusSnoozed:      true,
+	},
+	StatusFailed: {
+		StatusPending:   true, // retry
+		StatusCancelled: true,
+	},
+	StatusSnoozed: {
+		StatusProcessing: false,
+		StatusExpired:    false,
+		StatusPending:    true,
+		StatusCancelled:  true,
+	},
+	StatusExpired: {
+		StatusPending:   true, // rescheduled
+		StatusCancelled: true,
+	},
+	StatusCancelled: {
+		StatusPending: true, // reinstated
+	},
+	StatusAcknowledged: {},
+}
+
+// canTransition reports whether a reminder may move between the statuses; byUser limits it to
+// the moves users may make themselves. Staying in the same status is always allowed, though for
+// a user it changes nothing (see transitionStatus).
+func canTransition(from, to string, byUser bool) bool {
+	if from == to {
+		return true
+	}
+	userAllowed, ok := statusTransitions[from][to]
+	return ok && (userAllowed || !byUser)
+}
+
+// transitionError is an illegal status change, reported as 409
+type transitionError struct {
+	From, To string
+}
+
+func (e transitionError) Error() string {
+	return fmt.Sprintf("Cannot change status from %s to %s", e.From, e.To)
+}
+
+// transitionStatus checks a status change against the state machine and applies it.
+// A user naming the status a reminder already has leaves it, and the delivery details that go
+// with it, as they are; only the services re-enter a status (reporting another failure, say).
+func transitionStatus(reminder *Reminder, status, deliveryError string, byUser bool) error {
+	if !canTransition(reminder.Status, status, byUser) {
+		return transitionError{From: reminder.Status, To: status}
+	}
+	if byUser && reminder.Status == status {
+		return nil
+	}
+	setStatus(reminder, status, deliveryError)
+	return nil
+}
+
+// applyStatusChange is the status part of an update, shared by PUT and PATCH: the move is checked
+// against the state machine, a snooze must come with a new time in the future (rescheduled), and a
+// repeating reminder that was just sent or expired moves on to its next occurrence
+func applyStatusChange(reminder *Reminder, status, deliveryError string, rescheduled, byUser bool) error {
+	if err := transitionStatus(reminder, status, deliveryError, byUser); err != nil {
+		return err
+	}
+	if status == StatusSnoozed && (!rescheduled || !reminder.DateTime.After(time.Now())) {
+		return fmt.Errorf("Snooze needs a datetime in the future")
+	}
+	advanceRecurrence(reminder)
+	return nil
+}
+
+// setStatus moves a reminder to a new status, keeping the attempt counter and failure details up to date
+func setStatus(reminder *Reminder, status, deliveryError string) {
+	switch status {
+	case StatusProcessing:
+		if reminder.Status != StatusProcessing {
+			reminder.Attempts++
+		}
+	case StatusFailed:
+		now := time.Now()
+		reminder.FailedAt = &now
+		if deliveryError != "" {
+			reminder.LastError = deliveryError
+		}
+	case StatusSent:
+		reminder.LastError = ""
+		reminder.FailedAt = nil
+	case StatusPending:
+		// Back in the queue by hand rather than deferred, reset or moved on to the next occurrence
+		if reminder.Status != StatusPending && reminder.Status != StatusProcessing && reminder.Status != StatusSent {
+			now := time.Now()
+			reminder.RequeuedAt = &now
+		}
+	}
+	// Published channels only carry over while the occurrence is being retried
+	if status != StatusPending && status != StatusProcessing && status != StatusFailed {
+		reminder.PublishedChannels = nil
+	}
+	reminder.Status = status
+}
+
+// addPublishedChannels records channels that went out before a send failed, so the retry skips them
+func addPublishedChannels(reminder *Reminder, channels []string) {
+	for _, channel := range channels {
+		known := false
+		for _, published := range reminder.PublishedChannels {
+			known = known || published == channel
+		}
+		if !known {
+			reminder.PublishedChannels = append(reminder.PublishedChannels, channel)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		byUser   bool
+		want     bool
+	}{
+		// The scheduler's path through an occurrence
+		{StatusPending, StatusProcessing, false, true},
+		{StatusProcessing, StatusSent, false, true},
+		{StatusProcessing, StatusFailed, false, true},
+		{StatusProcessing, StatusPending, false, true},
+		{StatusSent, StatusPending, false, true},
+		{StatusSent, StatusFailed, false, true},
+		{StatusPending, StatusExpired, false, true},
+		{StatusSnoozed, StatusProcessing, false, true},
+
+		// What users may do themselves
+		{StatusFailed, StatusPending, true, true},
+		{StatusFailed, StatusCancelled, true, true},
+		{StatusPending, StatusCancelled, true, true},
+		{StatusPending, StatusSnoozed, true, true},
+		{StatusSent, StatusAcknowledged, true, true},
+		{StatusSent, StatusSnoozed, true, true},
+		{StatusSnoozed, StatusPending, true, true},
+		{StatusExpired, StatusPending, true, true},
+		{StatusCancelled, StatusPending, true, true},
+
+		// Scheduler-only moves are refused to users
+		{StatusPending, StatusProcessing, true, false},
+		{StatusProcessing, StatusSent, true, false},
+		{StatusProcessing, StatusFailed, true, false},
+		{StatusPending, StatusExpired, true, false},
+		{StatusSent, StatusPending, true, false},
+
+		// Never allowed
+		{StatusSent, StatusProcessing, false, false},
+		{StatusFailed, StatusSent, false, false},
+		{StatusCancelled, StatusProcessing, false, false},
+		{StatusCancelled, StatusSent, false, false},
+		{StatusAcknowledged, StatusPending, false, false},
+		{StatusAcknowledged, StatusPending, true, false},
+		{StatusExpired, StatusSent, false, false},
+		{StatusPending, StatusAcknowledged, true, false},
+		{"unknown", StatusPending, false, false},
+		{StatusPending, "unknown", false, false},
+
+		// Staying put is always fine
+		{StatusSent, StatusSent, true, true},
+		{StatusAcknowledged, StatusAcknowledged, false, true},
+	}
+
+	for _, tt := range tests {
+		if got := canTransition(tt.from, tt.to, tt.byUser); got != tt.want {
+			t.Errorf("canTransition(%s, %s, byUser=%v) = %v, want %v", tt.from, tt.to, tt.byUser, got, tt.want)
+		}
+	}
+}
+
+func TestTransitionStatus(t *testing.T) {
+	reminder := Reminder{Status: StatusSent}
+	err := transitionStatus(&reminder, StatusProcessing, "", false)
+	if _, ok := err.(transitionError); !ok {
+		t.Fatalf("sent -> processing: got %v, want a transitionError", err)
+	}
+	if reminder.Status != StatusSent {
+		t.Errorf("status after a refused transition = %s, want sent", reminder.Status)
+	}
+
+	reminder = Reminder{Status: StatusPending}
+	if err := transitionStatus(&reminder, StatusProcessing, "", false); err != nil {
+		t.Fatalf("pending -> processing: %v", err)
+	}
+	if reminder.Status != StatusProcessing || reminder.Attempts != 1 {
+		t.Errorf("after pending -> processing: status %s, attempts %d", reminder.Status, reminder.Attempts)
+	}
+}
+
+func TestTransitionStatusSameStatus(t *testing.T) {
+	// A user naming the current status can't rewrite how delivery went
+	reminder := Reminder{Status: StatusFailed, LastError: "smtp timeout", Attempts: 2}
+	if err := transitionStatus(&reminder, StatusFailed, "forged", true); err != nil {
+		t.Fatalf("failed -> failed by a user: %v", err)
+	}
+	if reminder.LastError != "smtp timeout" || reminder.FailedAt != nil {
+		t.Errorf("user's failed -> failed set last_error %q, failed_at %v; want both untouched", reminder.LastError, reminder.FailedAt)
+	}
+
+	reminder = Reminder{Status: StatusFailed, LastError: "mailbox full", PublishedChannels: []string{"email"}}
+	if err := transitionStatus(&reminder, StatusFailed, "smtp timeout", false); err != nil {
+		t.Fatalf("failed -> failed by a service: %v", err)
+	}
+	if reminder.LastError != "smtp timeout" || reminder.FailedAt == nil {
+		t.Errorf("service's failed -> failed: last_error %q, failed_at %v; want the new failure recorded", reminder.LastError, reminder.FailedAt)
+	}
+}
+
+func TestUpdateReminderDeliveryDetails(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		body    string
+		headers map[string]string
+		want    int
+	}{
+		{"users can't report an error", StatusFailed, `{"status": "failed", "error": "forged"}`, map[string]string{"X-User-ID": "u1"}, http.StatusBadRequest},
+		{"users can't claim a digest", StatusSent, `{"status": "sent", "delivered_by": "digest"}`, map[string]string{"X-User-ID": "u1"}, http.StatusBadRequest},
+		{"users can't mark channels published", StatusFailed, `{"published_channels": ["sms"]}`, map[string]string{"X-User-ID": "u1"}, http.StatusBadRequest},
+		{"users may resend the current status", StatusSent, `{"status": "sent", "title": "Renamed"}`, map[string]string{"X-User-ID": "u1"}, http.StatusOK},
+		{"the scheduler reports errors", StatusProcessing, `{"status": "failed", "error": "smtp timeout"}`, nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeDB(t)
+			useTenant(t, "u1", Tenant{})
+			reminder := patchFixture()
+			reminder.Status = tt.status
+			reminder.DeliveredBy = "digest"
+			reminder.LastError = "mailbox full"
+			fake.returns("reminders", []Reminder{reminder})
+
+			w := serve(updateReminder, "PUT", "r1", tt.body, tt.headers)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				if len(fake.executed("UPDATE")) != 0 {
+					t.Error("a refused update was written")
+				}
+				return
+			}
+
+			updates := fake.executed(`UPDATE "reminders"`)
+			if len(updates) != 1 {
+				t.Fatalf("updates = %v", fake.statements)
+			}
+			want := map[string]interface{}{"status": StatusFailed, "last_error": "smtp timeout"}
+			if tt.headers != nil {
+				want = map[string]interface{}{"status": StatusSent, "delivered_by": "digest", "last_error": "mailbox full", "title": "Renamed"}
+			}
+			for column, value := range want {
+				if got, _ := updates[0].set(column); got != value {
+					t.Errorf("%s written as %v, want %v", column, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestSetStatus(t *testing.T) {
+	reminder := Reminder{Status: StatusProcessing, Attempts: 1}
+
+	setStatus(&reminder, StatusFailed, "smtp timeout")
+	if reminder.LastError != "smtp timeout" || reminder.FailedAt == nil {
+		t.Errorf("failed: last_error %q, failed_at %v", reminder.LastError, reminder.FailedAt)
+	}
+
+	addPublishedChannels(&reminder, []string{"email"})
+	addPublishedChannels(&reminder, []string{"email", "sms"})
+	if len(reminder.PublishedChannels) != 2 {
+		t.Errorf("published channels = %v, want [email sms]", reminder.PublishedChannels)
+	}
+
+	// A retry keeps what was already published
+	setStatus(&reminder, StatusPending, "")
+	setStatus(&reminder, StatusProcessing, "")
+	if reminder.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", reminder.Attempts)
+	}
+	if len(reminder.PublishedChannels) != 2 {
+		t.Errorf("published channels after retry = %v, want kept", reminder.PublishedChannels)
+	}
+
+	// Processing again doesn't count as another attempt
+	setStatus(&reminder, StatusProcessing, "")
+	if reminder.Attempts != 2 {
+		t.Errorf("attempts = %d, want still 2", reminder.Attempts)
+	}
+
+	setStatus(&reminder, StatusSent, "")
+	if reminder.LastError != "" || reminder.FailedAt != nil || reminder.PublishedChannels != nil {
+		t.Errorf("sent: last_error %q, failed_at %v, published %v; want all cleared",
+			reminder.LastError, reminder.FailedAt, reminder.PublishedChannels)
+	}
+}
+
+func TestSetStatusRequeuedAt(t *testing.T) {
+	tests := []struct {
+		from string
+		want bool
+	}{
+		{StatusFailed, true},      // retried
+		{StatusExpired, true},     // rescheduled
+		{StatusCancelled, true},   // reinstated
+		{StatusSnoozed, true},     // snooze called off
+		{StatusProcessing, false}, // deferred for quiet hours, or reset after getting stuck
+		{StatusSent, false},       // a series moving on
+		{StatusPending, false},
+	}
+
+	for _, tt := range tests {
+		reminder := Reminder{Status: tt.from}
+		setStatus(&reminder, StatusPending, "")
+		if (reminder.RequeuedAt != nil) != tt.want {
+			t.Errorf("%s -> pending: requeued_at %v, want set %v", tt.from, reminder.RequeuedAt, tt.want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// tenant.go - Organizations (tenants), from user-service: every reminder belongs to its owner's
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	tenantCacheTTL = time.Minute
+	orgRoleAdmin   = "admin" // sees the reminders of every team in the organization
+)
+
+// Tenant structure (matches user service)
+type Tenant struct {
+	OrgID   string   `json:"org_id"` // empty for users in no organization
+	Role    string   `json:"role,omitempty"`
+	TeamIDs []string `json:"team_ids"` // the teams they're in, whose reminders they see
+}
+
+// TenantChangeRequest DTO
+type TenantChangeRequest struct {
+	OrgID         string `json:"org_id"`
+	MoveReminders bool   `json:"move_reminders"` // on joining, bring personal reminders along
+}
+
+// TenantDissolveRequest DTO
+type TenantDissolveRequest struct {
+	OrgID string `json:"org_id" binding:"required"`
+}
+
+type cachedTenant struct {
+	tenant  Tenant
+	expires time.Time
+}
+
+var (
+	tenantCache   = make(map[string]cachedTenant)
+	tenantCacheMu sync.Mutex
+)
+
+// errTenantUnavailable is a lookup that failed where there's no safe fallback, reported as 503
+var errTenantUnavailable = fmt.Errorf("Organization lookup is unavailable, please try again")
+
+// userTenant looks up the organization the user belongs to. Unlike timezones there's no safe
+// fallback in general, so a lookup that fails is an error. Lookups are cached for a minute.
+func userTenant(userID string) (Tenant, error) {
+	tenantCacheMu.Lock()
+	cached, ok := tenantCache[userID]
+	tenantCacheMu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.tenant, nil
+	}
+
+	tenant, err := fetchTenant(userID)
+	if err != nil {
+		return Tenant{}, err
+	}
+
+	tenantCacheMu.Lock()
+	tenantCache[userID] = cachedTenant{tenant: tenant, expires: time.Now().Add(tenantCacheTTL)}
+	tenantCacheMu.Unlock()
+	return tenant, nil
+}
+
+// inTeam reports whether the tenant's user is in the team
+func inTeam(tenant Tenant, teamID string) bool {
+	for _, id := range tenant.TeamIDs {
+		if id == teamID {
+			return true
+		}
+	}
+	return false
+}
+
+// memberOfTeam reports whether the user is in the team; if their organization can't be looked up
+// they aren't
+func memberOfTeam(userID, teamID string) bool {
+	tenant, err := userTenant(userID)
+	return err == nil && inTeam(tenant, teamID)
+}
+
+func fetchTenant(userID string) (Tenant, error) {
+	url := fmt.Sprintf("%s/api/orgs/tenant?user_id=%s", userServiceURL, url.QueryEscape(userID))
+
+	req, err := newServiceRequest("GET", url, nil)
+	if err != nil {
+		return Tenant{}, err
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Tenant{}, fmt.Errorf("failed to fetch tenant: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Tenant{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var tenant Tenant
+	if err := json.NewDecoder(resp.Body).Decode(&tenant); err != nil {
+		return Tenant{}, fmt.Errorf("failed to decode tenant: %w", err)
+	}
+	return tenant, nil
+}
+
+// moveToTenant follows the user into the organization they just joined, or out of the one they
+// left. Their organization's reminders, trashed ones included, become personal (and leave their
+// teams) when they leave;
+// on joining their personal reminders only move in if they agreed to it. Shares don't cross
+// organizations, so theirs go. user-service retries until this succeeds, so a change is only
+// applied while it's still the user's current one: a repeat or a stale retry does nothing.
+func moveToTenant(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req TenantChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, err := fetchTenant(userID)
+	if err != nil {
+		log.Printf("Error checking organization change for user %s: %v", userID, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": errTenantUnavailable.Error()})
+		return
+	}
+	tenantCacheMu.Lock()
+	tenantCache[userID] = cachedTenant{tenant: current, expires: time.Now().Add(tenantCacheTTL)}
+	tenantCacheMu.Unlock()
+	if current.OrgID != req.OrgID {
+		c.JSON(http.StatusOK, gin.H{"moved": 0, "stale": true})
+		return
+	}
+
+	var moved int64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if req.OrgID == "" || req.MoveReminders {
+			result := tx.Unscoped().Model(&Reminder{}).Where("user_id = ? AND org_id <> ?", userID, req.OrgID).
+				Updates(map[string]interface{}{"org_id": req.OrgID, "team_id": "", "version": gorm.Expr("version + 1")})
+			if result.Error != nil {
+				return result.Error
+			}
+			moved = result.RowsAffected
+		}
+		return tx.Where("owner_id = ? OR user_id = ?", userID, userID).Delete(&Share{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move reminders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"moved": moved})
+}
+
+// dissolveTenant makes every reminder in a deleted organization personal to its owner again, in
+// one go; user-service retries until it succeeds, and a repeat finds nothing left to move. Shares
+// between its members stay, as they're all personal now.
+func dissolveTenant(c *gin.Context) {
+	var req TenantDissolveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := db.Unscoped().Model(&Reminder{}).Where("org_id = ?", req.OrgID).
+		Updates(map[string]interface{}{"org_id": "", "team_id": "", "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move reminders"})
+		return
+	}
+
+	tenantCacheMu.Lock()
+	for userID, cached := range tenantCache {
+		if cached.tenant.OrgID == req.OrgID {
+			delete(tenantCache, userID)
+		}
+	}
+	tenantCacheMu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"moved": result.RowsAffected})
+}
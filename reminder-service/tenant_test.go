@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// useDryRunDB points db at a Postgres dialect that only builds SQL, never running it
+func useDryRunDB(t *testing.T) {
+	t.Helper()
+	dryRun, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("open dry-run db: %v", err)
+	}
+	previous := db
+	db = dryRun
+	t.Cleanup(func() { db = previous })
+}
+
+// useTenant caches the user's tenant so no lookup goes to user-service
+func useTenant(t *testing.T, userID string, tenant Tenant) {
+	t.Helper()
+	tenantCacheMu.Lock()
+	tenantCache[userID] = cachedTenant{tenant: tenant, expires: time.Now().Add(time.Hour)}
+	tenantCacheMu.Unlock()
+	t.Cleanup(func() {
+		tenantCacheMu.Lock()
+		delete(tenantCache, userID)
+		tenantCacheMu.Unlock()
+	})
+}
+
+// useUserService points tenant lookups at handler, or at nothing when it's nil
+func useUserService(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	previousURL := userServiceURL
+	userServiceURL = "http://127.0.0.1:1"
+	if handler != nil {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		userServiceURL = server.URL
+	}
+	t.Cleanup(func() { userServiceURL = previousURL })
+}
+
+func accessibleSQL(userID, permission string) (string, []interface{}) {
+	stmt := accessibleReminders(db.Model(&Reminder{}), userID, permission).Find(&[]Reminder{}).Statement
+	return stmt.SQL.String(), stmt.Vars
+}
+
+func TestAccessibleReminders(t *testing.T) {
+	useDryRunDB(t)
+	useTenant(t, "member", Tenant{OrgID: "o1", Role: "member", TeamIDs: []string{"t1", "t2"}})
+	useTenant(t, "admin", Tenant{OrgID: "o1", Role: orgRoleAdmin})
+	useTenant(t, "solo", Tenant{})
+	useUserService(t, nil)
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	tests := []struct {
+		name       string
+		userID     string
+		permission string
+		want       string
+		wantVars   []interface{}
+	}{
+		{"members see their teams' reminders", "member", permissionView,
+			"(user_id = $1 OR (org_id = $2 AND (team_id IN ($3,$4) OR id IN", []interface{}{"member", "o1", "t1", "t2"}},
+		{"teams don't grant edit", "member", permissionEdit,
+			"(user_id = $1 OR (org_id = $2 AND (team_id IN (NULL) OR id IN", []interface{}{"member", "o1", "member"}},
+		{"admins see team reminders, not private ones", "admin", permissionView,
+			"(user_id = $1 OR (org_id = $2 AND team_id <> ''))", []interface{}{"admin", "o1"}},
+		{"admins edit like anyone else", "admin", permissionEdit,
+			"(user_id = $1 OR (org_id = $2 AND (team_id IN (NULL) OR id IN", []interface{}{"admin", "o1", "admin"}},
+		{"personal users only get shares", "solo", permissionView,
+			"(user_id = $1 OR (org_id = $2 AND (team_id IN (NULL) OR id IN", []interface{}{"solo", "", "solo"}},
+		{"owners only", "member", permissionOwner,
+			"WHERE user_id = $1 AND", []interface{}{"member"}},
+		{"a failed lookup falls back to their own", "unknown", permissionView,
+			"WHERE user_id = $1 AND", []interface{}{"unknown"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, vars := accessibleSQL(tt.userID, tt.permission)
+			if !strings.Contains(sql, tt.want) {
+				t.Errorf("SQL = %s\nwant it to contain %s", sql, tt.want)
+			}
+			for i, want := range tt.wantVars {
+				if i >= len(vars) || vars[i] != want {
+					t.Errorf("vars = %v, want them to start %v", vars, tt.wantVars)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestHasPermissionTeams(t *testing.T) {
+	useTenant(t, "member", Tenant{OrgID: "o1", Role: "member", TeamIDs: []string{"t1"}})
+	useTenant(t, "admin", Tenant{OrgID: "o1", Role: orgRoleAdmin})
+
+	teamReminder := Reminder{ID: "r1", UserID: "owner", OrgID: "o1", TeamID: "t1"}
+	if !hasPermission(teamReminder, "member", permissionView) {
+		t.Error("team member can't view the team's reminder")
+	}
+	if !hasPermission(teamReminder, "admin", permissionView) {
+		t.Error("org admin can't view a team reminder")
+	}
+	if !hasPermission(teamReminder, "owner", permissionOwner) {
+		t.Error("owner lost their own reminder")
+	}
+}
+
+func TestInTeam(t *testing.T) {
+	tenant := Tenant{OrgID: "o1", TeamIDs: []string{"t1", "t2"}}
+	if !inTeam(tenant, "t2") || inTeam(tenant, "t3") || inTeam(Tenant{}, "t1") {
+		t.Error("inTeam gave the wrong answer")
+	}
+}
+
+func TestCreateReminderTenantUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		team string
+		want int
+	}{
+		{"personal, saved without the organization", "", http.StatusCreated},
+		{"for a team, which can't be checked", "t1", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeDB(t)
+			useUserService(t, nil)
+
+			body := `{"title": "Dentist", "datetime": "2099-03-02T09:00:00Z", "notification_type": "email", "email": "me@example.com", "team_id": "` + tt.team + `"}`
+			w := serve(createReminder, "POST", "", body, map[string]string{"X-User-ID": "lookup-fails"})
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			inserts := fake.executed(`INSERT INTO "reminders"`)
+			if tt.want != http.StatusCreated {
+				if len(inserts) != 0 {
+					t.Errorf("a team reminder was saved unchecked: %v", inserts)
+				}
+				return
+			}
+			if reminder := decodeReminder(t, w); len(inserts) != 1 || reminder.OrgID != "" || reminder.TeamID != "" {
+				t.Errorf("saved %+v, want a personal reminder", reminder)
+			}
+		})
+	}
+}
+
+func TestMoveToTenantOnlyCurrent(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		moved bool
+	}{
+		{"the user's current organization", `{"org_id": "o2", "move_reminders": true}`, true},
+		{"a repeat is harmless", `{"org_id": "o2", "move_reminders": true}`, true},
+		{"a stale change", `{"org_id": "o1", "move_reminders": true}`, false},
+		{"a stale leave", `{"org_id": ""}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeDB(t)
+			useUserService(t, func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(Tenant{OrgID: "o2", TeamIDs: []string{}})
+			})
+			t.Cleanup(func() {
+				tenantCacheMu.Lock()
+				delete(tenantCache, "u1")
+				tenantCacheMu.Unlock()
+			})
+
+			w := serve(moveToTenant, "POST", "", tt.body, map[string]string{"X-User-ID": "u1", "X-Service-Name": "user-service"})
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+			}
+			moved := len(fake.executed(`UPDATE "reminders" SET "org_id"=$1`)) == 1
+			if moved != tt.moved {
+				t.Errorf("moved = %v, want %v: %v", moved, tt.moved, fake.statements)
+			}
+			if tenant, err := userTenant("u1"); err != nil || tenant.OrgID != "o2" {
+				t.Errorf("cached tenant = %+v, %v; want the current one", tenant, err)
+			}
+		})
+	}
+}
+
+func TestMoveToTenantLookupFails(t *testing.T) {
+	fake := useFakeDB(t)
+	useUserService(t, nil)
+
+	w := serve(moveToTenant, "POST", "", `{"org_id": ""}`, map[string]string{"X-User-ID": "u1", "X-Service-Name": "user-service"})
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503 so user-service retries", w.Code)
+	}
+	if len(fake.statements) != 0 {
+		t.Errorf("moved without checking: %v", fake.statements)
+	}
+}
@@ -0,0 +1,97 @@
+// timezone.go - User timezones, from user-service preferences
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const timezoneCacheTTL = 5 * time.Minute
+
+type cachedTimezone struct {
+	loc     *time.Location
+	expires time.Time
+}
+
+var (
+	timezoneCache   = make(map[string]cachedTimezone)
+	timezoneCacheMu sync.Mutex
+)
+
+// resolveTimezone picks the zone to read local times in: the one named in the request if any,
+// else the user's preference
+func resolveTimezone(name, userID string) (*time.Location, error) {
+	if name != "" {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid timezone %q", name)
+		}
+		return loc, nil
+	}
+	return userTimezone(userID), nil
+}
+
+// userTimezone looks up the user's timezone preference, falling back to UTC if user-service
+// can't be reached. Lookups are cached for a few minutes.
+func userTimezone(userID string) *time.Location {
+	timezoneCacheMu.Lock()
+	cached, ok := timezoneCache[userID]
+	timezoneCacheMu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.loc
+	}
+
+	loc, err := fetchUserTimezone(userID)
+	if err != nil {
+		log.Printf("Error fetching timezone for user %s, using UTC: %v", userID, err)
+		return time.UTC
+	}
+
+	timezoneCacheMu.Lock()
+	timezoneCache[userID] = cachedTimezone{loc: loc, expires: time.Now().Add(timezoneCacheTTL)}
+	timezoneCacheMu.Unlock()
+	return loc
+}
+
+func fetchUserTimezone(userID string) (*time.Location, error) {
+	url := fmt.Sprintf("%s/api/preferences?user_id=%s", userServiceURL, url.QueryEscape(userID))
+
+	req, err := newServiceRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch preferences: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var prefs struct {
+		Timezone string `json:"timezone"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&prefs); err != nil {
+		return nil, fmt.Errorf("failed to decode preferences: %w", err)
+	}
+	if prefs.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(prefs.Timezone)
+}
+
+// forgetUserTimezone drops a cached preference after the user changes it
+func forgetUserTimezone(userID string) {
+	timezoneCacheMu.Lock()
+	delete(timezoneCache, userID)
+	timezoneCacheMu.Unlock()
+}
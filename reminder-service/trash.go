@@ -0,0 +1,169 @@
+// trash.go - Soft-deleted reminders: trash, restore, purge and retention
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const defaultTrashRetentionDays = 30
+
+// listTrash lists the user's deleted reminders, most recently deleted first
+func listTrash(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var reminders []Reminder
+	err := db.Unscoped().Preload("Tags").
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at desc, id asc").
+		Limit(maxPageSize).
+		Find(&reminders).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trash"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reminders)
+}
+
+func restoreReminder(c *gin.Context) {
+	var reminder Reminder
+	err := db.Unscoped().
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", c.Param("id"), getUserID(c)).
+		First(&reminder).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found in trash"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminder"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		reminder.Version++
+		err := tx.Unscoped().Model(&reminder).Updates(map[string]interface{}{"deleted_at": nil, "version": reminder.Version}).Error
+		if err != nil {
+			return err
+		}
+		return recordHistory(tx, auditFrom(c), "restore", nil, &reminder)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore reminder"})
+		return
+	}
+
+	db.Preload("Tags").First(&reminder, "id = ?", reminder.ID)
+
+	c.JSON(http.StatusOK, reminder)
+}
+
+// purgeReminder deletes one reminder from the trash for good
+func purgeReminder(c *gin.Context) {
+	purged, err := purgeReminders(auditFrom(c), "id = ? AND user_id = ? AND deleted_at IS NOT NULL", c.Param("id"), getUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge reminder"})
+		return
+	}
+
+	if purged == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found in trash"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reminder permanently deleted"})
+}
+
+// emptyTrash permanently deletes everything in the user's trash
+func emptyTrash(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	purged, err := purgeReminders(auditFrom(c), "user_id = ? AND deleted_at IS NOT NULL", userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to empty trash"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Trash emptied", "count": purged})
+}
+
+// purgeReminders hard-deletes the reminders matching the condition, along with their tag links and shares.
+// Their history stays.
+func purgeReminders(audit auditInfo, condition string, args ...interface{}) (int64, error) {
+	var purged int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var reminders []Reminder
+		if err := tx.Unscoped().Where(condition, args...).Find(&reminders).Error; err != nil {
+			return err
+		}
+		if len(reminders) == 0 {
+			return nil
+		}
+
+		ids := make([]string, 0, len(reminders))
+		for i := range reminders {
+			ids = append(ids, reminders[i].ID)
+			if err := recordHistory(tx, audit, "purge", &reminders[i], nil); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Exec("DELETE FROM reminder_tags WHERE reminder_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("reminder_id IN ?", ids).Delete(&Share{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where("id IN ?", ids).Delete(&Reminder{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
+// runTrashRetention purges reminders that have been in the trash longer than
+// TRASH_RETENTION_DAYS (default 30; 0 keeps them forever). Checks hourly.
+func runTrashRetention() {
+	days := defaultTrashRetentionDays
+	if value := os.Getenv("TRASH_RETENTION_DAYS"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			log.Printf("Invalid TRASH_RETENTION_DAYS %q, using %d", value, defaultTrashRetentionDays)
+		} else {
+			days = n
+		}
+	}
+	if days == 0 {
+		log.Println("Trash retention disabled")
+		return
+	}
+
+	log.Printf("Purging trash older than %d days", days)
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		cutoff := time.Now().AddDate(0, 0, -days)
+		purged, err := purgeReminders(systemAudit, "deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+		if err != nil {
+			log.Printf("Error purging trash: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d reminders from trash", purged)
+		}
+		<-ticker.C
+	}
+}
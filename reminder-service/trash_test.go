@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func trashFixtures() []Reminder {
+	deleted := gorm.DeletedAt{Time: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Valid: true}
+	return []Reminder{
+		{ID: "r1", UserID: "u1", Title: "Old", Status: StatusSent, Version: 2, DeletedAt: deleted},
+		{ID: "r2", UserID: "u1", Title: "Older", Status: StatusCancelled, Version: 5, DeletedAt: deleted},
+	}
+}
+
+func TestListTrash(t *testing.T) {
+	fake := useFakeDB(t)
+	fake.returns("reminders", trashFixtures())
+
+	w := serve(listTrash, "GET", "", "", map[string]string{"X-User-ID": "u1"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var reminders []Reminder
+	json.Unmarshal(w.Body.Bytes(), &reminders)
+	if len(reminders) != 2 {
+		t.Errorf("got %d reminders, want 2", len(reminders))
+	}
+
+	selects := fake.executed(`SELECT * FROM "reminders"`)
+	if len(selects) != 1 {
+		t.Fatalf("selects = %v", fake.statements)
+	}
+	sql := selects[0].SQL
+	if !strings.Contains(sql, "user_id = $1 AND deleted_at IS NOT NULL") || strings.Contains(sql, `"reminders"."deleted_at" IS NULL`) ||
+		!strings.Contains(sql, "ORDER BY deleted_at desc") {
+		t.Errorf("SQL = %s, want the user's deleted reminders, newest deletion first", sql)
+	}
+
+	if w := serve(listTrash, "GET", "", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("without a user: status = %d, want 400", w.Code)
+	}
+}
+
+func TestRestoreReminder(t *testing.T) {
+	fake := useFakeDB(t)
+	fake.returns("reminders", trashFixtures()[:1])
+
+	w := serve(restoreReminder, "POST", "r1", "", map[string]string{"X-User-ID": "u1"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+
+	updates := fake.executed(`UPDATE "reminders" SET "deleted_at"=$1,"version"=$2`)
+	if len(updates) != 1 || updates[0].Vars[0] != nil || updates[0].Vars[1] != 3 {
+		t.Errorf("updates = %v, want deleted_at cleared and version 3", fake.statements)
+	}
+	history := fake.executed(`INSERT INTO "reminder_histories"`)
+	if len(history) != 1 || history[0].Vars[3] != "restore" {
+		t.Errorf("history = %v, want a restore entry", history)
+	}
+}
+
+func TestRestoreReminderNotInTrash(t *testing.T) {
+	fake := useFakeDB(t)
+
+	if w := serve(restoreReminder, "POST", "r1", "", map[string]string{"X-User-ID": "u1"}); w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+	selects := fake.executed(`SELECT * FROM "reminders"`, "user_id = $2 AND deleted_at IS NOT NULL")
+	if len(selects) != 1 || selects[0].Vars[1] != "u1" {
+		t.Errorf("restore didn't look only in the user's own trash: %v", fake.statements)
+	}
+	if len(fake.executed("UPDATE")) != 0 {
+		t.Error("something was restored")
+	}
+}
+
+func TestPurgeReminders(t *testing.T) {
+	fake := useFakeDB(t)
+	fake.returns("reminders", trashFixtures())
+	fake.affected = func(stmt *gorm.Statement) int64 {
+		if strings.HasPrefix(stmt.SQL.String(), `DELETE FROM "reminders"`) {
+			return 2
+		}
+		return 1
+	}
+
+	w := serve(emptyTrash, "DELETE", "", "", map[string]string{"X-User-ID": "u1"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Count int64 `json:"count"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Count != 2 {
+		t.Errorf("count = %d, want 2", body.Count)
+	}
+
+	history := fake.executed(`INSERT INTO "reminder_histories"`)
+	if len(history) != 2 || history[0].Vars[3] != "purge" || history[1].Vars[3] != "purge" {
+		t.Errorf("history = %v, want a purge entry per reminder", history)
+	}
+	for _, fragment := range []string{"DELETE FROM reminder_tags WHERE reminder_id IN", `DELETE FROM "shares" WHERE reminder_id IN`, `DELETE FROM "reminders" WHERE id IN`} {
+		if len(fake.executed(fragment)) != 1 {
+			t.Errorf("missing %s in %v", fragment, fake.statements)
+		}
+	}
+	if len(fake.executed(`DELETE FROM "reminder_histories"`)) != 0 {
+		t.Error("history was deleted with the reminders")
+	}
+	if len(fake.executed("COMMIT")) != 1 {
+		t.Error("purge wasn't committed")
+	}
+}
+
+func TestPurgeReminderNotInTrash(t *testing.T) {
+	fake := useFakeDB(t)
+
+	if w := serve(purgeReminder, "DELETE", "r1", "", map[string]string{"X-User-ID": "u1"}); w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+	if len(fake.executed("DELETE")) != 0 {
+		t.Error("deleted something that wasn't in the trash")
+	}
+}
@@ -0,0 +1,207 @@
+// wallclock.go - Wall-clock reminder times in a fixed or floating timezone
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/teambition/rrule-go"
+	"gorm.io/gorm"
+)
+
+// A reminder's Timezone says how its time is kept:
+//   - "" - an absolute instant (DateTime), as before
+//   - an IANA zone such as "Europe/Berlin" - a wall-clock time in that zone
+//   - "floating" - a wall-clock time in whatever zone the user is in now
+//
+// Wall-clock reminders keep LocalTime, and their occurrences are worked out from it so that
+// "9am daily" stays at 9am across DST changes and, when floating, when the user travels.
+const (
+	floatingTimezone = "floating"
+	localTimeLayout  = "2006-01-02T15:04:05"
+)
+
+// validateTimezone checks a reminder timezone: empty, floating or an IANA zone
+func validateTimezone(name string) error {
+	if name == "" || name == floatingTimezone {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("Invalid timezone %q", name)
+	}
+	return nil
+}
+
+// reminderLocation is the zone a reminder's wall-clock time is read in
+func reminderLocation(reminder Reminder) *time.Location {
+	switch reminder.Timezone {
+	case "":
+		return time.UTC
+	case floatingTimezone:
+		return userTimezone(reminder.UserID)
+	}
+	loc, err := time.LoadLocation(reminder.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// parseReminderTime reads a datetime given with a reminder: RFC3339, or for a wall-clock
+// reminder also a local time without an offset ("2026-03-08T09:00:00"), read in loc
+func parseReminderTime(value string, loc *time.Location) (time.Time, error) {
+	if datetime, err := time.Parse(time.RFC3339, value); err == nil {
+		return datetime, nil
+	}
+	if loc != nil {
+		if wall, err := time.Parse(localTimeLayout, value); err == nil {
+			return inZone(wall, loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Invalid datetime format. Use RFC3339")
+}
+
+// syncLocalTime records the wall-clock time of a reminder's anchor - the series start for a
+// repeating reminder, else its due time - after either has moved. Instant reminders keep none.
+func syncLocalTime(reminder *Reminder) {
+	if reminder.Timezone == "" {
+		reminder.LocalTime = ""
+		return
+	}
+
+	anchor := reminder.DateTime
+	if reminder.Recurrence != "" && reminder.RecurrenceStart != nil {
+		anchor = *reminder.RecurrenceStart
+	}
+	reminder.LocalTime = anchor.In(reminderLocation(*reminder)).Format(localTimeLayout)
+}
+
+// wallClock returns t's date and clock reading, as a UTC time
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// inZone turns a wall-clock reading (given as UTC) into an instant in loc, following RFC 5545:
+// a time skipped by a spring-forward gap moves forward by the length of the gap (02:30 becomes
+// 03:30), and a time repeated by a fall-back overlap is the first of the two.
+func inZone(wall time.Time, loc *time.Location) time.Time {
+	// The offsets either side of any transition near this time (they're never closer than a day)
+	_, offsetBefore := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, offsetAfter := wall.Add(24 * time.Hour).In(loc).Zone()
+
+	before := wall.Add(-time.Duration(offsetBefore) * time.Second).In(loc)
+	after := wall.Add(-time.Duration(offsetAfter) * time.Second).In(loc)
+	beforeValid := wallClock(before).Equal(wall)
+	afterValid := wallClock(after).Equal(wall)
+
+	switch {
+	case beforeValid && afterValid:
+		if after.Before(before) {
+			return after
+		}
+		return before
+	case afterValid:
+		return after
+	default:
+		// Valid on the old offset, or in the gap, where the old offset carries it past the gap
+		return before
+	}
+}
+
+// nextWallClockOccurrence is nextOccurrence for a wall-clock reminder: the rule runs over local
+// times from LocalTime, and each is placed in the reminder's zone
+func nextWallClockOccurrence(reminder Reminder, opt *rrule.ROption, after time.Time) (time.Time, bool) {
+	start, err := time.Parse(localTimeLayout, reminder.LocalTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+	opt.Dtstart = start
+
+	rule, err := rrule.NewRRule(*opt)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// Start a few hours early: gaps and overlaps move local times by up to an hour either way
+	loc := reminderLocation(reminder)
+	wall := rule.After(wallClock(after.In(loc)).Add(-3*time.Hour), true)
+	for !wall.IsZero() {
+		if next := inZone(wall, loc); next.After(after) {
+			return next, true
+		}
+		wall = rule.After(wall, false)
+	}
+	return time.Time{}, false
+}
+
+// TimezoneChangeRequest DTO - sent by user-service when a user's timezone preference changes
+type TimezoneChangeRequest struct {
+	PreviousTimezone string `json:"previous_timezone"`
+	Timezone         string `json:"timezone" binding:"required"`
+}
+
+// moveFloatingReminders keeps the user's floating reminders at the same wall-clock time in
+// their new zone: 9am in New York becomes 9am in London
+func moveFloatingReminders(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req TimezoneChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	previous, err := time.LoadLocation(req.PreviousTimezone) // UTC if they had none
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid previous_timezone"})
+		return
+	}
+	current, err := time.LoadLocation(req.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+	forgetUserTimezone(userID)
+
+	var reminders []Reminder
+	if err := db.Where("user_id = ? AND timezone = ? AND status IN ?", userID, floatingTimezone,
+		[]string{StatusPending, StatusSnoozed}).Find(&reminders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminders"})
+		return
+	}
+
+	audit := auditFrom(c)
+	moved := 0
+	for _, reminder := range reminders {
+		before := reminder
+		reminder.DateTime = inZone(wallClock(reminder.DateTime.In(previous)), current)
+		if reminder.RecurrenceStart != nil {
+			start := inZone(wallClock(reminder.RecurrenceStart.In(previous)), current)
+			reminder.RecurrenceStart = &start
+		}
+		if reminder.DateTime.Equal(before.DateTime) {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := updateVersioned(tx, &reminder, "date_time", "recurrence_start"); err != nil {
+				return err
+			}
+			return recordHistory(tx, audit, "update", &before, &reminder)
+		})
+		if err != nil {
+			// Most likely written meanwhile; it keeps its old time
+			log.Printf("Error moving reminder %s to %s: %v", reminder.ID, current, err)
+			continue
+		}
+		moved++
+	}
+
+	c.JSON(http.StatusOK, gin.H{"moved": moved})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// In 2026 New York springs forward on March 8 (02:00 EST -> 03:00 EDT)
+// and falls back on November 1 (02:00 EDT -> 01:00 EST)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	return loc
+}
+
+func TestInZone(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name string
+		wall time.Time
+		want string
+	}{
+		{"ordinary time", time.Date(2026, 3, 7, 9, 0, 0, 0, time.UTC), "2026-03-07T09:00:00-05:00"},
+		{"spring-forward gap moves forward", time.Date(2026, 3, 8, 2, 30, 0, 0, time.UTC), "2026-03-08T03:30:00-04:00"},
+		{"just after the gap", time.Date(2026, 3, 8, 3, 0, 0, 0, time.UTC), "2026-03-08T03:00:00-04:00"},
+		{"fall-back overlap takes the first", time.Date(2026, 11, 1, 1, 30, 0, 0, time.UTC), "2026-11-01T01:30:00-04:00"},
+		{"just after the overlap", time.Date(2026, 11, 1, 2, 0, 0, 0, time.UTC), "2026-11-01T02:00:00-05:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inZone(tt.wall, newYork).Format(time.RFC3339); got != tt.want {
+				t.Errorf("inZone(%s) = %s, want %s", tt.wall.Format(localTimeLayout), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextOccurrenceAcrossDST(t *testing.T) {
+	mustLoadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name      string
+		localTime string
+		after     time.Time
+		want      []string
+	}{
+		{
+			name:      "9am daily across spring-forward",
+			localTime: "2026-03-01T09:00:00",
+			after:     time.Date(2026, 3, 6, 15, 0, 0, 0, time.UTC),
+			want:      []string{"2026-03-07T09:00:00-05:00", "2026-03-08T09:00:00-04:00", "2026-03-09T09:00:00-04:00"},
+		},
+		{
+			name:      "9am daily across fall-back",
+			localTime: "2026-10-25T09:00:00",
+			after:     time.Date(2026, 10, 30, 14, 0, 0, 0, time.UTC),
+			want:      []string{"2026-10-31T09:00:00-04:00", "2026-11-01T09:00:00-05:00", "2026-11-02T09:00:00-05:00"},
+		},
+		{
+			name:      "2:30am daily moves past the gap only on the day it's skipped",
+			localTime: "2026-03-01T02:30:00",
+			after:     time.Date(2026, 3, 6, 8, 0, 0, 0, time.UTC),
+			want:      []string{"2026-03-07T02:30:00-05:00", "2026-03-08T03:30:00-04:00", "2026-03-09T02:30:00-04:00"},
+		},
+		{
+			name:      "1:30am daily fires once on the repeated hour",
+			localTime: "2026-10-25T01:30:00",
+			after:     time.Date(2026, 10, 31, 6, 0, 0, 0, time.UTC),
+			want:      []string{"2026-11-01T01:30:00-04:00", "2026-11-02T01:30:00-05:00", "2026-11-03T01:30:00-05:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reminder := Reminder{Recurrence: "FREQ=DAILY", Timezone: "America/New_York", LocalTime: tt.localTime}
+
+			after := tt.after
+			for _, want := range tt.want {
+				next, ok := nextOccurrence(reminder, after)
+				if !ok {
+					t.Fatalf("nextOccurrence after %s: series ended, want %s", after.Format(time.RFC3339), want)
+				}
+				if got := next.Format(time.RFC3339); got != want {
+					t.Fatalf("nextOccurrence after %s = %s, want %s", after.Format(time.RFC3339), got, want)
+				}
+				after = next
+			}
+		})
+	}
+}
+
+func TestNextOccurrenceFixedInstant(t *testing.T) {
+	// Reminders without a timezone keep their absolute interval, as before
+	start := time.Date(2026, 3, 7, 14, 0, 0, 0, time.UTC)
+	reminder := Reminder{Recurrence: "FREQ=DAILY", DateTime: start, RecurrenceStart: &start}
+
+	next, ok := nextOccurrence(reminder, start)
+	if !ok || !next.Equal(start.Add(24*time.Hour)) {
+		t.Errorf("nextOccurrence = %s, %v, want %s", next, ok, start.Add(24*time.Hour))
+	}
+}
@@ -0,0 +1,188 @@
+// catch_up.go - Catch-up policy for reminders found long past due (e.g. after the scheduler was down)
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Catch-up policies (matches reminder and user services)
+const (
+	catchUpFire   = "fire"   // send however late
+	catchUpGrace  = "grace"  // send if no later than the grace window, else expire
+	catchUpExpire = "expire" // send only on time, else expire
+)
+
+const (
+	defaultCatchUp      = catchUpFire
+	defaultGraceMinutes = 60
+	onTimeTolerance     = 5 * time.Minute // lateness from polling and retries, which isn't a miss
+)
+
+// UserPreferences structure (matches user service)
+type UserPreferences struct {
+	Timezone     string `json:"timezone"`
+	CatchUp      string `json:"catch_up"`
+	GraceMinutes int    `json:"grace_minutes"`
+	Email        string `json:"email,omitempty"`
+	Phone        string `json:"phone,omitempty"`
+}
+
+// missedReminder reports whether a due reminder is too late to send under its catch-up policy:
+// the reminder's own, else the user's default. Lateness counts from the due time, or from when it
+// was last put back in the queue by hand (retried, reinstated) if later, so that still goes out.
+// Other edits don't restart the clock.
+func missedReminder(reminder Reminder, prefs UserPreferences, now time.Time) bool {
+	policy, grace := reminder.CatchUp, reminder.GraceMinutes
+	if policy == "" {
+		policy = prefs.CatchUp
+	}
+	if grace == 0 {
+		grace = prefs.GraceMinutes
+	}
+
+	overdueSince := reminder.DateTime
+	if reminder.RequeuedAt != nil && reminder.RequeuedAt.After(overdueSince) {
+		overdueSince = *reminder.RequeuedAt
+	}
+	late := now.Sub(overdueSince)
+	switch policy {
+	case catchUpFire:
+		return false
+	case catchUpExpire:
+		return late > onTimeTolerance
+	default:
+		if grace <= 0 {
+			grace = defaultGraceMinutes
+		}
+		return late > time.Duration(grace)*time.Minute
+	}
+}
+
+// expireReminder marks a missed reminder expired; a repeating one moves on to its next occurrence
+func expireReminder(reminder Reminder) error {
+	return updateReminderStatus(reminder.ID, reminder.Status, "expired")
+}
+
+// preferencesCache holds user preferences for one scheduler pass
+type preferencesCache map[string]UserPreferences
+
+// get returns the user's preferences, or the defaults if user-service can't be reached
+func (cache preferencesCache) get(userID string) UserPreferences {
+	if prefs, ok := cache[userID]; ok {
+		return prefs
+	}
+
+	prefs, err := fetchPreferences(userID)
+	if err != nil {
+		log.Printf("Error fetching preferences for user %s, using defaults: %v", userID, err)
+		prefs = UserPreferences{}
+	}
+	if prefs.CatchUp == "" {
+		prefs.CatchUp = defaultCatchUp
+	}
+	if prefs.GraceMinutes == 0 {
+		prefs.GraceMinutes = defaultGraceMinutes
+	}
+	cache[userID] = prefs
+	return prefs
+}
+
+func fetchPreferences(userID string) (UserPreferences, error) {
+	url := fmt.Sprintf("%s/api/preferences?user_id=%s", userServiceURL, url.QueryEscape(userID))
+
+	req, err := newServiceRequest("GET", url, nil)
+	if err != nil {
+		return UserPreferences{}, err
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return UserPreferences{}, fmt.Errorf("failed to fetch preferences: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return UserPreferences{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var prefs UserPreferences
+	if err := json.NewDecoder(resp.Body).Decode(&prefs); err != nil {
+		return UserPreferences{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return prefs, nil
+}
+
+// sendMissedSummaries tells each recipient, in one message, which of their reminders expired
+// unsent, rather than sending each one late
+func sendMissedSummaries(missed []Reminder, prefs preferencesCache) {
+	type recipientKey struct{ channel, address string }
+	summaries := make(map[recipientKey][]Reminder)
+	var order []recipientKey
+
+	for _, reminder := range missed {
+		recipients, err := resolveRecipients(reminder)
+		if err != nil {
+			log.Printf("Error resolving recipients for missed reminder %s: %v", reminder.ID, err)
+			continue
+		}
+		for _, recipient := range recipients {
+			key := recipientKey{recipient.Channel, recipient.Address}
+			if _, ok := summaries[key]; !ok {
+				order = append(order, key)
+			}
+			summaries[key] = append(summaries[key], reminder)
+		}
+	}
+
+	for _, key := range order {
+		reminders := summaries[key]
+		sort.Slice(reminders, func(i, j int) bool { return reminders[i].DateTime.Before(reminders[j].DateTime) })
+
+		loc, err := time.LoadLocation(prefs.get(reminders[0].UserID).Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		title, description := missedSummary(reminders, loc)
+		message := NotificationMessage{
+			Title:            title,
+			Description:      description,
+			DateTime:         time.Now(),
+			NotificationType: key.channel,
+			Priority:         "normal",
+			OrgID:            reminders[0].OrgID,
+		}
+		if key.channel == "email" {
+			message.Email = key.address
+		} else {
+			message.Phone = key.address
+		}
+
+		if err := publishNotification(message); err != nil {
+			log.Printf("Error sending missed reminders summary: %v", err)
+		}
+	}
+}
+
+// missedSummary is the title and text of a missed reminders summary, one line per reminder with
+// its due time in loc (the email keeps the line breaks)
+func missedSummary(reminders []Reminder, loc *time.Location) (string, string) {
+	lines := make([]string, 0, len(reminders))
+	for _, reminder := range reminders {
+		lines = append(lines, fmt.Sprintf("- %s (due %s)", reminder.Title, reminder.DateTime.In(loc).Format("Mon Jan 2 15:04 MST")))
+	}
+
+	title := "You missed 1 reminder"
+	if len(reminders) > 1 {
+		title = fmt.Sprintf("You missed %d reminders", len(reminders))
+	}
+	return title, "These reminders came due while they couldn't be sent, and were skipped:\n" + strings.Join(lines, "\n")
+}
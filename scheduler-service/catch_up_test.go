@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMissedReminder(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	hoursAgo := func(h float64) time.Time { return now.Add(-time.Duration(h * float64(time.Hour))) }
+	requeued := hoursAgo(0.5)
+	defaults := UserPreferences{CatchUp: defaultCatchUp, GraceMinutes: defaultGraceMinutes}
+
+	tests := []struct {
+		name     string
+		reminder Reminder
+		prefs    UserPreferences
+		want     bool
+	}{
+		{"default fires however late", Reminder{DateTime: hoursAgo(48)}, defaults, false},
+		{"expire, on time", Reminder{DateTime: hoursAgo(0.05), CatchUp: catchUpExpire}, defaults, false},
+		{"expire, late", Reminder{DateTime: hoursAgo(1), CatchUp: catchUpExpire}, defaults, true},
+		{"grace, within", Reminder{DateTime: hoursAgo(0.5), CatchUp: catchUpGrace}, defaults, false},
+		{"grace, past", Reminder{DateTime: hoursAgo(2), CatchUp: catchUpGrace}, defaults, true},
+		{"grace, own window", Reminder{DateTime: hoursAgo(2), CatchUp: catchUpGrace, GraceMinutes: 180}, defaults, false},
+		{"user's policy", Reminder{DateTime: hoursAgo(2)}, UserPreferences{CatchUp: catchUpGrace, GraceMinutes: 30}, true},
+		{"reminder's policy over the user's", Reminder{DateTime: hoursAgo(2), CatchUp: catchUpFire}, UserPreferences{CatchUp: catchUpExpire}, false},
+		{"retried, counts from the retry", Reminder{DateTime: hoursAgo(48), CatchUp: catchUpGrace, RequeuedAt: &requeued}, defaults, false},
+		{"edited, still counts from the due time", Reminder{DateTime: hoursAgo(48), CatchUp: catchUpGrace, UpdatedAt: hoursAgo(0.1)}, defaults, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missedReminder(tt.reminder, tt.prefs, now); got != tt.want {
+				t.Errorf("missedReminder = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissedSummary(t *testing.T) {
+	loc := mustLoadLocation(t, "Europe/Berlin")
+	reminders := []Reminder{
+		{Title: "Dentist", DateTime: time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)},
+		{Title: "Call Ann", DateTime: time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)},
+	}
+
+	title, description := missedSummary(reminders, loc)
+	if title != "You missed 2 reminders" {
+		t.Errorf("title = %q", title)
+	}
+	lines := strings.Split(description, "\n")
+	if len(lines) != 3 || lines[1] != "- Dentist (due Mon Mar 2 09:00 CET)" || lines[2] != "- Call Ann (due Mon Mar 2 10:30 CET)" {
+		t.Errorf("description = %q, want a line per reminder in local time", description)
+	}
+
+	if title, _ := missedSummary(reminders[:1], loc); title != "You missed 1 reminder" {
+		t.Errorf("title for one = %q", title)
+	}
+}
@@ -0,0 +1,237 @@
+// digest.go - Daily and weekly digests: one message listing a user's reminders for the period
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DigestSubscription structure (matches user service's due digests)
+type DigestSubscription struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Frequency string    `json:"frequency"` // daily or weekly
+	Timezone  string    `json:"timezone"`
+	Channel   string    `json:"channel"`
+	Email     string    `json:"email,omitempty"`
+	Phone     string    `json:"phone,omitempty"`
+	DueAt     time.Time `json:"due_at"` // start of the period the digest covers
+}
+
+// DigestItem is one reminder listed in a digest (matches notification service)
+type DigestItem struct {
+	ReminderID  string    `json:"reminder_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	DateTime    time.Time `json:"datetime"`
+	Priority    string    `json:"priority"`
+}
+
+// processDigests sends every digest that's due. It runs before the regular pass so reminders due
+// right at digest time go out in the digest rather than on their own.
+func processDigests() {
+	digests, err := fetchDueDigests()
+	if err != nil {
+		log.Printf("Error fetching due digests: %v", err)
+		return
+	}
+
+	for _, digest := range digests {
+		if err := sendDigest(digest); err != nil {
+			log.Printf("Error sending digest %s: %v", digest.ID, err)
+			continue
+		}
+		if err := markDigestSent(digest); err != nil {
+			log.Printf("Error marking digest %s sent: %v", digest.ID, err)
+		}
+	}
+}
+
+// sendDigest claims the subscription's reminders for the digest's period and publishes them as one message
+func sendDigest(digest DigestSubscription) error {
+	from, to := digestPeriod(digest)
+	reminders, err := claimDigestReminders(digest, from, to)
+	if err != nil {
+		return err
+	}
+	if len(reminders) == 0 {
+		log.Printf("Digest %s has no reminders, skipping", digest.ID)
+		return nil
+	}
+
+	if err := publishNotification(digestMessage(digest, reminders)); err != nil {
+		// Put the reminders back so they still go out, one by one
+		for _, reminder := range reminders {
+			if releaseErr := updateReminderStatus(reminder.ID, "processing", "pending"); releaseErr != nil {
+				log.Printf("Error releasing reminder %s from digest: %v", reminder.ID, releaseErr)
+			}
+		}
+		return err
+	}
+
+	for _, reminder := range reminders {
+		err := updateReminder(reminder.ID, map[string]interface{}{
+			"status":          "sent",
+			"expected_status": "processing",
+			"delivered_by":    "digest",
+		})
+		if err != nil {
+			log.Printf("Error marking reminder %s delivered by digest: %v", reminder.ID, err)
+		}
+	}
+
+	log.Printf("Digest %s sent with %d reminder(s)", digest.ID, len(reminders))
+	return nil
+}
+
+// digestPeriod is the day or week a digest covers, starting at its due time in the subscription's zone
+func digestPeriod(digest DigestSubscription) (time.Time, time.Time) {
+	from := digest.DueAt.In(digestLocation(digest))
+	if digest.Frequency == "weekly" {
+		return from, from.AddDate(0, 0, 7)
+	}
+	return from, from.AddDate(0, 0, 1)
+}
+
+func digestLocation(digest DigestSubscription) *time.Location {
+	loc, err := time.LoadLocation(digest.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// digestMessage lists the claimed reminders, in the order claimed (by due time), as one message
+// to the subscriber
+func digestMessage(digest DigestSubscription, reminders []Reminder) NotificationMessage {
+	loc := digestLocation(digest)
+	from := digest.DueAt.In(loc)
+	title := fmt.Sprintf("Your reminders for %s", from.Format("Monday, January 2"))
+	if digest.Frequency == "weekly" {
+		title = fmt.Sprintf("Your reminders for the week of %s", from.Format("January 2"))
+	}
+
+	message := NotificationMessage{
+		DigestID:         digest.ID,
+		Template:         "digest",
+		Title:            title,
+		DateTime:         digest.DueAt,
+		NotificationType: digest.Channel,
+		Email:            digest.Email,
+		Phone:            digest.Phone,
+		Priority:         "normal",
+		Timezone:         loc.String(),
+		OrgID:            reminders[0].OrgID,
+	}
+	for _, reminder := range reminders {
+		message.Items = append(message.Items, DigestItem{
+			ReminderID:  reminder.ID,
+			Title:       reminder.Title,
+			Description: reminder.Description,
+			DateTime:    reminder.DateTime,
+			Priority:    reminder.Priority,
+		})
+	}
+	return message
+}
+
+func fetchDueDigests() ([]DigestSubscription, error) {
+	url := fmt.Sprintf("%s/api/digests/due", userServiceURL)
+
+	req, err := newServiceRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch digests: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var digests []DigestSubscription
+	if err := json.NewDecoder(resp.Body).Decode(&digests); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return digests, nil
+}
+
+// claimDigestReminders claims the reminders due in the period that go only to the subscription's address
+func claimDigestReminders(digest DigestSubscription, from, to time.Time) ([]Reminder, error) {
+	address := digest.Email
+	if digest.Channel == "sms" {
+		address = digest.Phone
+	}
+
+	url := fmt.Sprintf("%s/api/reminders/digest?user_id=%s", reminderServiceURL, url.QueryEscape(digest.UserID))
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"digest_id": digest.ID,
+		"channel":   digest.Channel,
+		"address":   address,
+		"from":      from,
+		"to":        to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := newServiceRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim reminders: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var reminders []Reminder
+	if err := json.NewDecoder(resp.Body).Decode(&reminders); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return reminders, nil
+}
+
+func markDigestSent(digest DigestSubscription) error {
+	url := fmt.Sprintf("%s/api/digests/%s/sent", userServiceURL, digest.ID)
+	jsonData, err := json.Marshal(map[string]time.Time{"due_at": digest.DueAt})
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := newServiceRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
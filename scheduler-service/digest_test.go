@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDigestPeriod(t *testing.T) {
+	loc := mustLoadLocation(t, "America/New_York")
+	dueAt := time.Date(2026, 3, 7, 8, 0, 0, 0, loc) // the week clocks go forward
+
+	tests := []struct {
+		frequency string
+		wantTo    time.Time
+	}{
+		{"daily", time.Date(2026, 3, 8, 8, 0, 0, 0, loc)},
+		{"weekly", time.Date(2026, 3, 14, 8, 0, 0, 0, loc)},
+	}
+
+	for _, tt := range tests {
+		from, to := digestPeriod(DigestSubscription{Frequency: tt.frequency, Timezone: "America/New_York", DueAt: dueAt.UTC()})
+		if !from.Equal(dueAt) || !to.Equal(tt.wantTo) {
+			t.Errorf("%s: period %v to %v, want %v to %v (local wall clock)", tt.frequency, from, to, dueAt, tt.wantTo)
+		}
+	}
+}
+
+func TestDigestMessage(t *testing.T) {
+	dueAt := time.Date(2026, 3, 2, 7, 0, 0, 0, time.UTC)
+	digest := DigestSubscription{ID: "d1", UserID: "u1", Frequency: "weekly", Timezone: "Europe/Berlin", Channel: "sms", Phone: "+15550100", DueAt: dueAt}
+	reminders := []Reminder{
+		{ID: "r1", OrgID: "o1", Title: "Dentist", Description: "Bring the card", DateTime: dueAt.Add(2 * time.Hour), Priority: "high"},
+		{ID: "r2", OrgID: "o1", Title: "Call Ann", DateTime: dueAt.Add(26 * time.Hour), Priority: "normal"},
+	}
+	mustLoadLocation(t, "Europe/Berlin")
+
+	message := digestMessage(digest, reminders)
+	if message.Title != "Your reminders for the week of March 2" || message.Template != "digest" || message.DigestID != "d1" {
+		t.Errorf("message = %+v", message)
+	}
+	if message.NotificationType != "sms" || message.Phone != "+15550100" || message.Email != "" {
+		t.Errorf("sent on %s to %q/%q, want the subscriber's phone", message.NotificationType, message.Email, message.Phone)
+	}
+	if message.Timezone != "Europe/Berlin" || message.OrgID != "o1" || message.Priority != "normal" {
+		t.Errorf("timezone %s, org %s, priority %s", message.Timezone, message.OrgID, message.Priority)
+	}
+	if len(message.Items) != 2 || message.Items[0] != (DigestItem{ReminderID: "r1", Title: "Dentist", Description: "Bring the card", DateTime: reminders[0].DateTime, Priority: "high"}) ||
+		message.Items[1].ReminderID != "r2" {
+		t.Errorf("items = %+v, want one per reminder in order", message.Items)
+	}
+
+	digest.Frequency = "daily"
+	if title := digestMessage(digest, reminders).Title; title != "Your reminders for Monday, March 2" {
+		t.Errorf("daily title = %q", title)
+	}
+}
@@ -6,6 +6,7 @@ toolchain go1.24.5
 
 require (
 	github.com/gin-gonic/gin v1.11.0
+	github.com/golang-jwt/jwt/v5 v5.1.0
 	github.com/rabbitmq/amqp091-go v1.10.0
 )
 
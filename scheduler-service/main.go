@@ -8,47 +8,84 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// serviceName is who this service is in its service token and X-Service-Name (see auth.go)
+const serviceName = "scheduler-service"
+
 // Reminder structure (matches reminder service)
 type Reminder struct {
-	ID               string    `json:"id"`
-	UserID           string    `json:"user_id"`
-	Title            string    `json:"title"`
-	Description      string    `json:"description"`
-	DateTime         time.Time `json:"datetime"`
-	NotificationType string    `json:"notification_type"`
-	Email            string    `json:"email,omitempty"`
-	Phone            string    `json:"phone,omitempty"`
-	Status           string    `json:"status"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID                string     `json:"id"`
+	UserID            string     `json:"user_id"`
+	OrgID             string     `json:"org_id,omitempty"`
+	Title             string     `json:"title"`
+	Description       string     `json:"description"`
+	DateTime          time.Time  `json:"datetime"`
+	NotificationType  string     `json:"notification_type"`
+	Email             string     `json:"email,omitempty"`
+	Phone             string     `json:"phone,omitempty"`
+	ContactIDs        []string   `json:"contact_ids,omitempty"`
+	GroupIDs          []string   `json:"group_ids,omitempty"`
+	Priority          string     `json:"priority"`
+	Status            string     `json:"status"`
+	CatchUp           string     `json:"catch_up,omitempty"`
+	GraceMinutes      int        `json:"grace_minutes,omitempty"`
+	Collaborators     []string   `json:"collaborators,omitempty"`      // users it's shared with who get its notifications
+	PublishedChannels []string   `json:"published_channels,omitempty"` // channels this occurrence already went out on before a failure
+	RequeuedAt        *time.Time `json:"requeued_at,omitempty"`        // last put back in the queue by hand; catch-up lateness counts from here
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
 // NotificationMessage for RabbitMQ
 type NotificationMessage struct {
-	ReminderID       string    `json:"reminder_id"`
-	Title            string    `json:"title"`
-	Description      string    `json:"description"`
-	DateTime         time.Time `json:"datetime"`
-	NotificationType string    `json:"notification_type"`
-	Email            string    `json:"email,omitempty"`
-	Phone            string    `json:"phone,omitempty"`
+	ReminderID       string       `json:"reminder_id"`
+	Title            string       `json:"title"`
+	Description      string       `json:"description"`
+	DateTime         time.Time    `json:"datetime"`
+	NotificationType string       `json:"notification_type"`
+	Email            string       `json:"email,omitempty"`
+	Phone            string       `json:"phone,omitempty"`
+	Priority         string       `json:"priority"`
+	DigestID         string       `json:"digest_id,omitempty"`
+	Template         string       `json:"template,omitempty"` // "digest" for a digest of Items
+	Items            []DigestItem `json:"items,omitempty"`
+	Timezone         string       `json:"timezone,omitempty"` // zone item times are shown in
+	OrgID            string       `json:"org_id,omitempty"`   // sent with the organization's credentials and counted against its quota
+}
+
+// Queues: high and critical reminders get their own priority queue so they never wait behind bulk sends
+const (
+	notificationsQueue         = "notifications"
+	priorityNotificationsQueue = "notifications.priority"
+	maxMessagePriority         = 10
+)
+
+// AMQP message priority per reminder priority
+var messagePriorities = map[string]uint8{
+	"low":      1,
+	"normal":   3,
+	"high":     6,
+	"critical": 9,
 }
 
 var (
 	rabbitConn         *amqp.Connection
 	rabbitCh           *amqp.Channel
 	reminderServiceURL string
+	userServiceURL     string
 )
 
 func main() {
 	// Load configuration
+	initAuth()
 	reminderServiceURL = getEnv("REMINDER_SERVICE_URL", "http://reminder-service:8081")
+	userServiceURL = getEnv("USER_SERVICE_URL", "http://user-service:8084")
 
 	// Initialize RabbitMQ
 	initRabbitMQ()
@@ -62,7 +99,7 @@ func main() {
 	router := gin.Default()
 
 	router.GET("/health", healthCheck)
-	router.POST("/trigger", triggerManualCheck) // Manual trigger for testing
+	router.POST("/trigger", authMiddleware(), requireRole(roleAdmin, roleService), triggerManualCheck) // Manual trigger, admins only
 
 	// Start server
 	port := getEnv("PORT", "8083")
@@ -89,17 +126,30 @@ func initRabbitMQ() {
 
 	// Declare notifications queue
 	_, err = rabbitCh.QueueDeclare(
-		"notifications", // queue name
-		true,            // durable
-		false,           // delete when unused
-		false,           // exclusive
-		false,           // no-wait
-		nil,             // arguments
+		notificationsQueue, // queue name
+		true,               // durable
+		false,              // delete when unused
+		false,              // exclusive
+		false,              // no-wait
+		nil,                // arguments
 	)
 	if err != nil {
 		log.Fatal("Failed to declare queue:", err)
 	}
 
+	// Declare priority queue for high and critical notifications
+	_, err = rabbitCh.QueueDeclare(
+		priorityNotificationsQueue, // queue name
+		true,                       // durable
+		false,                      // delete when unused
+		false,                      // exclusive
+		false,                      // no-wait
+		amqp.Table{"x-max-priority": maxMessagePriority}, // arguments
+	)
+	if err != nil {
+		log.Fatal("Failed to declare priority queue:", err)
+	}
+
 	log.Println("RabbitMQ connected successfully")
 }
 
@@ -112,10 +162,12 @@ func runScheduler() {
 
 	// Run immediately on startup
 	resetStuckProcessingReminders()
+	processDigests()
 	checkAndProcessReminders()
 
 	for range ticker.C {
 		resetStuckProcessingReminders()
+		processDigests()
 		checkAndProcessReminders()
 	}
 }
@@ -125,7 +177,7 @@ func resetStuckProcessingReminders() {
 
 	url := fmt.Sprintf("%s/api/reminders/reset-stuck", reminderServiceURL)
 
-	req, err := http.NewRequest("POST", url, nil)
+	req, err := newServiceRequest("POST", url, nil) // the service name shows up as the actor in reminder history
 	if err != nil {
 		log.Printf("Error creating reset request: %v", err)
 		return
@@ -160,36 +212,68 @@ func checkAndProcessReminders() {
 
 	log.Printf("Found %d pending reminder(s)", len(reminders))
 
+	prefs := make(preferencesCache)
+	var missed []Reminder
 	for _, reminder := range reminders {
 		// Check if reminder is due
 		now := time.Now()
 		if reminder.DateTime.Before(now) || reminder.DateTime.Equal(now) {
+			// Too late to be useful under its catch-up policy: expire it and report it in a summary
+			if missedReminder(reminder, prefs.get(reminder.UserID), now) {
+				log.Printf("Reminder %s missed its time (%s), expiring", reminder.ID, reminder.DateTime.Format(time.RFC3339))
+				if err := expireReminder(reminder); err != nil {
+					log.Printf("Error expiring reminder %s: %v", reminder.ID, err)
+				} else {
+					missed = append(missed, reminder)
+				}
+				continue
+			}
+
 			log.Printf("Processing reminder: %s - %s", reminder.ID, reminder.Title)
 
-			// First, update status to "processing" to prevent duplicate processing
-			if err := updateReminderStatus(reminder.ID, "processing"); err != nil {
+			// First, claim it by moving it to "processing"; if its status has changed since it
+			// was fetched, someone else (another scheduler pass, a user edit) got there first
+			if err := updateReminderStatus(reminder.ID, reminder.Status, "processing"); err != nil {
 				log.Printf("Error updating reminder status to processing for %s: %v", reminder.ID, err)
 				continue
 			}
 
-			// Send notification
-			if err := sendNotification(reminder); err != nil {
+			// Send notification, unless a quiet hours window holds it back
+			deferUntil, published, err := sendNotification(reminder)
+			if err != nil {
 				log.Printf("Error sending notification for reminder %s: %v", reminder.ID, err)
-				// Update status to failed
-				updateReminderStatus(reminder.ID, "failed")
+				// Update status to failed, recording why for the triage view and which
+				// channels already went out so a retry doesn't send them again
+				failReminder(reminder.ID, err, published)
+			} else if !deferUntil.IsZero() {
+				log.Printf("Reminder %s is in quiet hours, deferring until %s", reminder.ID, deferUntil.Format(time.RFC3339))
+				if err := deferReminder(reminder.ID, deferUntil); err != nil {
+					log.Printf("Error deferring reminder %s: %v", reminder.ID, err)
+					failReminder(reminder.ID, err, nil)
+				}
 			} else {
 				log.Printf("Notification sent for reminder: %s", reminder.ID)
 				// Update status to sent
-				updateReminderStatus(reminder.ID, "sent")
+				updateReminderStatus(reminder.ID, "processing", "sent")
 			}
 		}
 	}
+
+	if len(missed) > 0 {
+		sendMissedSummaries(missed, prefs)
+	}
 }
 
 func fetchPendingReminders() ([]Reminder, error) {
 	url := fmt.Sprintf("%s/api/reminders/pending", reminderServiceURL)
 
-	resp, err := http.Get(url)
+	req, err := newServiceRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch reminders: %w", err)
 	}
@@ -207,30 +291,102 @@ func fetchPendingReminders() ([]Reminder, error) {
 	return reminders, nil
 }
 
-func sendNotification(reminder Reminder) error {
-	message := NotificationMessage{
-		ReminderID:       reminder.ID,
-		Title:            reminder.Title,
-		Description:      reminder.Description,
-		DateTime:         reminder.DateTime,
-		NotificationType: reminder.NotificationType,
-		Email:            reminder.Email,
-		Phone:            reminder.Phone,
+// sendNotification publishes a reminder to its recipients, or returns the time it should be deferred to.
+// Channels published before a failure (on this pass or an earlier one) are returned with the error,
+// and skipped when the reminder is retried.
+func sendNotification(reminder Reminder) (time.Time, []string, error) {
+	recipients, err := resolveRecipients(reminder)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	recipients, deferUntil := applyQuietHours(reminder, recipients, time.Now())
+	if !deferUntil.IsZero() {
+		return deferUntil, nil, nil
+	}
+
+	published, err := publishByChannel(reminder, recipients, publishNotification)
+	return time.Time{}, published, err
+}
+
+// publishByChannel publishes one message per channel the recipients are reached on, skipping
+// channels the reminder was already published on. It returns every channel published so far,
+// with the error if one failed.
+func publishByChannel(reminder Reminder, recipients []Recipient, publish func(NotificationMessage) error) ([]string, error) {
+	// Contacts may be reached on a channel other than the reminder's own (preferred channel,
+	// quiet hours downgrade), so a single reminder may fan out into one message per channel
+	addresses := make(map[string][]string)
+	for _, recipient := range recipients {
+		addresses[recipient.Channel] = append(addresses[recipient.Channel], recipient.Address)
 	}
 
+	published := append([]string(nil), reminder.PublishedChannels...)
+	for _, channel := range []string{"email", "sms"} {
+		if len(addresses[channel]) == 0 {
+			continue
+		}
+		if containsChannel(published, channel) {
+			log.Printf("Reminder %s was already published on %s, skipping", reminder.ID, channel)
+			continue
+		}
+
+		message := NotificationMessage{
+			ReminderID:       reminder.ID,
+			Title:            reminder.Title,
+			Description:      reminder.Description,
+			DateTime:         reminder.DateTime,
+			NotificationType: channel,
+			Priority:         reminder.Priority,
+			OrgID:            reminder.OrgID,
+		}
+		if channel == "email" {
+			message.Email = strings.Join(addresses[channel], ", ")
+		} else {
+			message.Phone = strings.Join(addresses[channel], ", ")
+		}
+
+		if err := publish(message); err != nil {
+			return published, err
+		}
+		published = append(published, channel)
+	}
+
+	if len(published) == 0 {
+		return nil, fmt.Errorf("no recipients for reminder %s", reminder.ID)
+	}
+
+	return published, nil
+}
+
+func containsChannel(channels []string, channel string) bool {
+	for _, c := range channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+func publishNotification(message NotificationMessage) error {
 	body, err := json.Marshal(message)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
+	queue := notificationsQueue
+	if message.Priority == "high" || message.Priority == "critical" {
+		queue = priorityNotificationsQueue
+	}
+
 	err = rabbitCh.Publish(
-		"",              // exchange
-		"notifications", // routing key (queue name)
-		false,           // mandatory
-		false,           // immediate
+		"",    // exchange
+		queue, // routing key (queue name)
+		false, // mandatory
+		false, // immediate
 		amqp.Publishing{
 			DeliveryMode: amqp.Persistent,
 			ContentType:  "application/json",
+			Priority:     messagePriorities[message.Priority],
 			Body:         body,
 		},
 	)
@@ -242,22 +398,45 @@ func sendNotification(reminder Reminder) error {
 	return nil
 }
 
-func updateReminderStatus(reminderID, status string) error {
+// updateReminderStatus moves a reminder from one status to another; the reminder service
+// refuses with 409 if it's no longer in the expected status
+func updateReminderStatus(reminderID, from, to string) error {
+	return updateReminder(reminderID, map[string]interface{}{"status": to, "expected_status": from})
+}
+
+// failReminder marks a reminder failed along with the error that caused it and the channels
+// that had already been published
+func failReminder(reminderID string, cause error, published []string) error {
+	return updateReminder(reminderID, map[string]interface{}{
+		"status":             "failed",
+		"error":              cause.Error(),
+		"expected_status":    "processing",
+		"published_channels": published,
+	})
+}
+
+// deferReminder puts a reminder back to pending with a later due time
+func deferReminder(reminderID string, until time.Time) error {
+	return updateReminder(reminderID, map[string]interface{}{
+		"status":          "pending",
+		"datetime":        until.UTC().Format(time.RFC3339),
+		"expected_status": "processing",
+	})
+}
+
+func updateReminder(reminderID string, payload map[string]interface{}) error {
 	url := fmt.Sprintf("%s/api/reminders/%s", reminderServiceURL, reminderID)
 
-	payload := map[string]string{"status": status}
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(jsonData))
+	req, err := newServiceRequest("PUT", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// recordingPublisher remembers what was published, failing on the channels it's told to
+type recordingPublisher struct {
+	sent   []NotificationMessage
+	failOn string
+}
+
+func (p *recordingPublisher) publish(message NotificationMessage) error {
+	if message.NotificationType == p.failOn {
+		return errors.New("broker unavailable")
+	}
+	p.sent = append(p.sent, message)
+	return nil
+}
+
+var fanOutRecipients = []Recipient{
+	{Channel: "email", Address: "me@example.com"},
+	{Channel: "sms", Address: "+15550100"},
+	{Channel: "email", Address: "ann@example.com"},
+}
+
+func TestPublishByChannel(t *testing.T) {
+	publisher := &recordingPublisher{}
+	published, err := publishByChannel(Reminder{ID: "r1"}, fanOutRecipients, publisher.publish)
+	if err != nil {
+		t.Fatalf("publishByChannel: %v", err)
+	}
+	if !reflect.DeepEqual(published, []string{"email", "sms"}) {
+		t.Errorf("published = %v, want [email sms]", published)
+	}
+	if len(publisher.sent) != 2 || publisher.sent[0].Email != "me@example.com, ann@example.com" || publisher.sent[1].Phone != "+15550100" {
+		t.Errorf("sent = %+v, want one email to both addresses and one sms", publisher.sent)
+	}
+}
+
+func TestPublishByChannelPartialRetry(t *testing.T) {
+	// The first pass gets email out, then sms fails
+	publisher := &recordingPublisher{failOn: "sms"}
+	published, err := publishByChannel(Reminder{ID: "r1"}, fanOutRecipients, publisher.publish)
+	if err == nil || !reflect.DeepEqual(published, []string{"email"}) {
+		t.Fatalf("first pass = %v, %v; want [email] and an error", published, err)
+	}
+
+	// The retry only sends what's still missing
+	retry := &recordingPublisher{}
+	published, err = publishByChannel(Reminder{ID: "r1", PublishedChannels: published}, fanOutRecipients, retry.publish)
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if len(retry.sent) != 1 || retry.sent[0].NotificationType != "sms" {
+		t.Errorf("retry sent %+v, want only the sms", retry.sent)
+	}
+	if !reflect.DeepEqual(published, []string{"email", "sms"}) {
+		t.Errorf("published after retry = %v, want [email sms]", published)
+	}
+}
+
+func TestPublishByChannelNothingToSend(t *testing.T) {
+	publisher := &recordingPublisher{}
+	if _, err := publishByChannel(Reminder{ID: "r1"}, nil, publisher.publish); err == nil {
+		t.Error("no recipients: want an error")
+	}
+
+	// Everything already out counts as nothing to send, without publishing again
+	published, err := publishByChannel(Reminder{ID: "r1", PublishedChannels: []string{"email", "sms"}}, fanOutRecipients, publisher.publish)
+	if len(publisher.sent) != 0 {
+		t.Errorf("sent %+v, want nothing", publisher.sent)
+	}
+	if err != nil || !reflect.DeepEqual(published, []string{"email", "sms"}) {
+		t.Errorf("all published = %v, %v; want [email sms] and no error", published, err)
+	}
+}
@@ -0,0 +1,218 @@
+// quiet_hours.go - Quiet hours / do-not-disturb enforcement
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Quiet hours policies (matches user service)
+const (
+	policyDefer     = "defer"
+	policyDowngrade = "downgrade"
+	policySend      = "send"
+)
+
+var defaultQuietHoursPolicies = map[string]string{
+	"low":      policyDefer,
+	"normal":   policyDefer,
+	"high":     policyDowngrade,
+	"critical": policySend,
+}
+
+// QuietHoursRule structure (matches user service)
+type QuietHoursRule struct {
+	ID        string            `json:"id"`
+	ContactID string            `json:"contact_id,omitempty"`
+	Start     string            `json:"start"`
+	End       string            `json:"end"`
+	Days      []int             `json:"days,omitempty"`
+	Timezone  string            `json:"timezone"`
+	Policies  map[string]string `json:"policies"`
+}
+
+// applyQuietHours filters recipients through the reminder owner's quiet hours: their own windows,
+// which cover every recipient, and the windows they set for particular contacts. Recipients' own
+// quiet hours are not consulted; a collaborator's rules only apply to reminders they own.
+// It returns the recipients to send to now, or a non-zero time the whole reminder must wait for.
+func applyQuietHours(reminder Reminder, recipients []Recipient, now time.Time) ([]Recipient, time.Time) {
+	// Critical reminders always go out, whatever the windows say
+	if reminder.Priority == "critical" {
+		return recipients, time.Time{}
+	}
+
+	rules, err := fetchQuietHours(reminder.UserID)
+	if err != nil {
+		// Don't hold back delivery because the rules are unavailable
+		log.Printf("Error fetching quiet hours for user %s, sending anyway: %v", reminder.UserID, err)
+		return recipients, time.Time{}
+	}
+	if len(rules) == 0 {
+		return recipients, time.Time{}
+	}
+
+	var deferUntil time.Time
+	allowed := make([]Recipient, 0, len(recipients))
+
+	for _, recipient := range recipients {
+		policy, windowEnd := quietHoursPolicy(rules, recipient, reminder.Priority, now)
+
+		switch policy {
+		case policySend:
+			allowed = append(allowed, recipient)
+			continue
+		case policyDowngrade:
+			if recipient.Channel != "sms" {
+				allowed = append(allowed, recipient)
+				continue
+			}
+			if recipient.Contact != nil && len(recipient.Contact.Emails) > 0 {
+				for _, email := range recipient.Contact.Emails {
+					allowed = append(allowed, Recipient{Channel: "email", Address: email, Contact: recipient.Contact})
+				}
+				continue
+			}
+			// Nothing quieter to downgrade to
+		}
+
+		if windowEnd.After(deferUntil) {
+			deferUntil = windowEnd
+		}
+	}
+
+	// A reminder is delivered as a whole, so any recipient that must wait holds back everyone
+	if !deferUntil.IsZero() {
+		return nil, deferUntil
+	}
+	return allowed, time.Time{}
+}
+
+// quietHoursPolicy returns the strictest policy of the windows currently covering a recipient
+func quietHoursPolicy(rules []QuietHoursRule, recipient Recipient, priority string, now time.Time) (string, time.Time) {
+	if priority == "" {
+		priority = "normal"
+	}
+
+	policy := policySend
+	var windowEnd time.Time
+
+	for _, rule := range rules {
+		// User-level windows cover everyone, contact windows only that contact
+		if rule.ContactID != "" && (recipient.Contact == nil || recipient.Contact.ID != rule.ContactID) {
+			continue
+		}
+
+		end, active := quietWindowEnd(rule, now)
+		if !active {
+			continue
+		}
+
+		rulePolicy := rule.Policies[priority]
+		if rulePolicy == "" {
+			rulePolicy = defaultQuietHoursPolicies[priority]
+		}
+
+		if policyRank(rulePolicy) > policyRank(policy) {
+			policy = rulePolicy
+		}
+		if end.After(windowEnd) {
+			windowEnd = end
+		}
+	}
+
+	return policy, windowEnd
+}
+
+func policyRank(policy string) int {
+	switch policy {
+	case policyDefer:
+		return 2
+	case policyDowngrade:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// quietWindowEnd reports whether now falls inside the rule's window and when that window ends.
+// Windows that wrap past midnight (22:00-07:00) belong to the day they start on.
+func quietWindowEnd(rule QuietHoursRule, now time.Time) (time.Time, bool) {
+	loc, err := time.LoadLocation(rule.Timezone)
+	if err != nil {
+		log.Printf("Invalid timezone %q in quiet hours %s: %v", rule.Timezone, rule.ID, err)
+		return time.Time{}, false
+	}
+	start, err := time.Parse("15:04", rule.Start)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := time.Parse("15:04", rule.End)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	local := now.In(loc)
+
+	// Check the window starting yesterday (for wrap-around) and the one starting today
+	for _, offset := range []int{-1, 0} {
+		day := local.AddDate(0, 0, offset)
+		if !ruleAppliesOn(rule, day.Weekday()) {
+			continue
+		}
+
+		windowStart := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+		windowEnd := time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+		if !windowEnd.After(windowStart) {
+			windowEnd = windowEnd.AddDate(0, 0, 1)
+		}
+
+		if !now.Before(windowStart) && now.Before(windowEnd) {
+			return windowEnd, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func ruleAppliesOn(rule QuietHoursRule, weekday time.Weekday) bool {
+	if len(rule.Days) == 0 {
+		return true
+	}
+	for _, day := range rule.Days {
+		if time.Weekday(day) == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func fetchQuietHours(userID string) ([]QuietHoursRule, error) {
+	url := fmt.Sprintf("%s/api/quiet-hours?user_id=%s", userServiceURL, url.QueryEscape(userID))
+
+	req, err := newServiceRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch quiet hours: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var rules []QuietHoursRule
+	if err := json.NewDecoder(resp.Body).Decode(&rules); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestQuietWindowEnd(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, newYork)
+	}
+	overnight := QuietHoursRule{Start: "22:00", End: "07:00", Timezone: "America/New_York"}
+	// Fridays only: the window starting Friday night runs into Saturday morning
+	fridayNights := QuietHoursRule{Start: "22:00", End: "07:00", Days: []int{int(time.Friday)}, Timezone: "America/New_York"}
+	workday := QuietHoursRule{Start: "09:00", End: "17:00", Timezone: "America/New_York"}
+
+	tests := []struct {
+		name    string
+		rule    QuietHoursRule
+		now     time.Time
+		active  bool
+		wantEnd time.Time
+	}{
+		{"before the window", overnight, at(2026, 3, 11, 21, 59), false, time.Time{}},
+		{"at the start", overnight, at(2026, 3, 11, 22, 0), true, at(2026, 3, 12, 7, 0)},
+		{"before midnight", overnight, at(2026, 3, 11, 23, 30), true, at(2026, 3, 12, 7, 0)},
+		{"after midnight, from yesterday's window", overnight, at(2026, 3, 12, 6, 59), true, at(2026, 3, 12, 7, 0)},
+		{"at the end", overnight, at(2026, 3, 12, 7, 0), false, time.Time{}},
+		{"daytime window", workday, at(2026, 3, 11, 12, 0), true, at(2026, 3, 11, 17, 0)},
+		{"outside a daytime window", workday, at(2026, 3, 11, 18, 0), false, time.Time{}},
+
+		// 13 March 2026 is a Friday
+		{"friday night", fridayNights, at(2026, 3, 13, 23, 0), true, at(2026, 3, 14, 7, 0)},
+		{"saturday morning belongs to friday", fridayNights, at(2026, 3, 14, 2, 0), true, at(2026, 3, 14, 7, 0)},
+		{"friday morning belongs to thursday", fridayNights, at(2026, 3, 13, 2, 0), false, time.Time{}},
+		{"saturday night", fridayNights, at(2026, 3, 14, 23, 0), false, time.Time{}},
+
+		// Clocks spring forward on 8 March and fall back on 1 November 2026; the window
+		// keeps its wall-clock end, so it's an hour shorter or longer that night
+		{"spring forward night", overnight, at(2026, 3, 8, 6, 30), true, at(2026, 3, 8, 7, 0)},
+		{"fall back night", overnight, at(2026, 11, 1, 1, 30), true, at(2026, 11, 1, 7, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, active := quietWindowEnd(tt.rule, tt.now.UTC())
+			if active != tt.active || !end.Equal(tt.wantEnd) {
+				t.Errorf("quietWindowEnd at %s = %s %v, want %s %v", tt.now, end, active, tt.wantEnd, tt.active)
+			}
+		})
+	}
+}
+
+func TestQuietWindowEndSpringForwardLength(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	rule := QuietHoursRule{Start: "22:00", End: "07:00", Timezone: "America/New_York"}
+
+	// Nine wall-clock hours, eight elapsed, the night the clocks go forward
+	start := time.Date(2026, 3, 7, 22, 0, 0, 0, newYork)
+	end, active := quietWindowEnd(rule, start)
+	if !active || end.Sub(start) != 8*time.Hour {
+		t.Errorf("window from %s ends %s (%v), want 8 hours later", start, end, end.Sub(start))
+	}
+}
+
+func TestQuietWindowEndInvalidRule(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	now := time.Date(2026, 3, 11, 23, 0, 0, 0, time.UTC)
+	for _, rule := range []QuietHoursRule{
+		{Start: "22:00", End: "07:00", Timezone: "Mars/Olympus"},
+		{Start: "late", End: "07:00", Timezone: "UTC"},
+		{Start: "22:00", End: "25:00", Timezone: "UTC"},
+	} {
+		if _, active := quietWindowEnd(rule, now); active {
+			t.Errorf("invalid rule %+v is active, want ignored", rule)
+		}
+	}
+}
+
+func TestQuietHoursPolicy(t *testing.T) {
+	now := time.Date(2026, 3, 11, 23, 0, 0, 0, time.UTC)
+	ann := &Contact{ID: "ann"}
+	userWide := QuietHoursRule{ID: "user", Start: "22:00", End: "07:00", Timezone: "UTC"}
+	annOnly := QuietHoursRule{ID: "ann", ContactID: "ann", Start: "20:00", End: "08:00", Timezone: "UTC",
+		Policies: map[string]string{"high": policyDefer}}
+
+	tests := []struct {
+		name      string
+		rules     []QuietHoursRule
+		recipient Recipient
+		priority  string
+		want      string
+		wantEnd   int // hour of the next day the window ends, if any
+	}{
+		{"defaults by priority", []QuietHoursRule{userWide}, Recipient{Channel: "sms"}, "high", policyDowngrade, 7},
+		{"empty priority is normal", []QuietHoursRule{userWide}, Recipient{Channel: "sms"}, "", policyDefer, 7},
+		{"critical goes through", []QuietHoursRule{userWide}, Recipient{Channel: "sms"}, "critical", policySend, 7},
+		{"contact rules skip other recipients", []QuietHoursRule{annOnly}, Recipient{Channel: "sms"}, "high", policySend, 0},
+		{"the strictest window wins", []QuietHoursRule{userWide, annOnly}, Recipient{Channel: "sms", Contact: ann}, "high", policyDefer, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, end := quietHoursPolicy(tt.rules, tt.recipient, tt.priority, now)
+			wantEnd := time.Time{}
+			if tt.wantEnd != 0 {
+				wantEnd = time.Date(2026, 3, 12, tt.wantEnd, 0, 0, 0, time.UTC)
+			}
+			if policy != tt.want || !end.Equal(wantEnd) {
+				t.Errorf("quietHoursPolicy = %s until %s, want %s until %s", policy, end, tt.want, wantEnd)
+			}
+		})
+	}
+}
+
+func TestApplyQuietHoursCritical(t *testing.T) {
+	recipients := []Recipient{{Channel: "sms", Address: "+15550100"}}
+
+	// Critical reminders don't even look the rules up
+	allowed, deferUntil := applyQuietHours(Reminder{UserID: "u1", Priority: "critical"}, recipients, time.Now())
+	if len(allowed) != 1 || !deferUntil.IsZero() {
+		t.Errorf("critical = %v, defer until %s; want sent now", allowed, deferUntil)
+	}
+}
@@ -0,0 +1,146 @@
+// recipients.go - Contact and group recipient resolution
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// Contact structure (matches user service)
+type Contact struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Emails           []string `json:"emails"`
+	Phones           []string `json:"phones"`
+	PreferredChannel string   `json:"preferred_channel"`
+	Timezone         string   `json:"timezone,omitempty"`
+}
+
+// Recipient is a single delivery address for a reminder
+type Recipient struct {
+	Channel string   // email or sms
+	Address string   // may hold a comma-separated list when entered directly on the reminder
+	Contact *Contact // nil for addresses entered directly on the reminder
+}
+
+// resolveRecipients expands a reminder's raw addresses, contacts, groups and collaborators into
+// recipients. Contacts are looked up at send time so address changes apply to every future reminder.
+func resolveRecipients(reminder Reminder) ([]Recipient, error) {
+	var recipients []Recipient
+
+	if reminder.NotificationType == "email" && reminder.Email != "" {
+		recipients = append(recipients, Recipient{Channel: "email", Address: reminder.Email})
+	}
+	if reminder.NotificationType == "sms" && reminder.Phone != "" {
+		recipients = append(recipients, Recipient{Channel: "sms", Address: reminder.Phone})
+	}
+
+	if len(reminder.ContactIDs) > 0 || len(reminder.GroupIDs) > 0 {
+		contacts, err := fetchContacts(reminder)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range contacts {
+			contact := &contacts[i]
+			channel, addresses := contactAddresses(*contact, reminder.NotificationType)
+			if len(addresses) == 0 {
+				log.Printf("Contact %s has no address for reminder %s, skipping", contact.ID, reminder.ID)
+				continue
+			}
+			for _, address := range addresses {
+				recipients = append(recipients, Recipient{Channel: channel, Address: address, Contact: contact})
+			}
+		}
+	}
+
+	for _, userID := range reminder.Collaborators {
+		recipient, ok := collaboratorRecipient(userID, reminder.NotificationType)
+		if !ok {
+			log.Printf("Collaborator %s has no address for reminder %s, skipping", userID, reminder.ID)
+			continue
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	return recipients, nil
+}
+
+// collaboratorRecipient addresses a user the reminder is shared with, from their preferences:
+// on the reminder's channel if they have an address for it, else on the other one
+func collaboratorRecipient(userID, channel string) (Recipient, bool) {
+	prefs, err := fetchPreferences(userID)
+	if err != nil {
+		log.Printf("Error fetching preferences for collaborator %s: %v", userID, err)
+		return Recipient{}, false
+	}
+
+	byChannel := map[string]string{
+		"email": prefs.Email,
+		"sms":   prefs.Phone,
+	}
+	if byChannel[channel] != "" {
+		return Recipient{Channel: channel, Address: byChannel[channel]}, true
+	}
+	for _, fallback := range []string{"email", "sms"} {
+		if byChannel[fallback] != "" {
+			return Recipient{Channel: fallback, Address: byChannel[fallback]}, true
+		}
+	}
+	return Recipient{}, false
+}
+
+// contactAddresses picks the requested channel, falling back to the contact's preferred channel
+func contactAddresses(contact Contact, channel string) (string, []string) {
+	byChannel := map[string][]string{
+		"email": contact.Emails,
+		"sms":   contact.Phones,
+	}
+
+	if len(byChannel[channel]) > 0 {
+		return channel, byChannel[channel]
+	}
+	return contact.PreferredChannel, byChannel[contact.PreferredChannel]
+}
+
+func fetchContacts(reminder Reminder) ([]Contact, error) {
+	url := fmt.Sprintf("%s/api/contacts/resolve", userServiceURL)
+
+	payload := map[string]interface{}{
+		"user_id":     reminder.UserID,
+		"contact_ids": reminder.ContactIDs,
+		"group_ids":   reminder.GroupIDs,
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := newServiceRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve contacts: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Contacts []Contact `json:"contacts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result.Contacts, nil
+}
@@ -0,0 +1,419 @@
+// contacts.go - Contact book and recipient groups
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var errUnknownContact = errors.New("one or more contacts not found")
+
+// Contact model
+type Contact struct {
+	ID               string    `json:"id" gorm:"primaryKey"`
+	UserID           string    `json:"user_id" gorm:"index;not null"`
+	Name             string    `json:"name" gorm:"not null"`
+	Emails           []string  `json:"emails" gorm:"serializer:json;type:text"`
+	Phones           []string  `json:"phones" gorm:"serializer:json;type:text"`
+	PreferredChannel string    `json:"preferred_channel" gorm:"default:'email'"` // email or sms
+	Timezone         string    `json:"timezone,omitempty"`                       // IANA zone, e.g. America/New_York
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// ContactGroup model - a named set of contacts used as a single recipient
+type ContactGroup struct {
+	ID          string    `json:"id" gorm:"primaryKey"`
+	UserID      string    `json:"user_id" gorm:"index;not null"`
+	Name        string    `json:"name" gorm:"not null"`
+	Description string    `json:"description"`
+	Contacts    []Contact `json:"contacts" gorm:"many2many:contact_group_members;"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ContactRequest DTO
+type ContactRequest struct {
+	Name             string   `json:"name"`
+	Emails           []string `json:"emails" binding:"omitempty,dive,email"`
+	Phones           []string `json:"phones"`
+	PreferredChannel string   `json:"preferred_channel" binding:"omitempty,oneof=email sms"`
+	Timezone         string   `json:"timezone"`
+}
+
+// ContactGroupRequest DTO
+type ContactGroupRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	ContactIDs  []string `json:"contact_ids"`
+}
+
+// ResolveContactsRequest DTO - recipients referenced by a reminder
+type ResolveContactsRequest struct {
+	UserID     string   `json:"user_id" binding:"required"`
+	ContactIDs []string `json:"contact_ids"`
+	GroupIDs   []string `json:"group_ids"`
+}
+
+func listContacts(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var contacts []Contact
+	if err := db.Where("user_id = ?", userID).Order("name asc").Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contacts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, contacts)
+}
+
+func getContact(c *gin.Context) {
+	var contact Contact
+	if err := db.Where("id = ? AND user_id = ?", c.Param("id"), getUserID(c)).First(&contact).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contact"})
+		return
+	}
+
+	c.JSON(http.StatusOK, contact)
+}
+
+func createContact(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req ContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+	}
+
+	contact := Contact{
+		ID:               uuid.New().String(),
+		UserID:           userID,
+		Name:             req.Name,
+		Emails:           req.Emails,
+		Phones:           req.Phones,
+		PreferredChannel: req.PreferredChannel,
+		Timezone:         req.Timezone,
+	}
+	if contact.PreferredChannel == "" {
+		contact.PreferredChannel = "email"
+	}
+
+	if err := db.Create(&contact).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create contact"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, contact)
+}
+
+func updateContact(c *gin.Context) {
+	var req ContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var contact Contact
+	if err := db.Where("id = ? AND user_id = ?", c.Param("id"), getUserID(c)).First(&contact).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contact"})
+		return
+	}
+
+	// Update fields; a provided list replaces the stored one
+	if req.Name != "" {
+		contact.Name = req.Name
+	}
+	if req.Emails != nil {
+		contact.Emails = req.Emails
+	}
+	if req.Phones != nil {
+		contact.Phones = req.Phones
+	}
+	if req.PreferredChannel != "" {
+		contact.PreferredChannel = req.PreferredChannel
+	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+		contact.Timezone = req.Timezone
+	}
+
+	if err := db.Save(&contact).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact"})
+		return
+	}
+
+	c.JSON(http.StatusOK, contact)
+}
+
+func deleteContact(c *gin.Context) {
+	id := c.Param("id")
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, getUserID(c)).Delete(&Contact{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Exec("DELETE FROM contact_group_members WHERE contact_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Where("contact_id = ?", id).Delete(&QuietHours{}).Error
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete contact"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contact deleted successfully"})
+}
+
+func listContactGroups(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var groups []ContactGroup
+	if err := db.Preload("Contacts").Where("user_id = ?", userID).Order("name asc").Find(&groups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contact groups"})
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+func getContactGroup(c *gin.Context) {
+	var group ContactGroup
+	if err := db.Preload("Contacts").Where("id = ? AND user_id = ?", c.Param("id"), getUserID(c)).First(&group).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contact group not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contact group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+func createContactGroup(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req ContactGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	members, err := findOwnedContacts(userID, req.ContactIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group := ContactGroup{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Name:        req.Name,
+		Description: req.Description,
+		Contacts:    members,
+	}
+
+	if err := db.Create(&group).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create contact group"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+func updateContactGroup(c *gin.Context) {
+	userID := getUserID(c)
+
+	var req ContactGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var group ContactGroup
+	if err := db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&group).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contact group not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contact group"})
+		return
+	}
+
+	if req.Name != "" {
+		group.Name = req.Name
+	}
+	if req.Description != "" {
+		group.Description = req.Description
+	}
+
+	var members []Contact
+	if req.ContactIDs != nil {
+		var err error
+		if members, err = findOwnedContacts(userID, req.ContactIDs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Contacts").Save(&group).Error; err != nil {
+			return err
+		}
+		// A provided member list replaces the current membership
+		if req.ContactIDs != nil {
+			return tx.Model(&group).Association("Contacts").Replace(members)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact group"})
+		return
+	}
+
+	db.Preload("Contacts").First(&group, "id = ?", group.ID)
+	c.JSON(http.StatusOK, group)
+}
+
+func deleteContactGroup(c *gin.Context) {
+	id := c.Param("id")
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, getUserID(c)).Delete(&ContactGroup{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Exec("DELETE FROM contact_group_members WHERE contact_group_id = ?", id).Error
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contact group not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete contact group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contact group deleted successfully"})
+}
+
+func resolveContacts(c *gin.Context) {
+	// This endpoint is for the scheduler service to expand reminder recipients at send time
+	var req ResolveContactsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var contacts []Contact
+	if len(req.ContactIDs) > 0 {
+		if err := db.Where("user_id = ? AND id IN ?", req.UserID, req.ContactIDs).Find(&contacts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve contacts"})
+			return
+		}
+	}
+
+	if len(req.GroupIDs) > 0 {
+		var groups []ContactGroup
+		if err := db.Preload("Contacts").Where("user_id = ? AND id IN ?", req.UserID, req.GroupIDs).Find(&groups).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve contact groups"})
+			return
+		}
+		for _, group := range groups {
+			contacts = append(contacts, group.Contacts...)
+		}
+	}
+
+	// A contact may be referenced directly and through several groups
+	seen := make(map[string]bool)
+	resolved := make([]Contact, 0, len(contacts))
+	for _, contact := range contacts {
+		if seen[contact.ID] {
+			continue
+		}
+		seen[contact.ID] = true
+		resolved = append(resolved, contact)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"contacts": resolved})
+}
+
+// findOwnedContacts loads the given contacts, rejecting IDs that don't belong to the user
+func findOwnedContacts(userID string, ids []string) ([]Contact, error) {
+	if len(ids) == 0 {
+		return []Contact{}, nil
+	}
+
+	unique := make(map[string]bool)
+	for _, id := range ids {
+		unique[id] = true
+	}
+
+	var contacts []Contact
+	if err := db.Where("user_id = ? AND id IN ?", userID, ids).Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+	if len(contacts) != len(unique) {
+		return nil, errUnknownContact
+	}
+	return contacts, nil
+}
@@ -0,0 +1,297 @@
+// digests.go - Digest subscriptions: one daily or weekly message listing upcoming reminders
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DigestSubscription model - while enabled, the user's reminders due in each period are sent
+// together in one digest at the start of the period instead of one by one
+type DigestSubscription struct {
+	ID         string     `json:"id" gorm:"primaryKey"`
+	UserID     string     `json:"user_id" gorm:"index;not null"`
+	Frequency  string     `json:"frequency" gorm:"not null"` // daily or weekly
+	Weekday    int        `json:"weekday"`                   // day a weekly digest goes out (0 = Sunday)
+	Time       string     `json:"time" gorm:"not null"`      // local time the digest goes out, "08:00"
+	Timezone   string     `json:"timezone" gorm:"not null"`  // IANA zone; defaults to the user's preference
+	Channel    string     `json:"channel" gorm:"not null"`   // email or sms
+	Email      string     `json:"email,omitempty"`
+	Phone      string     `json:"phone,omitempty"`
+	Enabled    bool       `json:"enabled" gorm:"not null"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"` // scheduled time of the last digest sent
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// DigestSubscriptionRequest DTO
+type DigestSubscriptionRequest struct {
+	Frequency string `json:"frequency" binding:"omitempty,oneof=daily weekly"`
+	Weekday   *int   `json:"weekday" binding:"omitempty,min=0,max=6"`
+	Time      string `json:"time"`
+	Timezone  string `json:"timezone"`
+	Channel   string `json:"channel" binding:"omitempty,oneof=email sms"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+	Enabled   *bool  `json:"enabled"`
+}
+
+// DigestSentRequest DTO
+type DigestSentRequest struct {
+	DueAt time.Time `json:"due_at" binding:"required"`
+}
+
+// DueDigest is a subscription whose digest should go out now, for the period starting at DueAt
+type DueDigest struct {
+	DigestSubscription
+	DueAt time.Time `json:"due_at"`
+}
+
+func listDigestSubscriptions(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var subscriptions []DigestSubscription
+	if err := db.Where("user_id = ?", userID).Order("created_at asc").Find(&subscriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch digest subscriptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+func createDigestSubscription(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req DigestSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription := DigestSubscription{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Frequency: "daily",
+		Time:      "08:00",
+		Timezone:  req.Timezone,
+		Channel:   req.Channel,
+		Email:     req.Email,
+		Phone:     req.Phone,
+		Enabled:   true,
+	}
+	if req.Frequency != "" {
+		subscription.Frequency = req.Frequency
+	}
+	if req.Weekday != nil {
+		subscription.Weekday = *req.Weekday
+	} else {
+		subscription.Weekday = int(time.Monday)
+	}
+	if req.Time != "" {
+		subscription.Time = req.Time
+	}
+	if req.Enabled != nil {
+		subscription.Enabled = *req.Enabled
+	}
+	if subscription.Channel == "" {
+		subscription.Channel = "email"
+	}
+	if subscription.Timezone == "" {
+		prefs, err := findPreferences(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch preferences"})
+			return
+		}
+		subscription.Timezone = prefs.Timezone
+	}
+
+	if err := validateDigestSubscription(&subscription); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := db.Create(&subscription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create digest subscription"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, subscription)
+}
+
+func updateDigestSubscription(c *gin.Context) {
+	var req DigestSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var subscription DigestSubscription
+	if err := db.Where("id = ? AND user_id = ?", c.Param("id"), getUserID(c)).First(&subscription).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Digest subscription not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch digest subscription"})
+		return
+	}
+
+	// Update fields
+	if req.Frequency != "" {
+		subscription.Frequency = req.Frequency
+	}
+	if req.Weekday != nil {
+		subscription.Weekday = *req.Weekday
+	}
+	if req.Time != "" {
+		subscription.Time = req.Time
+	}
+	if req.Timezone != "" {
+		subscription.Timezone = req.Timezone
+	}
+	if req.Channel != "" {
+		subscription.Channel = req.Channel
+	}
+	if req.Email != "" {
+		subscription.Email = req.Email
+	}
+	if req.Phone != "" {
+		subscription.Phone = req.Phone
+	}
+	if req.Enabled != nil {
+		subscription.Enabled = *req.Enabled
+	}
+
+	if err := validateDigestSubscription(&subscription); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := db.Save(&subscription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update digest subscription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+func deleteDigestSubscription(c *gin.Context) {
+	result := db.Where("id = ? AND user_id = ?", c.Param("id"), getUserID(c)).Delete(&DigestSubscription{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete digest subscription"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Digest subscription not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Digest subscription deleted successfully"})
+}
+
+// listDueDigests returns the enabled subscriptions whose latest digest hasn't been sent yet.
+// After downtime only the latest period's digest goes out, not every one that was missed.
+func listDueDigests(c *gin.Context) {
+	var subscriptions []DigestSubscription
+	if err := db.Where("enabled = ?", true).Find(&subscriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch digest subscriptions"})
+		return
+	}
+
+	now := time.Now()
+	due := []DueDigest{}
+	for _, subscription := range subscriptions {
+		dueAt, err := lastDigestTime(subscription, now)
+		if err != nil || dueAt.Before(subscription.CreatedAt) {
+			continue
+		}
+		if subscription.LastSentAt != nil && !dueAt.After(*subscription.LastSentAt) {
+			continue
+		}
+		due = append(due, DueDigest{DigestSubscription: subscription, DueAt: dueAt})
+	}
+
+	c.JSON(http.StatusOK, due)
+}
+
+// markDigestSent records that the digest for the period starting at due_at went out
+func markDigestSent(c *gin.Context) {
+	var req DigestSentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := db.Model(&DigestSubscription{}).Where("id = ?", c.Param("id")).Update("last_sent_at", req.DueAt)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update digest subscription"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Digest subscription not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Digest marked as sent"})
+}
+
+// lastDigestTime is the most recent time, at or before now, the subscription's digest was scheduled for
+func lastDigestTime(subscription DigestSubscription, now time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(subscription.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	clock, err := time.Parse("15:04", subscription.Time)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	local := now.In(loc)
+	day := local
+	if subscription.Frequency == "weekly" {
+		day = day.AddDate(0, 0, -((int(local.Weekday()) - subscription.Weekday + 7) % 7))
+	}
+	scheduled := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+	if scheduled.After(now) {
+		step := 1
+		if subscription.Frequency == "weekly" {
+			step = 7
+		}
+		day = day.AddDate(0, 0, -step)
+		scheduled = time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+	}
+	return scheduled, nil
+}
+
+func validateDigestSubscription(subscription *DigestSubscription) error {
+	if _, err := time.Parse("15:04", subscription.Time); err != nil {
+		return fmt.Errorf("invalid time %q, use HH:MM", subscription.Time)
+	}
+	if subscription.Weekday < 0 || subscription.Weekday > 6 {
+		return fmt.Errorf("weekday must be 0 (Sunday) to 6")
+	}
+	if _, err := time.LoadLocation(subscription.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", subscription.Timezone)
+	}
+	if subscription.Channel == "email" && subscription.Email == "" {
+		return fmt.Errorf("Email is required for email digests")
+	}
+	if subscription.Channel == "sms" && subscription.Phone == "" {
+		return fmt.Errorf("Phone is required for SMS digests")
+	}
+	return nil
+}
@@ -1,4 +1,187 @@
+// main.go - User Service
 package main
 
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// serviceName is who this service is in its service token and X-Service-Name (see auth.go)
+const serviceName = "user-service"
+
+var db *gorm.DB
+
 func main() {
+	// Load the token signing key
+	initAuth()
+
+	// Initialize database
+	initDB()
+
+	// Send organization changes that reminder-service hasn't applied yet
+	go retryTenantChanges()
+
+	// Initialize Gin router
+	router := gin.Default()
+
+	// CORS is handled by the API Gateway (nginx)
+
+	// Health check
+	router.GET("/health", healthCheck)
+
+	// Everything needs a bearer token; internal endpoints need a service (or admin) token
+	internal := requireRole(roleService, roleAdmin)
+
+	// Token routes
+	auth := router.Group("/api/auth")
+	auth.Use(authMiddleware())
+	{
+		auth.POST("/token", requireRole(roleService), issueUserToken) // For the service that signs users in
+	}
+
+	// Role administration routes
+	users := router.Group("/api/users")
+	users.Use(authMiddleware(), internal)
+	{
+		users.GET("", listUserRoles)
+		users.PUT("/:id/role", updateUserRole)
+	}
+
+	// Contact book routes
+	contacts := router.Group("/api/contacts")
+	contacts.Use(authMiddleware())
+	{
+		contacts.GET("", listContacts)
+		contacts.GET("/:id", getContact)
+		contacts.POST("", createContact)
+		contacts.PUT("/:id", updateContact)
+		contacts.DELETE("/:id", deleteContact)
+		contacts.POST("/resolve", internal, resolveContacts) // For scheduler service
+	}
+
+	// Recipient group routes
+	groups := router.Group("/api/contact-groups")
+	groups.Use(authMiddleware())
+	{
+		groups.GET("", listContactGroups)
+		groups.GET("/:id", getContactGroup)
+		groups.POST("", createContactGroup)
+		groups.PUT("/:id", updateContactGroup)
+		groups.DELETE("/:id", deleteContactGroup)
+	}
+
+	// Quiet hours routes
+	quietHours := router.Group("/api/quiet-hours")
+	quietHours.Use(authMiddleware())
+	{
+		quietHours.GET("", listQuietHours) // Also used by scheduler service
+		quietHours.POST("", createQuietHours)
+		quietHours.PUT("/:id", updateQuietHours)
+		quietHours.DELETE("/:id", deleteQuietHours)
+	}
+
+	// Preference routes
+	preferences := router.Group("/api/preferences")
+	preferences.Use(authMiddleware())
+	{
+		preferences.GET("", getPreferences) // Also used by reminder and scheduler services
+		preferences.PUT("", updatePreferences)
+	}
+
+	// Digest subscription routes
+	digests := router.Group("/api/digests")
+	digests.Use(authMiddleware())
+	{
+		digests.GET("", listDigestSubscriptions)
+		digests.POST("", createDigestSubscription)
+		digests.PUT("/:id", updateDigestSubscription)
+		digests.DELETE("/:id", deleteDigestSubscription)
+		digests.GET("/due", internal, listDueDigests)       // For scheduler service
+		digests.POST("/:id/sent", internal, markDigestSent) // For scheduler service
+	}
+
+	// Organization routes
+	orgs := router.Group("/api/orgs")
+	orgs.Use(authMiddleware())
+	{
+		orgs.POST("", createOrganization)
+		orgs.GET("/tenant", getTenant) // Also used by reminder service
+		orgs.GET("/invitations", listMyInvitations)
+		orgs.POST("/invitations/:inviteId/accept", acceptInvitation)
+		orgs.DELETE("/invitations/:inviteId", declineInvitation)
+		orgs.GET("/:id", getOrganization)
+		orgs.PUT("/:id", updateOrganization)
+		orgs.DELETE("/:id", deleteOrganization)
+		orgs.GET("/:id/delivery-settings", internal, getDeliverySettings) // For notification service
+		orgs.GET("/:id/members", listMembers)
+		orgs.PUT("/:id/members/:userId", updateMember)
+		orgs.DELETE("/:id/members/:userId", removeMember)
+		orgs.GET("/:id/invitations", listInvitations)
+		orgs.POST("/:id/invitations", inviteMember)
+		orgs.DELETE("/:id/invitations/:inviteId", cancelInvitation)
+		orgs.GET("/:id/teams", listTeams)
+		orgs.POST("/:id/teams", createTeam)
+		orgs.PUT("/:id/teams/:teamId", updateTeam)
+		orgs.DELETE("/:id/teams/:teamId", deleteTeam)
+		orgs.POST("/:id/teams/:teamId/members", addTeamMember)
+		orgs.DELETE("/:id/teams/:teamId/members/:userId", removeTeamMember)
+	}
+
+	// Start server
+	port := getEnv("PORT", "8084")
+
+	log.Printf("User Service starting on port %s", port)
+	if err := router.Run(":" + port); err != nil {
+		log.Fatal("Failed to start server:", err)
+	}
+}
+
+func initDB() {
+	dsn := getEnv("DATABASE_URL", "host=postgres user=reminder password=reminder dbname=reminder_db port=5432 sslmode=disable")
+
+	var err error
+	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	// Auto migrate the schema
+	if err := db.AutoMigrate(&Contact{}, &ContactGroup{}, &QuietHours{}, &UserPreferences{}, &DigestSubscription{},
+		&Organization{}, &Team{}, &Membership{}, &Invitation{}, &TeamMembership{}, &UserRole{}, &TenantChange{}); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	log.Println("Database connected and migrated successfully")
+}
+
+func healthCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "user-service",
+		"time":    time.Now(),
+	})
+}
+
+// getUserID returns the calling user from header or query; for user tokens authMiddleware has set
+// both from the token
+func getUserID(c *gin.Context) string {
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		userID = c.Query("user_id")
+	}
+	return userID
+}
+
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
 }
@@ -0,0 +1,814 @@
+// orgs.go - Organizations (tenants), their teams and members
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Organization roles
+const (
+	orgRoleAdmin  = "admin" // manages members, teams and settings, and sees the reminders of every team
+	orgRoleMember = "member"
+)
+
+var (
+	errNotOrgMember  = errors.New("not a member of this organization")
+	errNotOrgAdmin   = errors.New("organization admin required")
+	errAlreadyMember = errors.New("already a member of an organization")
+	errNoInvitation  = errors.New("invitation not found")
+)
+
+// Organization model - a tenant: its members' reminders are kept apart from everyone else's, and
+// are sent with its own mail and SMS credentials when set
+type Organization struct {
+	ID               string    `json:"id" gorm:"primaryKey"`
+	Name             string    `json:"name" gorm:"not null"`
+	SMTPHost         string    `json:"smtp_host,omitempty"` // empty: the system's mail server
+	SMTPPort         string    `json:"smtp_port,omitempty"`
+	SMTPUsername     string    `json:"smtp_username,omitempty"`
+	SMTPPassword     string    `json:"-"`
+	SMTPFrom         string    `json:"smtp_from,omitempty"`
+	TwilioAccountSID string    `json:"twilio_account_sid,omitempty"` // empty: the system's Twilio account
+	TwilioAuthToken  string    `json:"-"`
+	TwilioFromPhone  string    `json:"twilio_from_phone,omitempty"`
+	EmailDailyQuota  int       `json:"email_daily_quota"` // emails per day across the org, 0 = unlimited
+	SMSDailyQuota    int       `json:"sms_daily_quota"`   // SMS per day across the org, 0 = unlimited
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Team model - a group of members within an organization
+type Team struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	OrgID     string    `json:"org_id" gorm:"index;not null"`
+	Name      string    `json:"name" gorm:"not null"`
+	MemberIDs []string  `json:"member_ids" gorm:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Membership model - a user belongs to at most one organization
+type Membership struct {
+	UserID    string    `json:"user_id" gorm:"primaryKey"`
+	OrgID     string    `json:"org_id" gorm:"index;not null"`
+	Role      string    `json:"role" gorm:"not null"` // admin or member
+	TeamIDs   []string  `json:"team_ids" gorm:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Invitation model - an admin's offer of membership, which only the invited user can accept
+type Invitation struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	OrgID     string    `json:"org_id" gorm:"not null;uniqueIndex:idx_invitations_org_user"`
+	UserID    string    `json:"user_id" gorm:"not null;index;uniqueIndex:idx_invitations_org_user"`
+	Role      string    `json:"role" gorm:"not null"` // admin or member, once accepted
+	InvitedBy string    `json:"invited_by" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TeamMembership model - links a team and a member of its organization
+type TeamMembership struct {
+	TeamID string `json:"team_id" gorm:"primaryKey"`
+	UserID string `json:"user_id" gorm:"primaryKey;index"`
+}
+
+// OrganizationRequest DTO
+type OrganizationRequest struct {
+	Name             string  `json:"name"`
+	SMTPHost         *string `json:"smtp_host"`
+	SMTPPort         *string `json:"smtp_port"`
+	SMTPUsername     *string `json:"smtp_username"`
+	SMTPPassword     *string `json:"smtp_password"`
+	SMTPFrom         *string `json:"smtp_from" binding:"omitempty,email"`
+	TwilioAccountSID *string `json:"twilio_account_sid"`
+	TwilioAuthToken  *string `json:"twilio_auth_token"`
+	TwilioFromPhone  *string `json:"twilio_from_phone"`
+	EmailDailyQuota  *int    `json:"email_daily_quota" binding:"omitempty,min=0"`
+	SMSDailyQuota    *int    `json:"sms_daily_quota" binding:"omitempty,min=0"`
+	MoveReminders    bool    `json:"move_reminders"` // on creation, bring the creator's reminders into it
+}
+
+// MemberRequest DTO
+type MemberRequest struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role" binding:"omitempty,oneof=admin member"`
+}
+
+// AcceptInvitationRequest DTO
+type AcceptInvitationRequest struct {
+	MoveReminders bool `json:"move_reminders"` // bring the user's personal reminders into the organization
+}
+
+// TeamRequest DTO
+type TeamRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// Tenant is the organization a user's reminders belong to; empty for users in none
+type Tenant struct {
+	OrgID   string   `json:"org_id"`
+	Role    string   `json:"role,omitempty"`
+	TeamIDs []string `json:"team_ids"`
+}
+
+// DeliverySettings are an organization's credentials and quotas for sending (matches notification service)
+type DeliverySettings struct {
+	OrgID            string `json:"org_id"`
+	SMTPHost         string `json:"smtp_host,omitempty"`
+	SMTPPort         string `json:"smtp_port,omitempty"`
+	SMTPUsername     string `json:"smtp_username,omitempty"`
+	SMTPPassword     string `json:"smtp_password,omitempty"`
+	SMTPFrom         string `json:"smtp_from,omitempty"`
+	TwilioAccountSID string `json:"twilio_account_sid,omitempty"`
+	TwilioAuthToken  string `json:"twilio_auth_token,omitempty"`
+	TwilioFromPhone  string `json:"twilio_from_phone,omitempty"`
+	EmailDailyQuota  int    `json:"email_daily_quota"`
+	SMSDailyQuota    int    `json:"sms_daily_quota"`
+}
+
+// createOrganization creates an organization with the caller as its first admin. Their existing
+// reminders only move into it if they ask; the move follows once the organization is created.
+func createOrganization(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	org := Organization{ID: uuid.New().String()}
+	applyOrganizationRequest(&org, req)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		tx.Model(&Membership{}).Where("user_id = ?", userID).Count(&count)
+		if count > 0 {
+			return errAlreadyMember
+		}
+		if err := tx.Create(&org).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&Membership{UserID: userID, OrgID: org.ID, Role: orgRoleAdmin}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&Invitation{}).Error; err != nil {
+			return err
+		}
+		return tenantChanged(tx, userID, org.ID, req.MoveReminders)
+	})
+	if err == errAlreadyMember {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already belong to an organization"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		return
+	}
+	sendTenantChanges()
+
+	c.JSON(http.StatusCreated, org)
+}
+
+// getTenant returns the organization a user's reminders belong to, and their role in it
+func getTenant(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	tenant := Tenant{TeamIDs: []string{}}
+	var membership Membership
+	err := db.Where("user_id = ?", userID).First(&membership).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch membership"})
+		return
+	}
+	if err == nil {
+		if err := loadTeamIDs(&membership); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch teams"})
+			return
+		}
+		tenant = Tenant{OrgID: membership.OrgID, Role: membership.Role, TeamIDs: membership.TeamIDs}
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+func getOrganization(c *gin.Context) {
+	org, _, err := findOrganization(c, false)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+func updateOrganization(c *gin.Context) {
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, _, err := findOrganization(c, true)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	applyOrganizationRequest(&org, req)
+	if err := db.Save(&org).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update organization"})
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+// deleteOrganization removes the organization; its members' reminders become personal again
+func deleteOrganization(c *gin.Context) {
+	org, _, err := findOrganization(c, true)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("team_id IN (?)", tx.Model(&Team{}).Select("id").Where("org_id = ?", org.ID)).Delete(&TeamMembership{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("org_id = ?", org.ID).Delete(&Team{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("org_id = ?", org.ID).Delete(&Membership{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("org_id = ?", org.ID).Delete(&Invitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&org).Error; err != nil {
+			return err
+		}
+		return tenantDissolved(tx, org.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+		return
+	}
+	sendTenantChanges()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Organization deleted successfully"})
+}
+
+// getDeliverySettings returns the organization's sending credentials and quotas, secrets included
+func getDeliverySettings(c *gin.Context) {
+	var org Organization
+	if err := db.Where("id = ?", c.Param("id")).First(&org).Error; err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, DeliverySettings{
+		OrgID:            org.ID,
+		SMTPHost:         org.SMTPHost,
+		SMTPPort:         org.SMTPPort,
+		SMTPUsername:     org.SMTPUsername,
+		SMTPPassword:     org.SMTPPassword,
+		SMTPFrom:         org.SMTPFrom,
+		TwilioAccountSID: org.TwilioAccountSID,
+		TwilioAuthToken:  org.TwilioAuthToken,
+		TwilioFromPhone:  org.TwilioFromPhone,
+		EmailDailyQuota:  org.EmailDailyQuota,
+		SMSDailyQuota:    org.SMSDailyQuota,
+	})
+}
+
+// Members
+
+func listMembers(c *gin.Context) {
+	org, _, err := findOrganization(c, false)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	var members []Membership
+	if err := db.Where("org_id = ?", org.ID).Order("created_at asc").Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	for i := range members {
+		if err := loadTeamIDs(&members[i]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch teams"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// Invitations
+
+func listInvitations(c *gin.Context) {
+	org, _, err := findOrganization(c, true)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	var invitations []Invitation
+	if err := db.Where("org_id = ?", org.ID).Order("created_at asc").Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// inviteMember invites a user to the organization. Nothing changes for them until they accept.
+func inviteMember(c *gin.Context) {
+	var req MemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.UserID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	org, caller, err := findOrganization(c, true)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	var count int64
+	db.Model(&Membership{}).Where("user_id = ?", req.UserID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User already belongs to an organization"})
+		return
+	}
+
+	invitation := Invitation{ID: uuid.New().String(), OrgID: org.ID, UserID: req.UserID, Role: orgRoleMember, InvitedBy: caller.UserID}
+	if req.Role != "" {
+		invitation.Role = req.Role
+	}
+
+	err = db.Where("org_id = ? AND user_id = ?", org.ID, req.UserID).
+		Attrs(invitation).FirstOrCreate(&invitation).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite member"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+func cancelInvitation(c *gin.Context) {
+	org, _, err := findOrganization(c, true)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	result := db.Where("id = ? AND org_id = ?", c.Param("inviteId"), org.ID).Delete(&Invitation{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel invitation"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation cancelled successfully"})
+}
+
+// listMyInvitations returns the invitations waiting for the caller
+func listMyInvitations(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var invitations []Invitation
+	if err := db.Where("user_id = ?", userID).Order("created_at asc").Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// acceptInvitation makes the caller a member of the organization that invited them. Their
+// personal reminders stay personal unless they ask to bring them along.
+func acceptInvitation(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var member Membership
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var invitation Invitation
+		err := tx.Where("id = ? AND user_id = ?", c.Param("inviteId"), userID).First(&invitation).Error
+		if err == gorm.ErrRecordNotFound {
+			return errNoInvitation
+		}
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		tx.Model(&Membership{}).Where("user_id = ?", userID).Count(&count)
+		if count > 0 {
+			return errAlreadyMember
+		}
+
+		member = Membership{UserID: userID, OrgID: invitation.OrgID, Role: invitation.Role, TeamIDs: []string{}}
+		if err := tx.Create(&member).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&Invitation{}).Error; err != nil {
+			return err
+		}
+		return tenantChanged(tx, userID, member.OrgID, req.MoveReminders)
+	})
+	switch {
+	case err == errNoInvitation:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+	case err == errAlreadyMember:
+		c.JSON(http.StatusConflict, gin.H{"error": "You already belong to an organization"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+	default:
+		sendTenantChanges()
+		c.JSON(http.StatusCreated, member)
+	}
+}
+
+func declineInvitation(c *gin.Context) {
+	result := db.Where("id = ? AND user_id = ?", c.Param("inviteId"), getUserID(c)).Delete(&Invitation{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline invitation"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined successfully"})
+}
+
+func updateMember(c *gin.Context) {
+	var req MemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, _, err := findOrganization(c, true)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	var member Membership
+	if err := db.Where("user_id = ? AND org_id = ?", c.Param("userId"), org.ID).First(&member).Error; err != nil {
+		respondMemberError(c, err)
+		return
+	}
+
+	if req.Role != "" && req.Role != member.Role {
+		if member.Role == orgRoleAdmin && lastAdmin(org.ID) {
+			c.JSON(http.StatusConflict, gin.H{"error": "An organization needs at least one admin"})
+			return
+		}
+		member.Role = req.Role
+	}
+
+	if err := db.Save(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+
+	if err := loadTeamIDs(&member); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch teams"})
+		return
+	}
+	c.JSON(http.StatusOK, member)
+}
+
+// removeMember takes a user out of the organization (admins can remove anyone, members only
+// themselves); their reminders become personal again
+func removeMember(c *gin.Context) {
+	userID := c.Param("userId")
+	org, caller, err := findOrganization(c, false)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+	if caller.Role != orgRoleAdmin && caller.UserID != userID {
+		respondOrgError(c, errNotOrgAdmin)
+		return
+	}
+
+	var member Membership
+	if err := db.Where("user_id = ? AND org_id = ?", userID, org.ID).First(&member).Error; err != nil {
+		respondMemberError(c, err)
+		return
+	}
+	if member.Role == orgRoleAdmin && lastAdmin(org.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "An organization needs at least one admin"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND team_id IN (?)", userID,
+			tx.Model(&Team{}).Select("id").Where("org_id = ?", org.ID)).Delete(&TeamMembership{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&member).Error; err != nil {
+			return err
+		}
+		return tenantChanged(tx, userID, "", false)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	sendTenantChanges()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// Teams
+
+func listTeams(c *gin.Context) {
+	org, _, err := findOrganization(c, false)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	var teams []Team
+	if err := db.Where("org_id = ?", org.ID).Order("name asc").Find(&teams).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch teams"})
+		return
+	}
+	for i := range teams {
+		if err := loadMemberIDs(&teams[i]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team members"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, teams)
+}
+
+func createTeam(c *gin.Context) {
+	var req TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, _, err := findOrganization(c, true)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	team := Team{ID: uuid.New().String(), OrgID: org.ID, Name: req.Name, MemberIDs: []string{}}
+	if err := db.Create(&team).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create team"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, team)
+}
+
+func updateTeam(c *gin.Context) {
+	var req TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	team, err := findTeam(c)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	team.Name = req.Name
+	if err := db.Save(&team).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team"})
+		return
+	}
+
+	if err := loadMemberIDs(&team); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team members"})
+		return
+	}
+	c.JSON(http.StatusOK, team)
+}
+
+func deleteTeam(c *gin.Context) {
+	team, err := findTeam(c)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("team_id = ?", team.ID).Delete(&TeamMembership{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&team).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team deleted successfully"})
+}
+
+func addTeamMember(c *gin.Context) {
+	var req MemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	team, err := findTeam(c)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	var count int64
+	db.Model(&Membership{}).Where("user_id = ? AND org_id = ?", req.UserID, team.OrgID).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User is not a member of this organization"})
+		return
+	}
+
+	if err := db.Save(&TeamMembership{TeamID: team.ID, UserID: req.UserID}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add team member"})
+		return
+	}
+
+	if err := loadMemberIDs(&team); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team members"})
+		return
+	}
+	c.JSON(http.StatusOK, team)
+}
+
+func removeTeamMember(c *gin.Context) {
+	team, err := findTeam(c)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	result := db.Where("team_id = ? AND user_id = ?", team.ID, c.Param("userId")).Delete(&TeamMembership{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove team member"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team member not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team member removed successfully"})
+}
+
+// findOrganization loads the organization in the path along with the caller's membership in it.
+// Only members may see it, and only admins may change it.
+func findOrganization(c *gin.Context, adminOnly bool) (Organization, Membership, error) {
+	var org Organization
+	var membership Membership
+	err := db.Where("user_id = ? AND org_id = ?", getUserID(c), c.Param("id")).First(&membership).Error
+	if err == gorm.ErrRecordNotFound {
+		return org, membership, errNotOrgMember
+	}
+	if err != nil {
+		return org, membership, err
+	}
+	if adminOnly && membership.Role != orgRoleAdmin {
+		return org, membership, errNotOrgAdmin
+	}
+
+	err = db.Where("id = ?", membership.OrgID).First(&org).Error
+	return org, membership, err
+}
+
+// findTeam loads the team in the path, for an admin of its organization
+func findTeam(c *gin.Context) (Team, error) {
+	var team Team
+	org, _, err := findOrganization(c, true)
+	if err != nil {
+		return team, err
+	}
+	err = db.Where("id = ? AND org_id = ?", c.Param("teamId"), org.ID).First(&team).Error
+	return team, err
+}
+
+func respondOrgError(c *gin.Context, err error) {
+	switch err {
+	case errNotOrgMember, gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+	case errNotOrgAdmin:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only organization admins can do that"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organization"})
+	}
+}
+
+func respondMemberError(c *gin.Context, err error) {
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch member"})
+}
+
+func applyOrganizationRequest(org *Organization, req OrganizationRequest) {
+	if req.Name != "" {
+		org.Name = req.Name
+	}
+	setString := func(field *string, value *string) {
+		if value != nil {
+			*field = *value
+		}
+	}
+	setString(&org.SMTPHost, req.SMTPHost)
+	setString(&org.SMTPPort, req.SMTPPort)
+	setString(&org.SMTPUsername, req.SMTPUsername)
+	setString(&org.SMTPPassword, req.SMTPPassword)
+	setString(&org.SMTPFrom, req.SMTPFrom)
+	setString(&org.TwilioAccountSID, req.TwilioAccountSID)
+	setString(&org.TwilioAuthToken, req.TwilioAuthToken)
+	setString(&org.TwilioFromPhone, req.TwilioFromPhone)
+	if req.EmailDailyQuota != nil {
+		org.EmailDailyQuota = *req.EmailDailyQuota
+	}
+	if req.SMSDailyQuota != nil {
+		org.SMSDailyQuota = *req.SMSDailyQuota
+	}
+}
+
+// lastAdmin reports whether the organization has only one admin left
+func lastAdmin(orgID string) bool {
+	var count int64
+	db.Model(&Membership{}).Where("org_id = ? AND role = ?", orgID, orgRoleAdmin).Count(&count)
+	return count <= 1
+}
+
+func loadTeamIDs(membership *Membership) error {
+	membership.TeamIDs = []string{}
+	return db.Model(&TeamMembership{}).
+		Where("user_id = ? AND team_id IN (?)", membership.UserID,
+			db.Model(&Team{}).Select("id").Where("org_id = ?", membership.OrgID)).
+		Pluck("team_id", &membership.TeamIDs).Error
+}
+
+func loadMemberIDs(team *Team) error {
+	team.MemberIDs = []string{}
+	return db.Model(&TeamMembership{}).Where("team_id = ?", team.ID).Order("user_id asc").Pluck("user_id", &team.MemberIDs).Error
+}
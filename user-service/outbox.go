@@ -0,0 +1,89 @@
+// outbox.go - Organization changes reminder-service still has to apply, sent after they commit
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const tenantChangeRetryInterval = 30 * time.Second
+
+// TenantChange model - queued in the membership change's transaction, so it's only sent if that
+// commits, and kept until reminder-service has applied it. reminder-service ignores a change that's
+// no longer the user's current one, so sending one twice or late is harmless.
+type TenantChange struct {
+	ID            string    `json:"id" gorm:"primaryKey"`
+	UserID        string    `json:"user_id" gorm:"index"` // empty when an organization was deleted
+	OrgID         string    `json:"org_id"`               // the user's new organization ("" for none), or the deleted one
+	MoveReminders bool      `json:"move_reminders"`
+	Dissolved     bool      `json:"dissolved"` // the organization was deleted: all its reminders become personal
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// sendTenantChangesMu keeps a request and the retry loop from sending the same change at once
+var sendTenantChangesMu sync.Mutex
+
+// tenantChanged queues telling reminder-service the user's organization changed ("" for none):
+// their organization's reminders become personal when they leave, and on joining their personal
+// ones move in only if moveReminders is set
+func tenantChanged(tx *gorm.DB, userID, orgID string, moveReminders bool) error {
+	return tx.Create(&TenantChange{ID: uuid.New().String(), UserID: userID, OrgID: orgID, MoveReminders: moveReminders}).Error
+}
+
+// tenantDissolved queues making the reminders of a deleted organization personal again
+func tenantDissolved(tx *gorm.DB, orgID string) error {
+	return tx.Create(&TenantChange{ID: uuid.New().String(), OrgID: orgID, Dissolved: true}).Error
+}
+
+// sendTenantChanges sends the queued changes, oldest first. One that fails stays queued with the
+// user's later ones behind it, so they still arrive in order.
+func sendTenantChanges() {
+	sendTenantChangesMu.Lock()
+	defer sendTenantChangesMu.Unlock()
+
+	var changes []TenantChange
+	if err := db.Order("created_at asc, id asc").Find(&changes).Error; err != nil {
+		log.Printf("Error fetching queued organization changes: %v", err)
+		return
+	}
+
+	held := make(map[string]bool)
+	for _, change := range changes {
+		if held[change.UserID] {
+			continue
+		}
+
+		var err error
+		if change.Dissolved {
+			err = postToReminderService("/api/reminders/tenant-dissolve", "", map[string]string{"org_id": change.OrgID})
+		} else {
+			payload := map[string]interface{}{"org_id": change.OrgID, "move_reminders": change.MoveReminders}
+			err = postToReminderService("/api/reminders/tenant-change", change.UserID, payload)
+		}
+		if err != nil {
+			log.Printf("Error sending organization change %s to reminder-service, will retry: %v", change.ID, err)
+			held[change.UserID] = true
+			db.Model(&change).Updates(map[string]interface{}{"attempts": change.Attempts + 1, "last_error": err.Error()})
+			continue
+		}
+
+		if err := db.Delete(&change).Error; err != nil {
+			log.Printf("Error removing sent organization change %s: %v", change.ID, err)
+		}
+	}
+}
+
+// retryTenantChanges keeps sending whatever couldn't be sent straight after its change committed
+func retryTenantChanges() {
+	ticker := time.NewTicker(tenantChangeRetryInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		sendTenantChanges()
+	}
+}
@@ -0,0 +1,152 @@
+// preferences.go - Per-user preferences
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTimezone     = "UTC"
+	defaultCatchUp      = "fire"
+	defaultGraceMinutes = 60
+)
+
+// UserPreferences model - one row per user, created on first update
+type UserPreferences struct {
+	UserID       string    `json:"user_id" gorm:"primaryKey"`
+	Timezone     string    `json:"timezone" gorm:"not null;default:'UTC'"`  // IANA zone; times like "tomorrow 9am" are read in it
+	CatchUp      string    `json:"catch_up" gorm:"not null;default:'fire'"` // reminders found past due: fire, grace (fire if within grace_minutes) or expire
+	GraceMinutes int       `json:"grace_minutes" gorm:"not null;default:60"`
+	Email        string    `json:"email,omitempty"` // where reminders shared with the user are sent
+	Phone        string    `json:"phone,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// PreferencesRequest DTO
+type PreferencesRequest struct {
+	Timezone     string `json:"timezone"`
+	CatchUp      string `json:"catch_up" binding:"omitempty,oneof=fire grace expire"`
+	GraceMinutes int    `json:"grace_minutes" binding:"omitempty,min=1"`
+	Email        string `json:"email" binding:"omitempty,email"`
+	Phone        string `json:"phone"`
+}
+
+// getPreferences returns the user's preferences, or the defaults if they haven't set any
+func getPreferences(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	prefs, err := findPreferences(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+func updatePreferences(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req PreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs, err := findPreferences(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch preferences"})
+		return
+	}
+
+	previousTimezone := prefs.Timezone
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+		prefs.Timezone = req.Timezone
+	}
+	if req.CatchUp != "" {
+		prefs.CatchUp = req.CatchUp
+	}
+	if req.GraceMinutes != 0 {
+		prefs.GraceMinutes = req.GraceMinutes
+	}
+	if req.Email != "" {
+		prefs.Email = req.Email
+	}
+	if req.Phone != "" {
+		prefs.Phone = req.Phone
+	}
+
+	if err := db.Save(&prefs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preferences"})
+		return
+	}
+
+	if prefs.Timezone != previousTimezone {
+		// Floating reminders keep their wall-clock time in the new zone
+		payload := map[string]string{"previous_timezone": previousTimezone, "timezone": prefs.Timezone}
+		if err := postToReminderService("/api/reminders/timezone-change", userID, payload); err != nil {
+			log.Printf("Error notifying reminder-service of timezone change for user %s: %v", userID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+func findPreferences(userID string) (UserPreferences, error) {
+	prefs := UserPreferences{UserID: userID, Timezone: defaultTimezone, CatchUp: defaultCatchUp, GraceMinutes: defaultGraceMinutes}
+	err := db.Where("user_id = ?", userID).First(&prefs).Error
+	if err == gorm.ErrRecordNotFound {
+		err = nil
+	}
+	return prefs, err
+}
+
+// postToReminderService tells reminder-service about a change to one of the user's settings
+func postToReminderService(path, userID string, payload interface{}) error {
+	reminderServiceURL := getEnv("REMINDER_SERVICE_URL", "http://reminder-service:8081")
+	url := fmt.Sprintf("%s%s?user_id=%s", reminderServiceURL, path, url.QueryEscape(userID))
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := newServiceRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+// quiet_hours.go - Quiet hours / do-not-disturb windows
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Quiet hours policies, chosen per reminder priority
+const (
+	policyDefer     = "defer"     // hold the reminder until the window ends
+	policyDowngrade = "downgrade" // deliver on a less intrusive channel (SMS -> email)
+	policySend      = "send"      // ignore the window
+)
+
+var defaultQuietHoursPolicies = map[string]string{
+	"low":      policyDefer,
+	"normal":   policyDefer,
+	"high":     policyDowngrade,
+	"critical": policySend,
+}
+
+// QuietHours model - a do-not-disturb window for a user, or for one of their contacts
+type QuietHours struct {
+	ID        string            `json:"id" gorm:"primaryKey"`
+	UserID    string            `json:"user_id" gorm:"index;not null"`
+	ContactID string            `json:"contact_id,omitempty" gorm:"index"`               // empty for the user's own window
+	Start     string            `json:"start" gorm:"not null"`                           // local time, "22:00"
+	End       string            `json:"end" gorm:"not null"`                             // local time, "07:00"; may wrap past midnight
+	Days      []int             `json:"days,omitempty" gorm:"serializer:json;type:text"` // weekdays the window starts on (0 = Sunday), empty for every day
+	Timezone  string            `json:"timezone" gorm:"not null"`                        // IANA zone the window is evaluated in
+	Policies  map[string]string `json:"policies" gorm:"serializer:json;type:text"`       // priority -> defer, downgrade or send
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// QuietHoursRequest DTO
+type QuietHoursRequest struct {
+	ContactID string            `json:"contact_id"`
+	Start     string            `json:"start"`
+	End       string            `json:"end"`
+	Days      []int             `json:"days" binding:"omitempty,dive,min=0,max=6"`
+	Timezone  string            `json:"timezone"`
+	Policies  map[string]string `json:"policies" binding:"omitempty,dive,keys,oneof=low normal high critical,endkeys,oneof=defer downgrade send"`
+}
+
+func listQuietHours(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	query := db.Where("user_id = ?", userID)
+	if contactID := c.Query("contact_id"); contactID != "" {
+		query = query.Where("contact_id = ?", contactID)
+	}
+
+	var rules []QuietHours
+	if err := query.Order("created_at asc").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quiet hours"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func createQuietHours(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req QuietHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Start == "" || req.End == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start and end are required"})
+		return
+	}
+
+	rule := QuietHours{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		ContactID: req.ContactID,
+		Start:     req.Start,
+		End:       req.End,
+		Days:      req.Days,
+		Timezone:  req.Timezone,
+		Policies:  make(map[string]string),
+	}
+	for priority, policy := range defaultQuietHoursPolicies {
+		rule.Policies[priority] = policy
+	}
+	for priority, policy := range req.Policies {
+		rule.Policies[priority] = policy
+	}
+
+	// A contact's window defaults to the contact's own timezone
+	if rule.ContactID != "" {
+		var contact Contact
+		if err := db.Where("id = ? AND user_id = ?", rule.ContactID, userID).First(&contact).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Contact not found"})
+			return
+		}
+		if rule.Timezone == "" {
+			rule.Timezone = contact.Timezone
+		}
+	}
+
+	if err := validateQuietHours(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := db.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create quiet hours"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+func updateQuietHours(c *gin.Context) {
+	var req QuietHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var rule QuietHours
+	if err := db.Where("id = ? AND user_id = ?", c.Param("id"), getUserID(c)).First(&rule).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Quiet hours not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quiet hours"})
+		return
+	}
+
+	// Update fields
+	if req.Start != "" {
+		rule.Start = req.Start
+	}
+	if req.End != "" {
+		rule.End = req.End
+	}
+	if req.Days != nil {
+		rule.Days = req.Days
+	}
+	if req.Timezone != "" {
+		rule.Timezone = req.Timezone
+	}
+	if rule.Policies == nil {
+		rule.Policies = make(map[string]string)
+	}
+	for priority, policy := range req.Policies {
+		rule.Policies[priority] = policy
+	}
+
+	if err := validateQuietHours(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := db.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update quiet hours"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func deleteQuietHours(c *gin.Context) {
+	result := db.Where("id = ? AND user_id = ?", c.Param("id"), getUserID(c)).Delete(&QuietHours{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete quiet hours"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quiet hours not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Quiet hours deleted successfully"})
+}
+
+func validateQuietHours(rule *QuietHours) error {
+	if _, err := time.Parse("15:04", rule.Start); err != nil {
+		return fmt.Errorf("invalid start time %q, use HH:MM", rule.Start)
+	}
+	if _, err := time.Parse("15:04", rule.End); err != nil {
+		return fmt.Errorf("invalid end time %q, use HH:MM", rule.End)
+	}
+	if rule.Start == rule.End {
+		return fmt.Errorf("start and end must differ")
+	}
+	if rule.Timezone == "" {
+		return fmt.Errorf("timezone is required")
+	}
+	if _, err := time.LoadLocation(rule.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", rule.Timezone)
+	}
+	return nil
+}
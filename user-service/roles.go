@@ -0,0 +1,146 @@
+// roles.go - Users' roles, and the role-bearing tokens every service authenticates with
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultTokenTTL = 24 * time.Hour
+
+var errLastAdmin = errors.New("the last admin can't be taken away")
+
+// UserRole model - a user's role; users without one are ordinary users
+type UserRole struct {
+	UserID    string    `json:"user_id" gorm:"primaryKey"`
+	Role      string    `json:"role" gorm:"not null"` // user or admin
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TokenRequest DTO
+type TokenRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+// RoleRequest DTO
+type RoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user admin"`
+}
+
+// TokenResponse is returned when a token is issued
+type TokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserID    string    `json:"user_id"`
+	Role      string    `json:"role"`
+}
+
+// issueUserToken signs a token for a user, carrying their role. It's for the service that signed
+// the user in, which vouches for who they are; accounts and passwords are no concern of ours.
+func issueUserToken(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := userRole(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch role"})
+		return
+	}
+
+	token, expires, err := issueToken(req.UserID, role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue token"})
+		return
+	}
+	c.JSON(http.StatusOK, TokenResponse{Token: token, ExpiresAt: expires, UserID: req.UserID, Role: role})
+}
+
+// listUserRoles lists the users given a role
+func listUserRoles(c *gin.Context) {
+	var roles []UserRole
+	if err := db.Order("created_at asc").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// updateUserRole makes a user an admin or takes it away. The new role applies from their next token.
+func updateUserRole(c *gin.Context) {
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userRole := UserRole{UserID: c.Param("id")}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// The admins stay locked until the change commits, so two admins taken away at once
+		// can't each see the other one left
+		var admins []UserRole
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("role = ?", roleAdmin).Find(&admins).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userRole.UserID).First(&userRole).Error; err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		if userRole.Role == roleAdmin && req.Role != roleAdmin && len(admins) <= 1 {
+			return errLastAdmin
+		}
+		userRole.Role = req.Role
+		return tx.Save(&userRole).Error
+	})
+	if err == errLastAdmin {
+		c.JSON(http.StatusConflict, gin.H{"error": "There must be at least one admin"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, userRole)
+}
+
+// userRole returns the user's role, user unless they've been given another
+func userRole(userID string) (string, error) {
+	var role UserRole
+	err := db.Where("user_id = ?", userID).First(&role).Error
+	if err == gorm.ErrRecordNotFound {
+		return roleUser, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return role.Role, nil
+}
+
+// issueToken signs a token for the user, valid for JWT_EXPIRATION (default 24h)
+func issueToken(userID, role string) (string, time.Time, error) {
+	ttl, err := time.ParseDuration(getEnv("JWT_EXPIRATION", ""))
+	if err != nil || ttl <= 0 {
+		ttl = defaultTokenTTL
+	}
+
+	expires := time.Now().Add(ttl)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}).SignedString(jwtKey)
+	return token, expires, err
+}